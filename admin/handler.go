@@ -0,0 +1,78 @@
+// Package admin serves operational endpoints that aren't for users.
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Backupper writes a consistent snapshot of its database to dest
+type Backupper interface {
+	Backup(dest string) error
+}
+
+// Handler serves POST /admin/backup, which snapshots the database into Dir
+// and responds with the snapshot's name.
+//
+// Every request needs "Authorization: Bearer <Token>"; with no Token set,
+// every request is refused.
+type Handler struct {
+	Dir   string
+	Token string
+	Now   func() time.Time
+
+	backupper Backupper
+	// mu keeps backups from running over each other
+	mu sync.Mutex
+}
+
+func NewHandler(backupper Backupper, dir, token string) *Handler {
+	return &Handler{Dir: dir, Token: token, Now: time.Now, backupper: backupper}
+}
+
+type backupResponse struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (handler *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !handler.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if r.URL.Path != "/admin/backup" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+
+	created := handler.Now().UTC()
+	name := "posts-" + created.Format("20060102T150405.000Z") + ".db"
+	if err := handler.backupper.Backup(filepath.Join(handler.Dir, name)); err != nil {
+		http.Error(w, "backup failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(backupResponse{Name: name, CreatedAt: created})
+}
+
+func (handler *Handler) authorized(r *http.Request) bool {
+	if handler.Token == "" {
+		return false
+	}
+	expected := "Bearer " + handler.Token
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(expected)) == 1
+}
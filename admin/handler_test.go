@@ -0,0 +1,107 @@
+package admin_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/steve-kaufman/postsService/admin"
+)
+
+type fakeBackupper struct {
+	dests []string
+	err   error
+}
+
+func (b *fakeBackupper) Backup(dest string) error {
+	b.dests = append(b.dests, dest)
+	return b.err
+}
+
+func setup(token string) (*admin.Handler, *fakeBackupper) {
+	backupper := new(fakeBackupper)
+	handler := admin.NewHandler(backupper, "/backups", token)
+	handler.Now = func() time.Time {
+		return time.Date(2021, 5, 6, 7, 8, 9, 123456789, time.FixedZone("", 3600))
+	}
+	return handler, backupper
+}
+
+func request(handler http.Handler, method, path, auth string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, nil)
+	if auth != "" {
+		r.Header.Set("Authorization", auth)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestBackup_WritesTimestampedSnapshot(t *testing.T) {
+	handler, backupper := setup("secret")
+
+	w := request(handler, http.MethodPost, "/admin/backup", "Bearer secret")
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201; Got: %d", w.Code)
+	}
+	expected := `{"name":"posts-20210506T060809.123Z.db","created_at":"2021-05-06T06:08:09.123456789Z"}` + "\n"
+	if diff := cmp.Diff(expected, w.Body.String()); diff != "" {
+		t.Fatalf("Expected the snapshot's name: \n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"/backups/posts-20210506T060809.123Z.db"}, backupper.dests); diff != "" {
+		t.Fatalf("Expected a backup in the directory: \n%s", diff)
+	}
+}
+
+func TestBackup_RequiresToken(t *testing.T) {
+	tests := map[string]struct {
+		token string
+		auth  string
+	}{
+		"no header":    {"secret", ""},
+		"wrong token":  {"secret", "Bearer guess"},
+		"wrong scheme": {"secret", "Basic secret"},
+		"no token set": {"", "Bearer "},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			handler, backupper := setup(tc.token)
+
+			w := request(handler, http.MethodPost, "/admin/backup", tc.auth)
+
+			if w.Code != http.StatusUnauthorized {
+				t.Fatalf("Expected 401; Got: %d", w.Code)
+			}
+			if len(backupper.dests) != 0 {
+				t.Fatalf("Expected no backup; Got: '%v'", backupper.dests)
+			}
+		})
+	}
+}
+
+func TestBackup_RejectsOtherRequests(t *testing.T) {
+	handler, _ := setup("secret")
+
+	if w := request(handler, http.MethodGet, "/admin/backup", "Bearer secret"); w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("Expected 405; Got: %d", w.Code)
+	}
+	if w := request(handler, http.MethodPost, "/admin/restore", "Bearer secret"); w.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404; Got: %d", w.Code)
+	}
+}
+
+func TestBackup_ReportsFailure(t *testing.T) {
+	handler, backupper := setup("secret")
+	backupper.err = errors.New("disk full")
+
+	w := request(handler, http.MethodPost, "/admin/backup", "Bearer secret")
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected 500; Got: %d", w.Code)
+	}
+}
@@ -0,0 +1,18 @@
+// Package backoff computes how long to wait before retrying something
+// that failed.
+package backoff
+
+import "time"
+
+// Exponential doubles from base with every attempt after the first, up
+// to max
+func Exponential(base, max time.Duration, attempts int) time.Duration {
+	backoff := base
+	for i := 1; i < attempts && backoff < max; i++ {
+		backoff *= 2
+	}
+	if backoff > max {
+		return max
+	}
+	return backoff
+}
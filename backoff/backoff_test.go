@@ -0,0 +1,17 @@
+package backoff_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/steve-kaufman/postsService/backoff"
+)
+
+func TestExponential_DoublesUpToMax(t *testing.T) {
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, want := range expected {
+		if got := backoff.Exponential(time.Second, 5*time.Second, i+1); got != want {
+			t.Fatalf("Attempt %d: Expected %v; Got: %v", i+1, want, got)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+// Package cache keeps recently read posts in memory in front of a slower
+// repository.
+package cache
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/feeds"
+	"github.com/steve-kaufman/postsService/interfaces"
+	"github.com/steve-kaufman/postsService/ranking"
+)
+
+// Backend is the repository a Repository reads through to
+type Backend interface {
+	interfaces.Repository
+	interfaces.TxRunner
+}
+
+// Repository decorates a Backend, serving GetPost and GetPosts from an LRU
+// of at most size entries, each kept for TTL. Concurrent misses on the
+// same post, or on the list of posts, share a single load.
+//
+// Writes made through the Repository, including those inside WithinTx,
+// drop the entries they affect once they are done. Writes made to the
+// backend directly are only seen when their entries expire.
+//
+// The backend's optional interfaces are passed on uncached. Those it lacks
+// return errors.ErrUnsupported, except GetSortedPosts, which then sorts
+// the cached list.
+type Repository struct {
+	TTL time.Duration
+	Now func() time.Time
+
+	backend Backend
+	size    int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[key]*list.Element
+	loads   map[key]*load
+}
+
+// key is a post ID, or the list of every post
+type key struct {
+	all bool
+	id  int
+}
+
+var allPosts = key{all: true}
+
+type entry struct {
+	key     key
+	value   interface{}
+	expires time.Time
+}
+
+// load is a read of the backend that callers missing the same key wait on
+type load struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// errLoadPanicked is what callers waiting on a load get if it panics
+var errLoadPanicked = errors.New("cache: load panicked")
+
+func NewRepository(backend Backend, size int, ttl time.Duration) *Repository {
+	return &Repository{
+		TTL:     ttl,
+		Now:     time.Now,
+		backend: backend,
+		size:    size,
+		order:   list.New(),
+		entries: map[key]*list.Element{},
+		loads:   map[key]*load{},
+	}
+}
+
+func (repo *Repository) GetPost(id int) (entities.Post, error) {
+	value, err := repo.get(key{id: id}, func() (interface{}, error) {
+		return repo.backend.GetPost(id)
+	})
+	if err != nil {
+		return entities.Post{}, err
+	}
+	return value.(entities.Post), nil
+}
+
+// GetPosts returns a copy of the cached list, so callers may reorder it
+func (repo *Repository) GetPosts() ([]entities.Post, error) {
+	value, err := repo.get(allPosts, func() (interface{}, error) {
+		return repo.backend.GetPosts()
+	})
+	if err != nil {
+		return nil, err
+	}
+	posts := value.([]entities.Post)
+	if posts == nil {
+		return nil, nil
+	}
+	return append(make([]entities.Post, 0, len(posts)), posts...), nil
+}
+
+func (repo *Repository) SavePost(post entities.Post) (entities.Post, error) {
+	defer repo.invalidate(allPosts)
+	return repo.backend.SavePost(post)
+}
+
+func (repo *Repository) UpdatePost(id int, data entities.Post) error {
+	defer repo.invalidate(key{id: id}, allPosts)
+	return repo.backend.UpdatePost(id, data)
+}
+
+func (repo *Repository) DeletePost(id int) error {
+	defer repo.invalidate(key{id: id}, allPosts)
+	return repo.backend.DeletePost(id)
+}
+
+// WithinTx runs fn in one of the backend's transactions. Reads inside it
+// skip the cache so they see the transaction's own writes, and the entries
+// those writes affect are dropped after the transaction ends.
+func (repo *Repository) WithinTx(fn func(repo interfaces.Repository) error) error {
+	tx := new(txRepository)
+	defer func() { repo.invalidate(tx.written...) }()
+	return repo.backend.WithinTx(func(inner interfaces.Repository) error {
+		tx.Repository = inner
+		return fn(tx)
+	})
+}
+
+func (repo *Repository) GetSortedPosts(by ranking.Sort) ([]entities.Post, error) {
+	return interfaces.SortedPosts(repo.backend, repo, by)
+}
+
+func (repo *Repository) GetPostBySlug(slug string) (entities.Post, error) {
+	return interfaces.PostBySlug(repo.backend, slug)
+}
+
+func (repo *Repository) GetChangesSince(seq int64, limit int) ([]entities.Change, error) {
+	return interfaces.ChangesSince(repo.backend, seq, limit)
+}
+
+func (repo *Repository) RecentPosts(limit int) ([]feeds.Entry, error) {
+	return interfaces.RecentPosts(repo.backend, limit)
+}
+
+// Len returns how many entries are cached
+func (repo *Repository) Len() int {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	return repo.order.Len()
+}
+
+func (repo *Repository) get(k key, fetch func() (interface{}, error)) (interface{}, error) {
+	repo.mu.Lock()
+	if element, ok := repo.entries[k]; ok {
+		cached := element.Value.(*entry)
+		if repo.Now().Before(cached.expires) {
+			repo.order.MoveToFront(element)
+			repo.mu.Unlock()
+			return cached.value, nil
+		}
+		repo.remove(element)
+	}
+	if pending, ok := repo.loads[k]; ok {
+		repo.mu.Unlock()
+		<-pending.done
+		return pending.value, pending.err
+	}
+	pending := &load{done: make(chan struct{}), err: errLoadPanicked}
+	repo.loads[k] = pending
+	repo.mu.Unlock()
+
+	defer repo.finish(k, pending)
+	pending.value, pending.err = fetch()
+	return pending.value, pending.err
+}
+
+// finish keeps what pending loaded and releases its waiters, even if the
+// load panicked
+func (repo *Repository) finish(k key, pending *load) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	defer close(pending.done)
+	// a load invalidated while it ran may have read what was overwritten
+	if repo.loads[k] == pending {
+		delete(repo.loads, k)
+		if pending.err == nil {
+			repo.store(k, pending.value)
+		}
+	}
+}
+
+func (repo *Repository) store(k key, value interface{}) {
+	if repo.size <= 0 {
+		return
+	}
+	repo.entries[k] = repo.order.PushFront(&entry{key: k, value: value, expires: repo.Now().Add(repo.TTL)})
+	for repo.order.Len() > repo.size {
+		repo.remove(repo.order.Back())
+	}
+}
+
+func (repo *Repository) remove(element *list.Element) {
+	repo.order.Remove(element)
+	delete(repo.entries, element.Value.(*entry).key)
+}
+
+func (repo *Repository) invalidate(keys ...key) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	for _, k := range keys {
+		if element, ok := repo.entries[k]; ok {
+			repo.remove(element)
+		}
+		// callers already waiting still get its result, but it isn't kept
+		delete(repo.loads, k)
+	}
+}
+
+// txRepository is the backend's transaction, noting which entries its
+// writes affect
+type txRepository struct {
+	interfaces.Repository
+	written []key
+}
+
+func (tx *txRepository) SavePost(post entities.Post) (entities.Post, error) {
+	tx.written = append(tx.written, allPosts)
+	return tx.Repository.SavePost(post)
+}
+
+func (tx *txRepository) UpdatePost(id int, data entities.Post) error {
+	tx.written = append(tx.written, key{id: id}, allPosts)
+	return tx.Repository.UpdatePost(id, data)
+}
+
+func (tx *txRepository) DeletePost(id int) error {
+	tx.written = append(tx.written, key{id: id}, allPosts)
+	return tx.Repository.DeletePost(id)
+}
@@ -0,0 +1,298 @@
+package cache_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/steve-kaufman/postsService/cache"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/events"
+	"github.com/steve-kaufman/postsService/memory"
+	"github.com/steve-kaufman/postsService/render"
+	"github.com/steve-kaufman/postsService/repotest"
+	"github.com/steve-kaufman/postsService/useCases"
+)
+
+var examplePosts = []entities.Post{
+	{ID: 1, Title: "Post 1", Likes: 2},
+	{ID: 2, Title: "Post 2"},
+	{ID: 3, Title: "Post 3"},
+}
+
+// backend counts reads and, with a gate, holds what it read until the
+// gate is closed. With panics set, its next GetPost panics.
+type backend struct {
+	*memory.Repository
+	reads   int32
+	started chan struct{}
+	gate    chan struct{}
+	panics  int32
+}
+
+func newBackend() *backend {
+	return &backend{Repository: memory.NewRepository(examplePosts...)}
+}
+
+func (b *backend) wait() {
+	atomic.AddInt32(&b.reads, 1)
+	if b.started != nil {
+		b.started <- struct{}{}
+	}
+	if b.gate != nil {
+		<-b.gate
+	}
+}
+
+func (b *backend) GetPost(id int) (entities.Post, error) {
+	post, err := b.Repository.GetPost(id)
+	b.wait()
+	if atomic.CompareAndSwapInt32(&b.panics, 1, 0) {
+		panic("backend failed")
+	}
+	return post, err
+}
+
+func (b *backend) GetPosts() ([]entities.Post, error) {
+	posts, err := b.Repository.GetPosts()
+	b.wait()
+	return posts, err
+}
+
+func (b *backend) Reads() int {
+	return int(atomic.LoadInt32(&b.reads))
+}
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func setup(size int) (*cache.Repository, *backend, *clock) {
+	b := newBackend()
+	c := &clock{now: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}
+	repo := cache.NewRepository(b, size, time.Minute)
+	repo.Now = c.Now
+	return repo, b, c
+}
+
+func TestGetPost_ServesRepeatReadsFromCache(t *testing.T) {
+	repo, b, _ := setup(10)
+
+	for i := 0; i < 3; i++ {
+		post, err := repo.GetPost(1)
+		if err != nil || post.Title != "Post 1" {
+			t.Fatalf("Expected post 1; Got: '%v', '%v'", post, err)
+		}
+		repo.GetPosts()
+	}
+	if b.Reads() != 2 {
+		t.Fatalf("Expected one read each of the post and the list; Got: %d", b.Reads())
+	}
+}
+
+func TestGetPost_PassesErrorsThroughWithoutCaching(t *testing.T) {
+	repo, b, _ := setup(10)
+
+	for i := 0; i < 2; i++ {
+		if _, err := repo.GetPost(9); err != useCases.ErrNotFound {
+			t.Fatalf("Expected ErrNotFound; Got: '%v'", err)
+		}
+	}
+	if b.Reads() != 2 {
+		t.Fatalf("Expected each miss to be read; Got: %d reads", b.Reads())
+	}
+}
+
+func TestGetPost_ExpiresEntriesAfterTTL(t *testing.T) {
+	repo, b, c := setup(10)
+	repo.GetPost(1)
+
+	c.now = c.now.Add(59 * time.Second)
+	repo.GetPost(1)
+	c.now = c.now.Add(time.Second)
+	repo.GetPost(1)
+
+	if b.Reads() != 2 {
+		t.Fatalf("Expected a second read once the TTL passed; Got: %d", b.Reads())
+	}
+}
+
+func TestGetPost_EvictsLeastRecentlyUsed(t *testing.T) {
+	repo, b, _ := setup(2)
+	repo.GetPost(1)
+	repo.GetPost(2)
+	repo.GetPost(1)
+	repo.GetPost(3)
+
+	if repo.Len() != 2 {
+		t.Fatalf("Expected 2 entries; Got: %d", repo.Len())
+	}
+	repo.GetPost(1)
+	if b.Reads() != 3 {
+		t.Fatalf("Expected post 1 to still be cached; Got: %d reads", b.Reads())
+	}
+	repo.GetPost(2)
+	if b.Reads() != 4 {
+		t.Fatalf("Expected post 2 to have been evicted; Got: %d reads", b.Reads())
+	}
+}
+
+func TestGetPosts_ReturnsACopy(t *testing.T) {
+	repo, _, _ := setup(10)
+
+	posts, _ := repo.GetPosts()
+	posts[0].Title = "Changed"
+
+	if posts, _ := repo.GetPosts(); posts[0].Title != "Post 1" {
+		t.Fatalf("Expected the cached list to be unchanged; Got: '%v'", posts)
+	}
+}
+
+func TestWrites_InvalidateTheirEntries(t *testing.T) {
+	repo, _, _ := setup(10)
+	repo.GetPost(1)
+	repo.GetPost(2)
+	repo.GetPosts()
+
+	repo.UpdatePost(1, entities.Post{Title: "Updated"})
+	repo.DeletePost(2)
+	repo.SavePost(entities.Post{Title: "Post 4"})
+
+	if post, _ := repo.GetPost(1); post.Title != "Updated" {
+		t.Fatalf("Expected the updated post; Got: '%v'", post)
+	}
+	if _, err := repo.GetPost(2); err != useCases.ErrNotFound {
+		t.Fatalf("Expected the deleted post to be gone; Got: '%v'", err)
+	}
+	if posts, _ := repo.GetPosts(); len(posts) != 3 || posts[2].Title != "Post 4" {
+		t.Fatalf("Expected the list to be reloaded; Got: '%v'", posts)
+	}
+}
+
+func TestUseCases_InvalidateThroughTransactions(t *testing.T) {
+	repo, _, _ := setup(10)
+	repo.GetPost(1)
+	repo.GetPost(2)
+	repo.GetPosts()
+
+	if _, err := useCases.LikePost(repo, new(events.Recorder), render.Skip, 1); err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	useCases.UpdatePost(repo, new(events.Recorder), render.Skip, 2, entities.Post{Title: "Renamed"})
+	useCases.DeletePost(repo, new(events.Recorder), 3)
+
+	if post, _ := repo.GetPost(1); post.Likes != 3 {
+		t.Fatalf("Expected the vote to be seen; Got: '%v'", post)
+	}
+	if post, _ := repo.GetPost(2); post.Title != "Renamed" {
+		t.Fatalf("Expected the update to be seen; Got: '%v'", post)
+	}
+	if posts, _ := repo.GetPosts(); len(posts) != 2 {
+		t.Fatalf("Expected the delete to be seen; Got: '%v'", posts)
+	}
+}
+
+func TestGetPost_CollapsesConcurrentMisses(t *testing.T) {
+	repo, b, _ := setup(10)
+	b.started = make(chan struct{}, 10)
+	b.gate = make(chan struct{})
+
+	var wg sync.WaitGroup
+	results := make(chan entities.Post, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			post, _ := repo.GetPost(1)
+			results <- post
+		}()
+	}
+	<-b.started
+	// give the other callers time to join the load in flight
+	time.Sleep(20 * time.Millisecond)
+	close(b.gate)
+	wg.Wait()
+	close(results)
+
+	if b.Reads() != 1 {
+		t.Fatalf("Expected one read; Got: %d", b.Reads())
+	}
+	for post := range results {
+		if post.Title != "Post 1" {
+			t.Fatalf("Expected every caller to get post 1; Got: '%v'", post)
+		}
+	}
+}
+
+func TestGetPost_DoesNotKeepALoadInvalidatedWhileRunning(t *testing.T) {
+	repo, b, _ := setup(10)
+	b.started = make(chan struct{}, 1)
+	b.gate = make(chan struct{})
+
+	done := make(chan entities.Post)
+	go func() {
+		post, _ := repo.GetPost(1)
+		done <- post
+	}()
+	<-b.started
+	// the write lands after the old post was read but before it is cached
+	repo.UpdatePost(1, entities.Post{Title: "Updated"})
+	close(b.gate)
+	if post := <-done; post.Title != "Post 1" {
+		t.Fatalf("Expected the read in flight to return what it read; Got: '%v'", post)
+	}
+
+	b.started = nil
+	if post, _ := repo.GetPost(1); post.Title != "Updated" {
+		t.Fatalf("Expected the stale read not to be cached; Got: '%v'", post)
+	}
+}
+
+func TestGetPost_ReleasesALoadThatPanics(t *testing.T) {
+	repo, b, _ := setup(10)
+	b.started = make(chan struct{}, 10)
+	b.gate = make(chan struct{})
+	b.panics = 1
+
+	panicked := make(chan interface{})
+	go func() {
+		defer func() { panicked <- recover() }()
+		repo.GetPost(1)
+	}()
+	<-b.started
+	waited := make(chan error)
+	go func() {
+		_, err := repo.GetPost(1)
+		waited <- err
+	}()
+	// give the second caller time to join the load in flight
+	time.Sleep(20 * time.Millisecond)
+	close(b.gate)
+
+	if <-panicked == nil {
+		t.Fatal("Expected the panic to reach the caller that loaded")
+	}
+	if err := <-waited; err == nil {
+		t.Fatal("Expected the waiting caller to get an error")
+	}
+	if post, err := repo.GetPost(1); err != nil || post.Title != "Post 1" {
+		t.Fatalf("Expected the next read to load again; Got: '%v', '%v'", post, err)
+	}
+}
+
+func TestRepository_Conformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repository {
+		return cache.NewRepository(memory.NewRepository(), 10, time.Minute)
+	})
+}
+
+func TestRepository_Forwarding(t *testing.T) {
+	repotest.RunForwarding(t, func(t *testing.T, backend repotest.Repository) repotest.Repository {
+		return cache.NewRepository(backend, 10, time.Minute)
+	})
+}
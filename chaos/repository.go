@@ -0,0 +1,224 @@
+// Package chaos wraps a repository so that some of its calls fail or slow
+// down, for testing how the layers above cope with a partly broken store.
+package chaos
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/feeds"
+	"github.com/steve-kaufman/postsService/interfaces"
+	"github.com/steve-kaufman/postsService/ranking"
+)
+
+var ErrInjected = errors.New("injected fault")
+
+// Backend is the repository faults are injected in front of
+type Backend interface {
+	interfaces.Repository
+	interfaces.TxRunner
+}
+
+// Rule picks calls to slow down or fail. A call matches when both its
+// method and its post ID are listed, an empty list matching anything.
+type Rule struct {
+	// Methods are repository method names, such as "GetPost"
+	Methods []string
+	// IDs are post IDs. Calls without one, like GetPosts and SavePost,
+	// never match a rule that lists IDs.
+	IDs []int
+	// After is how many matching calls go through before any fails
+	After int
+	// Percent is the chance, from 0 to 100, that a matching call past
+	// After fails
+	Percent float64
+	// Latency is added to every matching call, failing or not
+	Latency time.Duration
+	// Err is what failing calls return. Nil means ErrInjected.
+	Err error
+}
+
+// Repository applies its rules to every call, including those made inside
+// WithinTx. A failing call never reaches the backend. The rules are
+// checked in order and the first failure wins. Calls are drawn from a
+// seeded source, so the same sequence of calls fails the same way each
+// run.
+type Repository struct {
+	txRepository
+	// Sleep waits out injected latency
+	Sleep func(time.Duration)
+
+	runner interfaces.TxRunner
+
+	mu       sync.Mutex
+	rules    []Rule
+	matched  []int
+	random   *rand.Rand
+	injected int
+}
+
+// txRepository injects faults into the repository a transaction runs
+// against
+type txRepository struct {
+	chaos   *Repository
+	backend interfaces.Repository
+}
+
+func NewRepository(backend Backend, seed int64, rules ...Rule) *Repository {
+	repo := &Repository{
+		Sleep:   time.Sleep,
+		runner:  backend,
+		rules:   rules,
+		matched: make([]int, len(rules)),
+		random:  rand.New(rand.NewSource(seed)),
+	}
+	repo.txRepository = txRepository{repo, backend}
+	return repo
+}
+
+// Injected is how many calls have been failed so far
+func (repo *Repository) Injected() int {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	return repo.injected
+}
+
+func (repo *Repository) WithinTx(fn func(repo interfaces.Repository) error) error {
+	if err := repo.inject("WithinTx", nil); err != nil {
+		return err
+	}
+	return repo.runner.WithinTx(func(inner interfaces.Repository) error {
+		return fn(&txRepository{repo, inner})
+	})
+}
+
+func (repo *Repository) GetSortedPosts(by ranking.Sort) ([]entities.Post, error) {
+	if err := repo.inject("GetSortedPosts", nil); err != nil {
+		return nil, err
+	}
+	return interfaces.SortedPosts(repo.backend, repo.backend, by)
+}
+
+func (repo *Repository) GetPostBySlug(slug string) (entities.Post, error) {
+	if err := repo.inject("GetPostBySlug", nil); err != nil {
+		return entities.Post{}, err
+	}
+	return interfaces.PostBySlug(repo.backend, slug)
+}
+
+func (repo *Repository) GetChangesSince(seq int64, limit int) ([]entities.Change, error) {
+	if err := repo.inject("GetChangesSince", nil); err != nil {
+		return nil, err
+	}
+	return interfaces.ChangesSince(repo.backend, seq, limit)
+}
+
+func (repo *Repository) RecentPosts(limit int) ([]feeds.Entry, error) {
+	if err := repo.inject("RecentPosts", nil); err != nil {
+		return nil, err
+	}
+	return interfaces.RecentPosts(repo.backend, limit)
+}
+
+func (repo *txRepository) GetPosts() ([]entities.Post, error) {
+	if err := repo.chaos.inject("GetPosts", nil); err != nil {
+		return nil, err
+	}
+	return repo.backend.GetPosts()
+}
+
+func (repo *txRepository) GetPost(id int) (entities.Post, error) {
+	if err := repo.chaos.inject("GetPost", &id); err != nil {
+		return entities.Post{}, err
+	}
+	return repo.backend.GetPost(id)
+}
+
+func (repo *txRepository) SavePost(post entities.Post) (entities.Post, error) {
+	if err := repo.chaos.inject("SavePost", nil); err != nil {
+		return entities.Post{}, err
+	}
+	return repo.backend.SavePost(post)
+}
+
+func (repo *txRepository) DeletePost(id int) error {
+	if err := repo.chaos.inject("DeletePost", &id); err != nil {
+		return err
+	}
+	return repo.backend.DeletePost(id)
+}
+
+func (repo *txRepository) UpdatePost(id int, data entities.Post) error {
+	if err := repo.chaos.inject("UpdatePost", &id); err != nil {
+		return err
+	}
+	return repo.backend.UpdatePost(id, data)
+}
+
+// inject waits out the latency of the rules that method and id match, then
+// returns the error of the first that fails the call. id is nil for calls
+// not about one post.
+func (repo *Repository) inject(method string, id *int) error {
+	repo.mu.Lock()
+	var latency time.Duration
+	var err error
+	for i, rule := range repo.rules {
+		if !rule.matches(method, id) {
+			continue
+		}
+		latency += rule.Latency
+		repo.matched[i]++
+		if repo.matched[i] <= rule.After {
+			continue
+		}
+		// drawn for every call past After, so a rule's failures don't
+		// depend on whether an earlier rule already failed the call
+		fails := repo.random.Float64()*100 < rule.Percent
+		if fails && err == nil {
+			err = rule.Err
+			if err == nil {
+				err = ErrInjected
+			}
+		}
+	}
+	if err != nil {
+		repo.injected++
+	}
+	repo.mu.Unlock()
+
+	if latency > 0 {
+		repo.Sleep(latency)
+	}
+	return err
+}
+
+func (rule Rule) matches(method string, id *int) bool {
+	if len(rule.Methods) > 0 && !containsString(rule.Methods, method) {
+		return false
+	}
+	if len(rule.IDs) > 0 && (id == nil || !containsInt(rule.IDs, *id)) {
+		return false
+	}
+	return true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
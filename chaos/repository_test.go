@@ -0,0 +1,152 @@
+package chaos_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/steve-kaufman/postsService/chaos"
+	"github.com/steve-kaufman/postsService/db"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/events"
+	"github.com/steve-kaufman/postsService/memory"
+	"github.com/steve-kaufman/postsService/render"
+	"github.com/steve-kaufman/postsService/repotest"
+	"github.com/steve-kaufman/postsService/useCases"
+)
+
+var examplePosts = []entities.Post{
+	{ID: 1, Title: "Foo"},
+	{ID: 2, Title: "Bar"},
+	{ID: 3, Title: "Baz"},
+}
+
+func setup(seed int64, rules ...chaos.Rule) *chaos.Repository {
+	return chaos.NewRepository(db.NewGoodRepository(examplePosts), seed, rules...)
+}
+
+// failures runs GetPost n times and returns which calls failed
+func failures(repo *chaos.Repository, n int) []bool {
+	failed := make([]bool, n)
+	for i := range failed {
+		_, err := repo.GetPost(1)
+		failed[i] = err != nil
+	}
+	return failed
+}
+
+func TestRepository_FailsAPercentageOfCalls(t *testing.T) {
+	repo := setup(7, chaos.Rule{Percent: 30})
+
+	failures(repo, 1000)
+
+	if n := repo.Injected(); n < 250 || n > 350 {
+		t.Fatalf("Expected about 300 of 1000 calls to fail; Got: %d", n)
+	}
+}
+
+func TestRepository_FailsTheSameCallsWithTheSameSeed(t *testing.T) {
+	first := failures(setup(42, chaos.Rule{Percent: 50}), 100)
+	second := failures(setup(42, chaos.Rule{Percent: 50}), 100)
+
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("Expected call %d to fail the same way with the same seed", i)
+		}
+	}
+}
+
+func TestRepository_FailsOnlyMatchingMethodsAndIDs(t *testing.T) {
+	repo := setup(1, chaos.Rule{Methods: []string{"GetPost", "DeletePost"}, IDs: []int{2}, Percent: 100})
+
+	if _, err := repo.GetPost(1); err != nil {
+		t.Fatalf("Expected GetPost(1) to succeed; Got: '%v'", err)
+	}
+	if _, err := repo.GetPosts(); err != nil {
+		t.Fatalf("Expected GetPosts to succeed; Got: '%v'", err)
+	}
+	if err := repo.UpdatePost(2, entities.Post{Title: "Qux"}); err != nil {
+		t.Fatalf("Expected UpdatePost(2) to succeed; Got: '%v'", err)
+	}
+	if _, err := repo.GetPost(2); err != chaos.ErrInjected {
+		t.Fatalf("Expected GetPost(2) to fail; Got: '%v'", err)
+	}
+	if err := repo.DeletePost(2); err != chaos.ErrInjected {
+		t.Fatalf("Expected DeletePost(2) to fail; Got: '%v'", err)
+	}
+	if posts, _ := repo.GetPosts(); len(posts) != 3 {
+		t.Fatalf("Expected a failed delete not to reach the backend; Got: '%v'", posts)
+	}
+}
+
+func TestRepository_FailsAfterNSuccesses(t *testing.T) {
+	errFull := errors.New("disk full")
+	repo := setup(1, chaos.Rule{Methods: []string{"SavePost"}, After: 2, Percent: 100, Err: errFull})
+
+	for i := 1; i <= 4; i++ {
+		_, err := repo.SavePost(entities.Post{ID: 10 + i, Title: "New"})
+		if i <= 2 && err != nil {
+			t.Fatalf("Expected save %d to succeed; Got: '%v'", i, err)
+		}
+		if i > 2 && err != errFull {
+			t.Fatalf("Expected save %d to fail with the rule's error; Got: '%v'", i, err)
+		}
+	}
+}
+
+func TestRepository_InjectsLatency(t *testing.T) {
+	var slept []time.Duration
+	repo := setup(1,
+		chaos.Rule{Latency: 5 * time.Millisecond},
+		chaos.Rule{Methods: []string{"GetPosts"}, Latency: time.Second},
+	)
+	repo.Sleep = func(d time.Duration) {
+		slept = append(slept, d)
+	}
+
+	repo.GetPost(1)
+	repo.GetPosts()
+
+	if len(slept) != 2 || slept[0] != 5*time.Millisecond || slept[1] != time.Second+5*time.Millisecond {
+		t.Fatalf("Expected the latencies of the matching rules to add up; Got: '%v'", slept)
+	}
+	if repo.Injected() != 0 {
+		t.Fatalf("Expected latency alone not to fail calls; Got: %d", repo.Injected())
+	}
+}
+
+func TestRepository_InjectsFaultsInsideTransactions(t *testing.T) {
+	repo := setup(1, chaos.Rule{Methods: []string{"DeletePost"}, Percent: 100})
+
+	_, err := useCases.DeletePost(repo, new(events.Recorder), 1)
+
+	if !errors.Is(err, useCases.ErrInternal) || !errors.Is(err, chaos.ErrInjected) {
+		t.Fatalf("Expected ErrInternal caused by the injected fault; Got: '%v'", err)
+	}
+	if _, err := useCases.GetOnePost(repo, render.Skip, 1); err != nil {
+		t.Fatalf("Expected the post to survive the failed delete; Got: '%v'", err)
+	}
+}
+
+func TestRepository_Conformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repository {
+		return chaos.NewRepository(memory.NewRepository(), 1)
+	})
+}
+
+func TestRepository_InjectsFaultsIntoOptionalCalls(t *testing.T) {
+	repo := setup(1, chaos.Rule{Methods: []string{"GetPostBySlug"}, Percent: 100})
+
+	if _, err := useCases.GetPostBySlug(repo, render.Skip, "foo"); !errors.Is(err, useCases.ErrInternal) {
+		t.Fatalf("Expected the injected fault to surface as ErrInternal; Got: '%v'", err)
+	}
+	if repo.Injected() != 1 {
+		t.Fatalf("Expected one injected fault; Got: %d", repo.Injected())
+	}
+}
+
+func TestRepository_Forwarding(t *testing.T) {
+	repotest.RunForwarding(t, func(t *testing.T, backend repotest.Repository) repotest.Repository {
+		return chaos.NewRepository(backend, 1)
+	})
+}
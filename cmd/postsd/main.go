@@ -0,0 +1,212 @@
+// Command postsd manages a posts database.
+//
+//	postsd export [-storage sqlite|memory] [-db posts.db] [-format jsonl|csv|json] [-out file]
+//	postsd import [-storage sqlite|memory] [-db posts.db] [-format jsonl|csv|json] [-ids reassign|preserve] [-report file] [file]
+//	postsd backup [-db posts.db] dest
+//	postsd restore [-db posts.db] snapshot
+//
+// export writes every post to -out, or standard output. import reads posts
+// from file, or standard input, and writes a JSON line for each record it
+// skipped to -report, or standard error.
+//
+// backup writes a consistent snapshot of the database to dest and is safe
+// while the server runs. restore checks a snapshot's integrity and swaps it
+// in for the database; stop the server first.
+//
+// -storage=memory keeps posts in memory.Repository instead of the -db
+// file. The posts start empty and are gone when postsd exits, so an import
+// into memory checks a file, reporting what it would skip, without writing
+// anything. backup and restore only work on SQLite files.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/steve-kaufman/postsService/db"
+	"github.com/steve-kaufman/postsService/memory"
+	"github.com/steve-kaufman/postsService/transfer"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	var err error
+	switch os.Args[1] {
+	case "export":
+		err = export(os.Args[2:])
+	case "import":
+		err = importPosts(os.Args[2:])
+	case "backup":
+		err = backup(os.Args[2:])
+	case "restore":
+		err = restore(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "postsd:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: postsd export|import|backup|restore [flags]")
+	os.Exit(2)
+}
+
+// storage is what export and import move posts in and out of
+type storage interface {
+	transfer.Source
+	transfer.Sink
+}
+
+func openStorage(kind, path string) (storage, error) {
+	switch kind {
+	case "sqlite":
+		repo, err := db.NewSqliteRepo(path)
+		if err != nil {
+			return nil, err
+		}
+		return repo, nil
+	case "memory":
+		return memory.NewRepository(), nil
+	default:
+		return nil, fmt.Errorf("-storage must be sqlite or memory")
+	}
+}
+
+func export(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	kind := flags.String("storage", "sqlite", "sqlite, or memory for an empty in-memory store")
+	path := flags.String("db", "posts.db", "database file")
+	format := flags.String("format", string(transfer.JSONLines), "jsonl, csv or json")
+	out := flags.String("out", "", "file to write to instead of standard output")
+	flags.Parse(args)
+
+	parsed, err := transfer.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+	if *kind == "sqlite" {
+		if _, err := os.Stat(*path); err != nil {
+			return err
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	repo, err := openStorage(*kind, *path)
+	if err != nil {
+		return err
+	}
+	exported, err := transfer.Export(repo, w, parsed)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "exported %d posts\n", exported)
+	return nil
+}
+
+type failureLine struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+func importPosts(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	kind := flags.String("storage", "sqlite", "sqlite, or memory to check a file without storing it")
+	path := flags.String("db", "posts.db", "database file")
+	format := flags.String("format", string(transfer.JSONLines), "jsonl, csv or json")
+	ids := flags.String("ids", "reassign", "reassign gives posts new IDs; preserve keeps theirs")
+	reportPath := flags.String("report", "", "file to write skipped records to instead of standard error")
+	flags.Parse(args)
+
+	parsed, err := transfer.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+	var mode transfer.IDMode
+	switch *ids {
+	case "reassign":
+		mode = transfer.ReassignIDs
+	case "preserve":
+		mode = transfer.PreserveIDs
+	default:
+		return fmt.Errorf("-ids must be reassign or preserve")
+	}
+
+	var r io.Reader = os.Stdin
+	if flags.NArg() > 0 {
+		file, err := os.Open(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+	var report io.Writer = os.Stderr
+	if *reportPath != "" {
+		file, err := os.Create(*reportPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		report = file
+	}
+
+	repo, err := openStorage(*kind, *path)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(report)
+	skipped := 0
+	imported, err := transfer.Import(repo, r, parsed, mode, func(failure transfer.Failure) {
+		skipped++
+		encoder.Encode(failureLine{Line: failure.Line, Error: failure.Err.Error()})
+	})
+	fmt.Fprintf(os.Stderr, "imported %d posts, skipped %d\n", imported, skipped)
+	return err
+}
+
+func backup(args []string) error {
+	flags := flag.NewFlagSet("backup", flag.ExitOnError)
+	path := flags.String("db", "posts.db", "database file")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return fmt.Errorf("backup needs a destination file")
+	}
+
+	if _, err := os.Stat(*path); err != nil {
+		return err
+	}
+	repo, err := db.NewSqliteRepo(*path)
+	if err != nil {
+		return err
+	}
+	return repo.Backup(flags.Arg(0))
+}
+
+func restore(args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	path := flags.String("db", "posts.db", "database file")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return fmt.Errorf("restore needs a snapshot file")
+	}
+
+	return db.Restore(flags.Arg(0), *path)
+}
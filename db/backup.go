@@ -0,0 +1,106 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var ErrBackupInTx = errors.New("can't back up from inside a transaction")
+var ErrBadSnapshot = errors.New("snapshot failed its integrity check")
+
+// Backup writes a consistent snapshot of the database to dest with VACUUM
+// INTO, which reads in one transaction while writers carry on. The
+// snapshot is written beside dest and renamed into place, so dest is never
+// a partial copy; an existing dest is replaced.
+func (repo SqliteRepo) Backup(dest string) error {
+	if repo.db == nil {
+		// VACUUM can't run in a transaction
+		return ErrBackupInTx
+	}
+	partial := dest + ".partial"
+	os.Remove(partial)
+	if _, err := repo.db.Exec(`VACUUM INTO ?`, partial); err != nil {
+		os.Remove(partial)
+		return err
+	}
+	return os.Rename(partial, dest)
+}
+
+// Restore replaces the database at path with snapshot once the snapshot
+// passes SQLite's integrity check. Nothing may have path open: restore
+// while the server is stopped.
+func Restore(snapshot, path string) error {
+	if err := checkSnapshot(snapshot); err != nil {
+		return err
+	}
+
+	restoring := path + ".restoring"
+	if err := copyFile(snapshot, restoring); err != nil {
+		os.Remove(restoring)
+		return err
+	}
+	// journals left by the old database must not be applied to the new one
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		if err := os.Remove(path + suffix); err != nil && !os.IsNotExist(err) {
+			os.Remove(restoring)
+			return err
+		}
+	}
+	return os.Rename(restoring, path)
+}
+
+func checkSnapshot(snapshot string) error {
+	if _, err := os.Stat(snapshot); err != nil {
+		return err
+	}
+	conn, err := sql.Open("sqlite3", "file:"+snapshot+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var result string
+	if err := conn.QueryRow(`PRAGMA integrity_check;`).Scan(&result); err != nil {
+		return fmt.Errorf("%w: %v", ErrBadSnapshot, err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("%w: %s", ErrBadSnapshot, result)
+	}
+	var hasPosts bool
+	err = conn.QueryRow(`SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'posts')`).
+		Scan(&hasPosts)
+	if err != nil {
+		return err
+	}
+	if !hasPosts {
+		return fmt.Errorf("%w: no posts table", ErrBadSnapshot)
+	}
+	return nil
+}
+
+// copyFile copies src to dest and syncs it, so a rename of dest after a
+// crash can't expose a partly written file
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
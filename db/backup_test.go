@@ -0,0 +1,92 @@
+package db_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/steve-kaufman/postsService/db"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/interfaces"
+)
+
+func TestBackup_WritesAUsableSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	repo := openRepo(t, filepath.Join(dir, "posts.db"))
+	repo.SavePost(entities.Post{Title: "Foo"})
+	snapshot := filepath.Join(dir, "snapshot.db")
+
+	if err := repo.Backup(snapshot); err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	repo.SavePost(entities.Post{Title: "After the backup"})
+	// an existing snapshot is replaced
+	if err := repo.Backup(snapshot); err != nil {
+		t.Fatalf("Expected no error replacing the snapshot; Got: '%v'", err)
+	}
+
+	posts, _ := openRepo(t, snapshot).GetPosts()
+	if len(posts) != 2 || posts[1].Title != "After the backup" {
+		t.Fatalf("Expected the snapshot to have both posts; Got: '%v'", posts)
+	}
+	if _, err := os.Stat(snapshot + ".partial"); !os.IsNotExist(err) {
+		t.Fatalf("Expected no partial file to be left; Got: '%v'", err)
+	}
+}
+
+func TestBackup_FailsInsideATransaction(t *testing.T) {
+	dir := t.TempDir()
+	repo := openRepo(t, filepath.Join(dir, "posts.db"))
+
+	err := repo.WithinTx(func(tx interfaces.Repository) error {
+		return tx.(*db.SqliteRepo).Backup(filepath.Join(dir, "snapshot.db"))
+	})
+	if err != db.ErrBackupInTx {
+		t.Fatalf("Expected ErrBackupInTx; Got: '%v'", err)
+	}
+}
+
+func TestRestore_SwapsInTheSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "posts.db")
+	snapshot := filepath.Join(dir, "snapshot.db")
+	repo := openRepo(t, path)
+	repo.SavePost(entities.Post{Title: "Kept"})
+	repo.Backup(snapshot)
+	repo.SavePost(entities.Post{Title: "Lost"})
+
+	if err := db.Restore(snapshot, path); err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+
+	posts, _ := openRepo(t, path).GetPosts()
+	if len(posts) != 1 || posts[0].Title != "Kept" {
+		t.Fatalf("Expected only the snapshot's post; Got: '%v'", posts)
+	}
+}
+
+func TestRestore_RejectsBadSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "posts.db")
+	openRepo(t, path).SavePost(entities.Post{Title: "Untouched"})
+
+	garbage := filepath.Join(dir, "garbage.db")
+	os.WriteFile(garbage, []byte("this is not a database, just some bytes padding it out"), 0644)
+	empty := filepath.Join(dir, "empty.db")
+	os.WriteFile(empty, nil, 0644)
+
+	for _, snapshot := range []string{garbage, empty} {
+		if err := db.Restore(snapshot, path); !errors.Is(err, db.ErrBadSnapshot) {
+			t.Fatalf("Expected ErrBadSnapshot for %s; Got: '%v'", snapshot, err)
+		}
+	}
+	if err := db.Restore(filepath.Join(dir, "missing.db"), path); !os.IsNotExist(err) {
+		t.Fatalf("Expected a missing snapshot to fail; Got: '%v'", err)
+	}
+
+	post, _ := openRepo(t, path).GetPost(1)
+	if post.Title != "Untouched" {
+		t.Fatalf("Expected the database to be untouched; Got: '%v'", post)
+	}
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"database/sql"
+
+	"github.com/steve-kaufman/postsService/entities"
+)
+
+// createChangesTable keeps one row per post holding the sequence number of
+// its latest change. Triggers bump it on every insert, update and delete so
+// no write path can forget to, and INSERT OR REPLACE moves the row to a new
+// AUTOINCREMENT seq, which never reuses or goes back on a number. Writes
+// that only touch the ranking scores are not changes.
+func createChangesTable(conn *sql.DB) error {
+	return execAll(conn,
+		`CREATE TABLE IF NOT EXISTS changes (
+			seq INTEGER PRIMARY KEY AUTOINCREMENT,
+			post_id INTEGER NOT NULL UNIQUE,
+			deleted INTEGER NOT NULL DEFAULT 0
+		);`,
+		`CREATE TRIGGER IF NOT EXISTS posts_changed_insert AFTER INSERT ON posts BEGIN
+			INSERT OR REPLACE INTO changes (post_id, deleted) VALUES (NEW.id, 0);
+		END;`,
+		`CREATE TRIGGER IF NOT EXISTS posts_changed_update AFTER UPDATE OF title, content, likes, dislikes ON posts BEGIN
+			INSERT OR REPLACE INTO changes (post_id, deleted) VALUES (NEW.id, 0);
+		END;`,
+		`CREATE TRIGGER IF NOT EXISTS posts_changed_delete AFTER DELETE ON posts BEGIN
+			INSERT OR REPLACE INTO changes (post_id, deleted) VALUES (OLD.id, 1);
+		END;`,
+		// posts written before the table existed
+		`INSERT INTO changes (post_id)
+			SELECT id FROM posts WHERE id NOT IN (SELECT post_id FROM changes) ORDER BY id;`,
+	)
+}
+
+func (repo SqliteRepo) GetChangesSince(seq int64, limit int) ([]entities.Change, error) {
+	rows, err := repo.conn.Query(`SELECT changes.seq, changes.post_id, changes.deleted,
+		COALESCE(posts.slug, ''), COALESCE(posts.title, ''), COALESCE(posts.content, ''),
+		COALESCE(posts.likes, 0), COALESCE(posts.dislikes, 0)
+	FROM changes LEFT JOIN posts ON posts.id = changes.post_id
+	WHERE changes.seq > ?
+	ORDER BY changes.seq LIMIT ?`, seq, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []entities.Change{}
+	for rows.Next() {
+		var change entities.Change
+		post := &change.Post
+		if err := rows.Scan(&change.Seq, &post.ID, &change.Deleted,
+			&post.Slug, &post.Title, &post.Content, &post.Likes, &post.Dislikes); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
@@ -0,0 +1,88 @@
+package db_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/steve-kaufman/postsService/db"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/interfaces"
+)
+
+func changesSince(t *testing.T, repo *db.SqliteRepo, seq int64) []entities.Change {
+	t.Helper()
+	changes, err := repo.GetChangesSince(seq, 100)
+	if err != nil {
+		t.Fatalf("Expected no error reading changes; Got: '%v'", err)
+	}
+	return changes
+}
+
+func TestGetChangesSince_ReportsLatestStateOfEachPostInOrder(t *testing.T) {
+	repo := openRepo(t, filepath.Join(t.TempDir(), "posts.db"))
+
+	repo.SavePost(entities.Post{Title: "Foo"})                      // seq 1
+	repo.SavePost(entities.Post{Title: "Bar"})                      // seq 2
+	repo.UpdatePost(1, entities.Post{Title: "Foo", Content: "Baz"}) // seq 3
+	repo.SavePost(entities.Post{Title: "Qux"})                      // seq 4
+	repo.DeletePost(2)                                              // seq 5
+
+	expected := []entities.Change{
+		{Seq: 3, Post: entities.Post{ID: 1, Slug: "foo", Title: "Foo", Content: "Baz"}},
+		{Seq: 4, Post: entities.Post{ID: 3, Slug: "qux", Title: "Qux"}},
+		{Seq: 5, Post: entities.Post{ID: 2}, Deleted: true},
+	}
+	if diff := cmp.Diff(expected, changesSince(t, repo, 0)); diff != "" {
+		t.Fatalf("Expected one change per post, ordered by seq: \n%s", diff)
+	}
+	if diff := cmp.Diff(expected[1:], changesSince(t, repo, 3)); diff != "" {
+		t.Fatalf("Expected only changes after seq 3: \n%s", diff)
+	}
+	if changes := changesSince(t, repo, 5); len(changes) != 0 {
+		t.Fatalf("Expected no changes after the high-water mark; Got: '%v'", changes)
+	}
+}
+
+func TestGetChangesSince_RespectsLimit(t *testing.T) {
+	repo := openRepo(t, filepath.Join(t.TempDir(), "posts.db"))
+	for i := 0; i < 5; i++ {
+		repo.SavePost(entities.Post{Title: "Foo"})
+	}
+
+	changes, err := repo.GetChangesSince(1, 2)
+	if err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	if len(changes) != 2 || changes[0].Seq != 2 || changes[1].Seq != 3 {
+		t.Fatalf("Expected seqs 2 and 3; Got: '%v'", changes)
+	}
+}
+
+func TestGetChangesSince_IgnoresRolledBackWrites(t *testing.T) {
+	repo := openRepo(t, filepath.Join(t.TempDir(), "posts.db"))
+	repo.SavePost(entities.Post{Title: "Foo"})
+
+	repo.WithinTx(func(tx interfaces.Repository) error {
+		tx.DeletePost(1)
+		return errors.New("fn failed")
+	})
+
+	expected := []entities.Change{{Seq: 1, Post: entities.Post{ID: 1, Slug: "foo", Title: "Foo"}}}
+	if diff := cmp.Diff(expected, changesSince(t, repo, 0)); diff != "" {
+		t.Fatalf("Expected rolled back delete to leave no tombstone: \n%s", diff)
+	}
+}
+
+func TestGetChangesSince_TracksOutboxWrites(t *testing.T) {
+	repo := setupOutbox(t)
+
+	repo.SavePost(entities.Post{Title: "Foo"})
+	repo.DeletePost(1)
+
+	expected := []entities.Change{{Seq: 2, Post: entities.Post{ID: 1}, Deleted: true}}
+	if diff := cmp.Diff(expected, changesSince(t, repo, 0)); diff != "" {
+		t.Fatalf("Expected a tombstone for the deleted post: \n%s", diff)
+	}
+}
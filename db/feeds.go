@@ -0,0 +1,44 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/steve-kaufman/postsService/feeds"
+)
+
+// createFeedColumns adds updated_at, the last time a post's title or
+// content changed; votes leave it alone. It runs after the ranking columns
+// so older posts can start from their created_at.
+func createFeedColumns(conn *sql.DB) error {
+	if err := addColumn(conn, "posts", "updated_at", "INTEGER"); err != nil {
+		return err
+	}
+	_, err := conn.Exec(`UPDATE posts SET updated_at = created_at WHERE updated_at IS NULL;`)
+	return err
+}
+
+func (repo SqliteRepo) RecentPosts(limit int) ([]feeds.Entry, error) {
+	now := time.Now().UnixNano()
+	rows, err := repo.conn.Query(`SELECT id, COALESCE(slug, ''), title, content, likes, dislikes,
+		COALESCE(created_at, ?), COALESCE(updated_at, created_at, ?)
+	FROM posts ORDER BY created_at DESC, id DESC LIMIT ?`, now, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []feeds.Entry{}
+	for rows.Next() {
+		var entry feeds.Entry
+		post := &entry.Post
+		var published, updated int64
+		if err := rows.Scan(&post.ID, &post.Slug, &post.Title, &post.Content, &post.Likes, &post.Dislikes, &published, &updated); err != nil {
+			return nil, err
+		}
+		entry.Published = time.Unix(0, published)
+		entry.Updated = time.Unix(0, updated)
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,45 @@
+package db_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/steve-kaufman/postsService/entities"
+)
+
+func TestRecentPosts_ReturnsNewestFirstWithTimes(t *testing.T) {
+	repo := openRepo(t, filepath.Join(t.TempDir(), "posts.db"))
+	repo.SavePost(entities.Post{Title: "Foo"})
+	repo.SavePost(entities.Post{Title: "Bar"})
+	repo.SavePost(entities.Post{Title: "Baz"})
+
+	entries, err := repo.RecentPosts(2)
+	if err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	if len(entries) != 2 || entries[0].Post.ID != 3 || entries[1].Post.ID != 2 {
+		t.Fatalf("Expected posts 3 and 2; Got: '%v'", entries)
+	}
+	for _, entry := range entries {
+		if entry.Published.IsZero() || !entry.Updated.Equal(entry.Published) {
+			t.Fatalf("Expected a new post to be updated when published; Got: '%v'", entry)
+		}
+	}
+}
+
+func TestRecentPosts_OnlyMovesUpdatedForEdits(t *testing.T) {
+	repo := openRepo(t, filepath.Join(t.TempDir(), "posts.db"))
+	repo.SavePost(entities.Post{Title: "Foo"})
+
+	repo.UpdatePost(1, entities.Post{Title: "Foo", Likes: 1})
+	entries, _ := repo.RecentPosts(1)
+	if !entries[0].Updated.Equal(entries[0].Published) {
+		t.Fatalf("Expected a vote not to change updated; Got: '%v'", entries[0])
+	}
+
+	repo.UpdatePost(1, entities.Post{Title: "Bar", Likes: 1})
+	entries, _ = repo.RecentPosts(1)
+	if !entries[0].Updated.After(entries[0].Published) {
+		t.Fatalf("Expected an edit to change updated; Got: '%v'", entries[0])
+	}
+}
@@ -0,0 +1,155 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/events"
+	"github.com/steve-kaufman/postsService/outbox"
+)
+
+func createOutboxTable(conn *sql.DB) error {
+	return execAll(conn,
+		`CREATE TABLE IF NOT EXISTS outbox (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			event TEXT NOT NULL,
+			payload TEXT NOT NULL,
+			status TEXT NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			next_attempt_at INTEGER NOT NULL,
+			last_error TEXT NOT NULL DEFAULT '',
+			created_at INTEGER NOT NULL
+		);`,
+		`CREATE INDEX IF NOT EXISTS outbox_due ON outbox (status, next_attempt_at);`,
+	)
+}
+
+// EnableOutbox makes every post mutation also record its domain event in
+// the outbox table, in the same transaction, for an outbox.Relay to deliver
+func (repo *SqliteRepo) EnableOutbox() {
+	repo.outbox = true
+}
+
+// PendingOutbox returns the due messages. One whose payload can't be
+// decoded would fail every attempt, so it is marked dead and left out.
+func (repo SqliteRepo) PendingOutbox(now time.Time, limit int) ([]outbox.Message, error) {
+	messages, undecodable, err := repo.pendingOutbox(now, limit)
+	if err != nil {
+		return nil, err
+	}
+	for id, cause := range undecodable {
+		if err := repo.MarkOutboxFailed(id, outbox.StatusDead, time.Time{}, cause); err != nil {
+			return nil, err
+		}
+	}
+	return messages, nil
+}
+
+func (repo SqliteRepo) pendingOutbox(now time.Time, limit int) ([]outbox.Message, map[int64]error, error) {
+	rows, err := repo.conn.Query(`SELECT id, event, payload, attempts FROM outbox
+		WHERE status = ? AND next_attempt_at <= ?
+		ORDER BY id LIMIT ?`, outbox.StatusPending, now.UnixNano(), limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	messages := []outbox.Message{}
+	undecodable := map[int64]error{}
+	for rows.Next() {
+		var message outbox.Message
+		var name, payload string
+		if err := rows.Scan(&message.ID, &name, &payload, &message.Attempts); err != nil {
+			return nil, nil, err
+		}
+		if message.Event, err = events.Unmarshal(name, []byte(payload)); err != nil {
+			undecodable[message.ID] = err
+			continue
+		}
+		messages = append(messages, message)
+	}
+	return messages, undecodable, rows.Err()
+}
+
+func (repo SqliteRepo) MarkOutboxDelivered(id int64) error {
+	_, err := repo.conn.Exec(`UPDATE outbox SET status = ? WHERE id = ?`, outbox.StatusDelivered, id)
+	return err
+}
+
+func (repo SqliteRepo) MarkOutboxFailed(id int64, status string, retryAt time.Time, cause error) error {
+	_, err := repo.conn.Exec(`UPDATE outbox SET
+		status = ?,
+		attempts = attempts + 1,
+		next_attempt_at = ?,
+		last_error = ?
+	WHERE id = ?`, status, retryAt.UnixNano(), cause.Error(), id)
+	return err
+}
+
+func (repo SqliteRepo) recordEvent(event events.Event) error {
+	payload, err := events.Marshal(event)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UnixNano()
+	_, err = repo.conn.Exec(`INSERT INTO outbox (event, payload, status, next_attempt_at, created_at)
+		VALUES (?, ?, ?, ?, ?)`, event.EventName(), string(payload), outbox.StatusPending, now, now)
+	return err
+}
+
+func (repo SqliteRepo) savePostWithOutbox(post entities.Post) (entities.Post, error) {
+	var saved entities.Post
+	err := repo.inTx(func(tx *SqliteRepo) (err error) {
+		saved, err = tx.insertPost(post, false)
+		if err != nil {
+			return err
+		}
+		return tx.recordEvent(events.PostCreated{Post: saved})
+	})
+	if err != nil {
+		return entities.Post{}, err
+	}
+	return saved, nil
+}
+
+func (repo SqliteRepo) deletePostWithOutbox(id int) error {
+	return repo.inTx(func(tx *SqliteRepo) error {
+		post, err := tx.GetPost(id)
+		if err != nil {
+			return err
+		}
+		if err := tx.deletePost(id); err != nil {
+			return err
+		}
+		return tx.recordEvent(events.PostDeleted{Post: post})
+	})
+}
+
+func (repo SqliteRepo) updatePostWithOutbox(id int, data entities.Post) error {
+	return repo.inTx(func(tx *SqliteRepo) error {
+		before, err := tx.GetPost(id)
+		if err != nil {
+			return err
+		}
+		if err := tx.updatePost(id, data); err != nil {
+			return err
+		}
+		after, err := tx.GetPost(id)
+		if err != nil {
+			return err
+		}
+		return tx.recordEvent(updateEvent(before, after))
+	})
+}
+
+// updateEvent is the event the use cases publish for an update: one that
+// only changes the votes is a vote, since UpdatePost can't change them
+func updateEvent(before, after entities.Post) events.Event {
+	voted := before.Title == after.Title && before.Content == after.Content &&
+		(before.Likes != after.Likes || before.Dislikes != after.Dislikes)
+	if voted {
+		return events.PostVoted{Post: after, Liked: after.Likes > before.Likes}
+	}
+	return events.PostUpdated{Before: before, After: after}
+}
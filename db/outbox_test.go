@@ -0,0 +1,154 @@
+package db_test
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/steve-kaufman/postsService/db"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/events"
+	"github.com/steve-kaufman/postsService/interfaces"
+	"github.com/steve-kaufman/postsService/outbox"
+	"github.com/steve-kaufman/postsService/repotest"
+)
+
+func setupOutbox(t *testing.T) *db.SqliteRepo {
+	repo := openRepo(t, filepath.Join(t.TempDir(), "posts.db"))
+	repo.EnableOutbox()
+	return repo
+}
+
+func pendingEvents(t *testing.T, repo *db.SqliteRepo) []events.Event {
+	t.Helper()
+	messages, err := repo.PendingOutbox(time.Now(), 100)
+	if err != nil {
+		t.Fatalf("Expected no error reading outbox; Got: '%v'", err)
+	}
+	pending := []events.Event{}
+	for _, message := range messages {
+		pending = append(pending, message.Event)
+	}
+	return pending
+}
+
+func TestSqliteRepoWithOutbox_Conformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repository {
+		return setupOutbox(t)
+	})
+}
+
+func TestOutbox_RecordsEventForEveryMutation(t *testing.T) {
+	repo := setupOutbox(t)
+
+	repo.SavePost(entities.Post{Title: "Foo", Content: "Bar"})
+	repo.UpdatePost(1, entities.Post{Title: "Baz", Content: "Bar"})
+	repo.DeletePost(1)
+
+	expected := []events.Event{
+		events.PostCreated{Post: entities.Post{ID: 1, Slug: "foo", Title: "Foo", Content: "Bar"}},
+		events.PostUpdated{
+			Before: entities.Post{ID: 1, Slug: "foo", Title: "Foo", Content: "Bar"},
+			After:  entities.Post{ID: 1, Slug: "baz", Title: "Baz", Content: "Bar"},
+		},
+		events.PostDeleted{Post: entities.Post{ID: 1, Slug: "baz", Title: "Baz", Content: "Bar"}},
+	}
+	if diff := cmp.Diff(expected, pendingEvents(t, repo)); diff != "" {
+		t.Fatalf("Expected an outbox event per mutation: \n%s", diff)
+	}
+}
+
+func TestOutbox_DiscardsEvents_WhenTransactionRollsBack(t *testing.T) {
+	repo := setupOutbox(t)
+	repo.SavePost(entities.Post{Title: "Foo"})
+
+	repo.WithinTx(func(tx interfaces.Repository) error {
+		tx.UpdatePost(1, entities.Post{Title: "Bar"})
+		return errors.New("fn failed")
+	})
+
+	expected := []events.Event{events.PostCreated{Post: entities.Post{ID: 1, Slug: "foo", Title: "Foo"}}}
+	if diff := cmp.Diff(expected, pendingEvents(t, repo)); diff != "" {
+		t.Fatalf("Expected rolled back update to leave no event: \n%s", diff)
+	}
+}
+
+func TestOutbox_IsNotWritten_WhenDisabled(t *testing.T) {
+	repo := openRepo(t, filepath.Join(t.TempDir(), "posts.db"))
+
+	repo.SavePost(entities.Post{Title: "Foo"})
+
+	if pending := pendingEvents(t, repo); len(pending) != 0 {
+		t.Fatalf("Expected no outbox events; Got: '%v'", pending)
+	}
+}
+
+func TestMarkOutboxFailed_DelaysOrStopsRetries(t *testing.T) {
+	repo := setupOutbox(t)
+	repo.SavePost(entities.Post{Title: "Foo"})
+	repo.SavePost(entities.Post{Title: "Bar"})
+	now := time.Now()
+
+	repo.MarkOutboxFailed(1, outbox.StatusPending, now.Add(time.Minute), errors.New("failed"))
+	repo.MarkOutboxFailed(2, outbox.StatusDead, time.Time{}, errors.New("failed"))
+
+	if messages, _ := repo.PendingOutbox(now, 100); len(messages) != 0 {
+		t.Fatalf("Expected no messages due yet; Got: '%v'", messages)
+	}
+	messages, _ := repo.PendingOutbox(now.Add(time.Minute), 100)
+	if len(messages) != 1 || messages[0].ID != 1 || messages[0].Attempts != 1 {
+		t.Fatalf("Expected message 1 to be due after one attempt; Got: '%v'", messages)
+	}
+}
+
+func TestOutbox_RecordsVotesAsPostVoted(t *testing.T) {
+	repo := setupOutbox(t)
+	repo.SavePost(entities.Post{Title: "Foo"})
+
+	repo.UpdatePost(1, entities.Post{Title: "Foo", Likes: 1})
+	repo.UpdatePost(1, entities.Post{Title: "Foo", Likes: 1, Dislikes: 1})
+
+	expected := []events.Event{
+		events.PostCreated{Post: entities.Post{ID: 1, Slug: "foo", Title: "Foo"}},
+		events.PostVoted{Post: entities.Post{ID: 1, Slug: "foo", Title: "Foo", Likes: 1}, Liked: true},
+		events.PostVoted{Post: entities.Post{ID: 1, Slug: "foo", Title: "Foo", Likes: 1, Dislikes: 1}, Liked: false},
+	}
+	if diff := cmp.Diff(expected, pendingEvents(t, repo)); diff != "" {
+		t.Fatalf("Expected votes to be recorded as PostVoted: \n%s", diff)
+	}
+}
+
+func TestPendingOutbox_DeadLettersUndecodableMessages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "posts.db")
+	repo := openRepo(t, path)
+	repo.EnableOutbox()
+	conn, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_, err = conn.Exec(`INSERT INTO outbox (event, payload, status, next_attempt_at, created_at)
+		VALUES ('post.created', '{not json', ?, 0, 0)`, outbox.StatusPending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.SavePost(entities.Post{Title: "Foo"})
+	recorder := new(events.Recorder)
+
+	if _, err := outbox.NewRelay(repo, outbox.ToPublisher(recorder)).RelayOnce(); err != nil {
+		t.Fatalf("Expected no error relaying; Got: '%v'", err)
+	}
+
+	expected := []events.Event{events.PostCreated{Post: entities.Post{ID: 1, Slug: "foo", Title: "Foo"}}}
+	if diff := cmp.Diff(expected, recorder.Events()); diff != "" {
+		t.Fatalf("Expected the message behind the bad one to be delivered: \n%s", diff)
+	}
+	var status, lastError string
+	conn.QueryRow(`SELECT status, last_error FROM outbox WHERE id = 1`).Scan(&status, &lastError)
+	if status != outbox.StatusDead || lastError == "" {
+		t.Fatalf("Expected the bad message to be dead with its error; Got: '%s', '%s'", status, lastError)
+	}
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/ranking"
+)
+
+// sortOrders are the ORDER BY clauses for each sort, each matching an index
+var sortOrders = map[ranking.Sort]string{
+	ranking.SortNew:           "created_at DESC, id DESC",
+	ranking.SortHot:           "hot DESC, id",
+	ranking.SortTop:           "top DESC, id",
+	ranking.SortControversial: "controversial DESC, id",
+}
+
+// createRankingColumns adds the materialized scores to posts, indexes them
+// and scores any posts written before they existed
+func createRankingColumns(conn *sql.DB) error {
+	for _, column := range [][2]string{
+		{"created_at", "INTEGER"},
+		{"hot", "REAL"},
+		{"top", "REAL"},
+		{"controversial", "REAL"},
+	} {
+		if err := addColumn(conn, "posts", column[0], column[1]); err != nil {
+			return err
+		}
+	}
+	err := execAll(conn,
+		`CREATE INDEX IF NOT EXISTS posts_new ON posts (created_at DESC, id DESC);`,
+		`CREATE INDEX IF NOT EXISTS posts_hot ON posts (hot DESC, id);`,
+		`CREATE INDEX IF NOT EXISTS posts_top ON posts (top DESC, id);`,
+		`CREATE INDEX IF NOT EXISTS posts_controversial ON posts (controversial DESC, id);`,
+	)
+	if err != nil {
+		return err
+	}
+
+	if _, err := conn.Exec(`UPDATE posts SET created_at = ? WHERE created_at IS NULL;`, time.Now().UnixNano()); err != nil {
+		return err
+	}
+	rows, err := conn.Query(`SELECT id, COALESCE(title, ''), COALESCE(content, ''), COALESCE(likes, 0), COALESCE(dislikes, 0), created_at
+		FROM posts WHERE hot IS NULL;`)
+	if err != nil {
+		return err
+	}
+	type unscored struct {
+		post    entities.Post
+		created int64
+	}
+	var posts []unscored
+	for rows.Next() {
+		var p unscored
+		if err := rows.Scan(&p.post.ID, &p.post.Title, &p.post.Content, &p.post.Likes, &p.post.Dislikes, &p.created); err != nil {
+			rows.Close()
+			return err
+		}
+		posts = append(posts, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	repo := SqliteRepo{conn: conn}
+	for _, p := range posts {
+		if err := repo.refreshScores(p.post, time.Unix(0, p.created)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (repo SqliteRepo) GetSortedPosts(by ranking.Sort) ([]entities.Post, error) {
+	order, ok := sortOrders[by]
+	if !ok {
+		return nil, ranking.ErrUnknownSort
+	}
+	rows, err := repo.conn.Query(`SELECT ` + postColumns + ` FROM posts ORDER BY ` + order)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return mapRowsToPosts(rows)
+}
+
+// refreshScores stores the scores of a post written without them
+func (repo SqliteRepo) refreshScores(post entities.Post, created time.Time) error {
+	_, err := repo.conn.Exec(`UPDATE posts SET
+		created_at = ?,
+		hot = ?,
+		top = ?,
+		controversial = ?
+	WHERE id = ?`,
+		created.UnixNano(),
+		ranking.Hot(post, created),
+		ranking.Top(post),
+		ranking.Controversial(post),
+		post.ID,
+	)
+	return err
+}
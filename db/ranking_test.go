@@ -0,0 +1,101 @@
+package db_test
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/steve-kaufman/postsService/db"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/ranking"
+)
+
+func sortedIDs(t *testing.T, repo *db.SqliteRepo, by ranking.Sort) []int {
+	t.Helper()
+	posts, err := repo.GetSortedPosts(by)
+	if err != nil {
+		t.Fatalf("Expected no error sorting by %s; Got: '%v'", by, err)
+	}
+	ids := []int{}
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+	return ids
+}
+
+func TestGetSortedPosts_OrdersByMaterializedScores(t *testing.T) {
+	repo := openRepo(t, filepath.Join(t.TempDir(), "posts.db"))
+	repo.SavePost(entities.Post{Title: "Loved", Likes: 30})
+	repo.SavePost(entities.Post{Title: "Divisive", Likes: 20, Dislikes: 20})
+	repo.SavePost(entities.Post{Title: "Disliked", Dislikes: 5})
+
+	testCases := map[ranking.Sort][]int{
+		ranking.SortNew:           {3, 2, 1},
+		ranking.SortHot:           {1, 2, 3},
+		ranking.SortTop:           {1, 2, 3},
+		ranking.SortControversial: {2, 1, 3},
+	}
+	for by, expected := range testCases {
+		if diff := cmp.Diff(expected, sortedIDs(t, repo, by)); diff != "" {
+			t.Fatalf("Expected %s order: \n%s", by, diff)
+		}
+	}
+}
+
+func TestGetSortedPosts_ReRanksPostsOnVote(t *testing.T) {
+	repo := openRepo(t, filepath.Join(t.TempDir(), "posts.db"))
+	repo.SavePost(entities.Post{Title: "Foo", Likes: 3})
+	repo.SavePost(entities.Post{Title: "Bar", Likes: 1})
+
+	repo.UpdatePost(2, entities.Post{Title: "Bar", Likes: 10})
+
+	if diff := cmp.Diff([]int{2, 1}, sortedIDs(t, repo, ranking.SortTop)); diff != "" {
+		t.Fatalf("Expected the voted post to move up: \n%s", diff)
+	}
+	if diff := cmp.Diff([]int{2, 1}, sortedIDs(t, repo, ranking.SortNew)); diff != "" {
+		t.Fatalf("Expected an update to keep the creation time: \n%s", diff)
+	}
+}
+
+func TestGetSortedPosts_UsesAnIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "posts.db")
+	openRepo(t, path)
+	conn, _ := sql.Open("sqlite3", path)
+	defer conn.Close()
+
+	orders := map[string]string{
+		"new":           "created_at DESC, id DESC",
+		"hot":           "hot DESC, id",
+		"top":           "top DESC, id",
+		"controversial": "controversial DESC, id",
+	}
+	for by, order := range orders {
+		rows, err := conn.Query(`EXPLAIN QUERY PLAN SELECT id, title, content, likes, dislikes FROM posts ORDER BY ` + order)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var plan []string
+		for rows.Next() {
+			var id, parent, unused int
+			var detail string
+			rows.Scan(&id, &parent, &unused, &detail)
+			plan = append(plan, detail)
+		}
+		rows.Close()
+
+		if !strings.Contains(fmt.Sprint(plan), "USING INDEX posts_"+by) {
+			t.Fatalf("Expected %s to scan its index; Got plan: '%v'", by, plan)
+		}
+	}
+}
+
+func TestGetSortedPosts_RejectsUnknownSort(t *testing.T) {
+	repo := openRepo(t, filepath.Join(t.TempDir(), "posts.db"))
+
+	if _, err := repo.GetSortedPosts("random"); err != ranking.ErrUnknownSort {
+		t.Fatalf("Expected ErrUnknownSort; Got: '%v'", err)
+	}
+}
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"database/sql"
+	"strconv"
+
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/useCases"
+)
+
+// createSlugColumns adds a unique slug to posts and a table of the slugs a
+// post had before its title changed, then gives older posts their slugs
+func createSlugColumns(conn *sql.DB) error {
+	if err := addColumn(conn, "posts", "slug", "TEXT"); err != nil {
+		return err
+	}
+	err := execAll(conn,
+		`CREATE UNIQUE INDEX IF NOT EXISTS posts_slug ON posts (slug);`,
+		`CREATE TABLE IF NOT EXISTS slug_aliases (
+			slug TEXT PRIMARY KEY,
+			post_id INTEGER NOT NULL
+		);`,
+		`CREATE INDEX IF NOT EXISTS slug_aliases_post ON slug_aliases (post_id);`,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := conn.Query(`SELECT id, COALESCE(title, '') FROM posts WHERE slug IS NULL ORDER BY id;`)
+	if err != nil {
+		return err
+	}
+	var posts []entities.Post
+	for rows.Next() {
+		var post entities.Post
+		if err := rows.Scan(&post.ID, &post.Title); err != nil {
+			rows.Close()
+			return err
+		}
+		posts = append(posts, post)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	repo := SqliteRepo{conn: conn}
+	for _, post := range posts {
+		slug, err := repo.uniqueSlug(post.Title, post.ID)
+		if err != nil {
+			return err
+		}
+		if _, err := conn.Exec(`UPDATE posts SET slug = ? WHERE id = ?`, slug, post.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPostBySlug returns the post with slug, or whose title used to give it
+// slug, in which case the post's Slug differs from the one asked for
+func (repo SqliteRepo) GetPostBySlug(slug string) (entities.Post, error) {
+	var id int
+	err := repo.conn.QueryRow(`SELECT id FROM posts WHERE slug = ?
+		UNION ALL SELECT post_id FROM slug_aliases WHERE slug = ?
+		LIMIT 1`, slug, slug).Scan(&id)
+	if err == sql.ErrNoRows {
+		return entities.Post{}, useCases.ErrNotFound
+	}
+	if err != nil {
+		return entities.Post{}, err
+	}
+	return repo.GetPost(id)
+}
+
+// uniqueSlug returns the slug for title, numbered from -2 if another post
+// has or had it. id's own slugs are free for it to take back. It must run
+// in the transaction that stores the slug.
+func (repo SqliteRepo) uniqueSlug(title string, id int) (string, error) {
+	base := entities.Slugify(title)
+	for n := 1; ; n++ {
+		slug := base
+		if n > 1 {
+			slug = base + "-" + strconv.Itoa(n)
+		}
+		var taken bool
+		err := repo.conn.QueryRow(`SELECT EXISTS (SELECT 1 FROM posts WHERE slug = ? AND id != ?)
+			OR EXISTS (SELECT 1 FROM slug_aliases WHERE slug = ? AND post_id != ?)`,
+			slug, id, slug, id).Scan(&taken)
+		if err != nil || !taken {
+			return slug, err
+		}
+	}
+}
+
+// changeSlug gives post id a new slug for title if the title's slug
+// differs from the one it had, keeping the old slug as an alias
+func (repo SqliteRepo) changeSlug(id int, oldTitle, title string, oldSlug sql.NullString) error {
+	if oldSlug.Valid && entities.Slugify(title) == entities.Slugify(oldTitle) {
+		return nil
+	}
+	slug, err := repo.uniqueSlug(title, id)
+	if err != nil || (oldSlug.Valid && slug == oldSlug.String) {
+		return err
+	}
+
+	if _, err := repo.conn.Exec(`DELETE FROM slug_aliases WHERE slug = ?`, slug); err != nil {
+		return err
+	}
+	if oldSlug.Valid {
+		if _, err := repo.conn.Exec(`INSERT INTO slug_aliases (slug, post_id) VALUES (?, ?)`, oldSlug.String, id); err != nil {
+			return err
+		}
+	}
+	_, err = repo.conn.Exec(`UPDATE posts SET slug = ? WHERE id = ?`, slug, id)
+	return err
+}
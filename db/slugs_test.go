@@ -0,0 +1,91 @@
+package db_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/steve-kaufman/postsService/db"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/useCases"
+)
+
+func expectSlug(t *testing.T, repo *db.SqliteRepo, slug string, id int, current string) {
+	t.Helper()
+	post, err := repo.GetPostBySlug(slug)
+	if err != nil {
+		t.Fatalf("Expected '%s' to find a post; Got: '%v'", slug, err)
+	}
+	if post.ID != id || post.Slug != current {
+		t.Fatalf("Expected '%s' to find post %d with slug '%s'; Got: '%v'", slug, id, current, post)
+	}
+}
+
+func TestSavePost_NumbersRepeatedSlugs(t *testing.T) {
+	repo := openRepo(t, filepath.Join(t.TempDir(), "posts.db"))
+	repo.SavePost(entities.Post{Title: "Hello, World"})
+	repo.SavePost(entities.Post{Title: "hello world!"})
+	repo.SavePost(entities.Post{Title: "Héllo wörld", Slug: "ignored"})
+
+	expectSlug(t, repo, "hello-world", 1, "hello-world")
+	expectSlug(t, repo, "hello-world-2", 2, "hello-world-2")
+	expectSlug(t, repo, "hello-woerld", 3, "hello-woerld")
+	if post, _ := repo.GetPost(2); post.Slug != "hello-world-2" {
+		t.Fatalf("Expected GetPost to include the slug; Got: '%v'", post)
+	}
+}
+
+func TestGetPostBySlug_ReturnsErrNotFound(t *testing.T) {
+	repo := openRepo(t, filepath.Join(t.TempDir(), "posts.db"))
+	repo.SavePost(entities.Post{Title: "Foo"})
+
+	if _, err := repo.GetPostBySlug("bar"); err != useCases.ErrNotFound {
+		t.Fatalf("Expected ErrNotFound; Got: '%v'", err)
+	}
+}
+
+func TestUpdatePost_KeepsOldSlugAsAlias(t *testing.T) {
+	repo := openRepo(t, filepath.Join(t.TempDir(), "posts.db"))
+	repo.SavePost(entities.Post{Title: "Foo"})
+
+	repo.UpdatePost(1, entities.Post{Title: "Bar"})
+	expectSlug(t, repo, "bar", 1, "bar")
+	expectSlug(t, repo, "foo", 1, "bar")
+
+	// the alias still belongs to post 1, so a new "Foo" is numbered
+	repo.SavePost(entities.Post{Title: "Foo"})
+	expectSlug(t, repo, "foo-2", 2, "foo-2")
+
+	// and post 1 can take it back
+	repo.UpdatePost(1, entities.Post{Title: "Foo"})
+	expectSlug(t, repo, "foo", 1, "foo")
+	expectSlug(t, repo, "bar", 1, "foo")
+}
+
+func TestUpdatePost_KeepsSlugWhenTitleSlugifiesTheSame(t *testing.T) {
+	repo := openRepo(t, filepath.Join(t.TempDir(), "posts.db"))
+	repo.SavePost(entities.Post{Title: "Foo"})
+	repo.SavePost(entities.Post{Title: "Foo"})
+
+	repo.UpdatePost(2, entities.Post{Title: "FOO!", Likes: 1})
+
+	expectSlug(t, repo, "foo-2", 2, "foo-2")
+	if _, err := repo.GetPostBySlug("foo-3"); err != useCases.ErrNotFound {
+		t.Fatalf("Expected no new slug; Got: '%v'", err)
+	}
+}
+
+func TestDeletePost_FreesItsSlugs(t *testing.T) {
+	repo := openRepo(t, filepath.Join(t.TempDir(), "posts.db"))
+	repo.SavePost(entities.Post{Title: "Foo"})
+	repo.UpdatePost(1, entities.Post{Title: "Bar"})
+
+	repo.DeletePost(1)
+	for _, slug := range []string{"foo", "bar"} {
+		if _, err := repo.GetPostBySlug(slug); err != useCases.ErrNotFound {
+			t.Fatalf("Expected '%s' to be gone; Got: '%v'", slug, err)
+		}
+	}
+
+	repo.SavePost(entities.Post{Title: "Bar"})
+	expectSlug(t, repo, "bar", 2, "bar")
+}
@@ -2,40 +2,223 @@ package db
 
 import (
 	"database/sql"
-	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/interfaces"
+	"github.com/steve-kaufman/postsService/ranking"
 	"github.com/steve-kaufman/postsService/useCases"
 )
 
+// sqlConn is satisfied by both *sql.DB and *sql.Tx
+type sqlConn interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
 type SqliteRepo struct {
-	conn *sql.DB
+	db     *sql.DB
+	conn   sqlConn
+	outbox bool
+}
+
+// Options configure how a database is opened
+type Options struct {
+	// WAL switches the database to write-ahead logging, so readers don't
+	// wait for writers. The mode is stored in the file and outlives the
+	// connection.
+	WAL bool
+	// BusyTimeout is how long a statement waits for a lock before failing
+	// with SQLITE_BUSY. Zero keeps the driver's default of five seconds.
+	BusyTimeout time.Duration
 }
 
-func NewSqliteRepo(path string) *SqliteRepo {
-	os.Create(path)
+// NewSqliteRepo opens the database at path, creating it if needed, and
+// brings its schema up to date
+func NewSqliteRepo(path string) (*SqliteRepo, error) {
+	return OpenSqliteRepo(path, Options{})
+}
 
-	conn, err := sql.Open("sqlite3", path)
+// OpenSqliteRepo is NewSqliteRepo with options
+func OpenSqliteRepo(path string, options Options) (*SqliteRepo, error) {
+	// _txlock=immediate makes every transaction begin with BEGIN IMMEDIATE,
+	// taking the write lock up front instead of upgrading after the first read
+	dsn := path + "?_txlock=immediate"
+	if options.WAL {
+		dsn += "&_journal_mode=WAL"
+	}
+	if options.BusyTimeout > 0 {
+		dsn += "&_busy_timeout=" + strconv.FormatInt(options.BusyTimeout.Milliseconds(), 10)
+	}
+	conn, err := sql.Open("sqlite3", dsn)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
+	if err := migrate(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	repo := new(SqliteRepo)
+	repo.db = conn
+	repo.conn = conn
+
+	return repo, nil
+}
 
-	conn.Exec(`CREATE TABLE IF NOT EXISTS posts (
-		id INTEGER PRIMARY KEY,
+// migrations bring the schema of an existing posts table up to date. Each
+// is safe to run again.
+var migrations = []func(*sql.DB) error{
+	createRankingColumns,
+	createFeedColumns,
+	createSlugColumns,
+	createChangesTable,
+	createOutboxTable,
+}
+
+func migrate(conn *sql.DB) error {
+	// AUTOINCREMENT so the ID of a deleted post is never handed out again
+	_, err := conn.Exec(`CREATE TABLE IF NOT EXISTS posts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		title TEXT,
 		content TEXT,
 		likes INTEGER,
 		dislikes INTEGER
 	);`)
+	if err != nil {
+		return err
+	}
+	if err := runMigrations(conn); err != nil {
+		return err
+	}
+	rebuilt, err := makeIDsAutoincrement(conn)
+	if err != nil || !rebuilt {
+		return err
+	}
+	// dropping the old table dropped its indexes and triggers
+	return runMigrations(conn)
+}
 
-	repo := new(SqliteRepo)
-	repo.conn = conn
+func runMigrations(conn *sql.DB) error {
+	for _, migration := range migrations {
+		if err := migration(conn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// makeIDsAutoincrement rebuilds a posts table created before its IDs were
+// AUTOINCREMENT, which SQLite can't add to an existing table. The rebuilt
+// table has the same columns and rows.
+func makeIDsAutoincrement(conn *sql.DB) (bool, error) {
+	var schema string
+	err := conn.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'posts'`).Scan(&schema)
+	if err != nil || strings.Contains(strings.ToUpper(schema), "AUTOINCREMENT") {
+		return false, err
+	}
+
+	rows, err := conn.Query(`SELECT name, type, "notnull", dflt_value FROM pragma_table_info('posts') WHERE name != 'id' ORDER BY cid`)
+	if err != nil {
+		return false, err
+	}
+	columns := []string{"id"}
+	definitions := []string{"id INTEGER PRIMARY KEY AUTOINCREMENT"}
+	for rows.Next() {
+		var name, kind string
+		var notNull bool
+		var defaultValue sql.NullString
+		if err := rows.Scan(&name, &kind, &notNull, &defaultValue); err != nil {
+			rows.Close()
+			return false, err
+		}
+		definition := name + " " + kind
+		if notNull {
+			definition += " NOT NULL"
+		}
+		if defaultValue.Valid {
+			definition += " DEFAULT " + defaultValue.String
+		}
+		columns = append(columns, name)
+		definitions = append(definitions, definition)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	list := strings.Join(columns, ", ")
+	for _, statement := range []string{
+		`CREATE TABLE posts_rebuilt (` + strings.Join(definitions, ", ") + `);`,
+		`INSERT INTO posts_rebuilt (` + list + `) SELECT ` + list + ` FROM posts;`,
+		`DROP TABLE posts;`,
+		`ALTER TABLE posts_rebuilt RENAME TO posts;`,
+	} {
+		if _, err := tx.Exec(statement); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+// addColumn adds column to table unless an earlier open already did
+func addColumn(conn *sql.DB, table, column, definition string) error {
+	var exists bool
+	err := conn.QueryRow(`SELECT EXISTS (SELECT 1 FROM pragma_table_info(?) WHERE name = ?)`, table, column).
+		Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+	_, err = conn.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + column + ` ` + definition + `;`)
+	return err
+}
+
+// execAll runs each statement, stopping at the first that fails
+func execAll(conn *sql.DB, statements ...string) error {
+	for _, statement := range statements {
+		if _, err := conn.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (repo SqliteRepo) WithinTx(fn func(repo interfaces.Repository) error) error {
+	if repo.db == nil {
+		// already inside a transaction
+		return fn(&repo)
+	}
+
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	return repo
+	if err := fn(&SqliteRepo{conn: tx, outbox: repo.outbox}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// inTx is WithinTx for the repository's own writes, which need the
+// transaction as a SqliteRepo
+func (repo SqliteRepo) inTx(fn func(tx *SqliteRepo) error) error {
+	return repo.WithinTx(func(tx interfaces.Repository) error {
+		return fn(tx.(*SqliteRepo))
+	})
 }
 
 func (repo SqliteRepo) GetPosts() ([]entities.Post, error) {
-	rows, err := repo.conn.Query(`SELECT id, title, content, likes, dislikes FROM posts;`)
+	rows, err := repo.conn.Query(`SELECT ` + postColumns + ` FROM posts;`)
 	if err != nil {
 		return nil, err
 	}
@@ -45,40 +228,156 @@ func (repo SqliteRepo) GetPosts() ([]entities.Post, error) {
 }
 
 func (repo SqliteRepo) GetPost(id int) (entities.Post, error) {
-	row := repo.conn.QueryRow(`SELECT id, title, content, likes, dislikes FROM posts WHERE id=?`, id)
+	row := repo.conn.QueryRow(`SELECT `+postColumns+` FROM posts WHERE id=?`, id)
 	post, err := mapToPost(row)
 	if err == sql.ErrNoRows {
 		return entities.Post{}, useCases.ErrNotFound
 	}
-	return post, nil
+	return post, err
+}
+
+func (repo SqliteRepo) SavePost(post entities.Post) (entities.Post, error) {
+	if repo.outbox {
+		return repo.savePostWithOutbox(post)
+	}
+	// in a transaction so no other post can take the slug in between
+	var saved entities.Post
+	err := repo.inTx(func(tx *SqliteRepo) (err error) {
+		saved, err = tx.insertPost(post, false)
+		return err
+	})
+	if err != nil {
+		return entities.Post{}, err
+	}
+	return saved, nil
+}
+
+func (repo SqliteRepo) DeletePost(id int) error {
+	if repo.outbox {
+		return repo.deletePostWithOutbox(id)
+	}
+	return repo.inTx(func(tx *SqliteRepo) error {
+		return tx.deletePost(id)
+	})
 }
 
-func (repo SqliteRepo) SavePost(post entities.Post) error {
-	_, err := repo.conn.Exec(`INSERT INTO posts (title, content, likes, dislikes) VALUES (?, ?, ?, ?);`,
+func (repo SqliteRepo) UpdatePost(id int, data entities.Post) error {
+	if repo.outbox {
+		return repo.updatePostWithOutbox(id, data)
+	}
+	return repo.inTx(func(tx *SqliteRepo) error {
+		return tx.updatePost(id, data)
+	})
+}
+
+// insertPost returns post as stored, with its ID and slug. Unless keepID
+// is set, post.ID is ignored and the next free ID is used.
+func (repo SqliteRepo) insertPost(post entities.Post, keepID bool) (entities.Post, error) {
+	var id interface{}
+	if keepID {
+		id = post.ID
+	}
+
+	slug, err := repo.uniqueSlug(post.Title, 0)
+	if err != nil {
+		return entities.Post{}, err
+	}
+
+	created := time.Now()
+	result, err := repo.conn.Exec(`INSERT INTO posts
+		(id, slug, title, content, likes, dislikes, created_at, updated_at, hot, top, controversial)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`,
+		id,
+		slug,
 		post.Title,
 		post.Content,
 		post.Likes,
 		post.Dislikes,
+		created.UnixNano(),
+		created.UnixNano(),
+		ranking.Hot(post, created),
+		ranking.Top(post),
+		ranking.Controversial(post),
 	)
-	return err
+	if err != nil {
+		return entities.Post{}, err
+	}
+	inserted, err := result.LastInsertId()
+	if err != nil {
+		return entities.Post{}, err
+	}
+	post.ID = int(inserted)
+	post.Slug = slug
+	post.ContentHTML = ""
+	return post, nil
 }
 
-func (repo SqliteRepo) DeletePost(id int) error {
-	_, err := repo.conn.Exec("DELETE FROM posts WHERE id=?", id)
+func (repo SqliteRepo) deletePost(id int) error {
+	result, err := repo.conn.Exec("DELETE FROM posts WHERE id=?", id)
+	if err != nil {
+		return err
+	}
+	if err := requireAffectedRow(result); err != nil {
+		return err
+	}
+	_, err = repo.conn.Exec("DELETE FROM slug_aliases WHERE post_id=?", id)
 	return err
 }
 
-func (repo SqliteRepo) UpdatePost(id int, data entities.Post) error {
-	if _, err := repo.GetPost(id); err != nil {
+func (repo SqliteRepo) updatePost(id int, data entities.Post) error {
+	var createdAt sql.NullInt64
+	var title string
+	var slug sql.NullString
+	err := repo.conn.QueryRow(`SELECT created_at, COALESCE(title, ''), slug FROM posts WHERE id = ?`, id).
+		Scan(&createdAt, &title, &slug)
+	if err == sql.ErrNoRows {
+		return useCases.ErrNotFound
+	}
+	if err != nil {
 		return err
 	}
-	_, err := repo.conn.Exec(`UPDATE posts SET
+	// posts inserted around the repository may have no creation time
+	created := time.Now()
+	if createdAt.Valid {
+		created = time.Unix(0, createdAt.Int64)
+	}
+
+	// the scores are rewritten with the counts so a vote re-ranks its post,
+	// and updated_at only moves when the title or content actually changes
+	result, err := repo.conn.Exec(`UPDATE posts SET
+		updated_at = CASE WHEN title IS NOT ? OR content IS NOT ? THEN ? ELSE updated_at END,
 		title = ?,
 		content = ?,
 		likes = ?,
-		dislikes = ?
-	`, data.Title, data.Content, data.Likes, data.Dislikes)
-	return err
+		dislikes = ?,
+		created_at = ?,
+		hot = ?,
+		top = ?,
+		controversial = ?
+	WHERE id = ?`,
+		data.Title, data.Content, time.Now().UnixNano(),
+		data.Title, data.Content, data.Likes, data.Dislikes,
+		created.UnixNano(), ranking.Hot(data, created), ranking.Top(data), ranking.Controversial(data),
+		id,
+	)
+	if err != nil {
+		return err
+	}
+	if err := requireAffectedRow(result); err != nil {
+		return err
+	}
+	return repo.changeSlug(id, title, data.Title, slug)
+}
+
+func requireAffectedRow(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return useCases.ErrNotFound
+	}
+	return nil
 }
 
 func mapRowsToPosts(rows *sql.Rows) ([]entities.Post, error) {
@@ -90,6 +389,9 @@ func mapRowsToPosts(rows *sql.Rows) ([]entities.Post, error) {
 		}
 		posts = append(posts, post)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 	return posts, nil
 }
 
@@ -97,9 +399,12 @@ type RowScanner interface {
 	Scan(dest ...interface{}) error
 }
 
+// postColumns are the columns mapToPost scans
+const postColumns = "id, COALESCE(slug, ''), title, content, likes, dislikes"
+
 func mapToPost(row RowScanner) (entities.Post, error) {
 	var post entities.Post
-	err := row.Scan(&post.ID, &post.Title, &post.Content, &post.Likes, &post.Dislikes)
+	err := row.Scan(&post.ID, &post.Slug, &post.Title, &post.Content, &post.Likes, &post.Dislikes)
 	if err != nil {
 		return entities.Post{}, err
 	}
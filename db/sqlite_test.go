@@ -2,39 +2,69 @@ package db_test
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/steve-kaufman/postsService/db"
 	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/interfaces"
+	"github.com/steve-kaufman/postsService/ranking"
+	"github.com/steve-kaufman/postsService/repotest"
 	"github.com/steve-kaufman/postsService/useCases"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
-var testDBPath = "./test.db"
+// testDBPath is where setup opens the test's database
+func testDBPath(t *testing.T) string {
+	return filepath.Join(t.TempDir(), "test.db")
+}
 
-func setup() (*db.SqliteRepo, *sql.DB) {
-	os.Remove(testDBPath)
-	repo := db.NewSqliteRepo(testDBPath)
-	conn, _ := sql.Open("sqlite3", testDBPath)
+func setup(t *testing.T) (*db.SqliteRepo, *sql.DB) {
+	path := testDBPath(t)
+	repo := openRepo(t, path)
+	conn, _ := sql.Open("sqlite3", path)
+	t.Cleanup(func() { conn.Close() })
 	return repo, conn
 }
 
+func openRepo(t *testing.T, path string) *db.SqliteRepo {
+	t.Helper()
+	repo, err := db.NewSqliteRepo(path)
+	if err != nil {
+		t.Fatalf("Expected no error opening %s; Got: '%v'", path, err)
+	}
+	return repo
+}
+
 func TestInstantiatingRepo_CreatesDBFile(t *testing.T) {
-	setup()
+	path := testDBPath(t)
+	openRepo(t, path)
 
-	_, err := os.Stat(testDBPath)
+	_, err := os.Stat(path)
 
 	if err != nil {
 		t.Fatal("Didn't create db file")
 	}
 }
 
+func TestInstantiatingRepo_KeepsExistingPosts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "posts.db")
+	openRepo(t, path).SavePost(entities.Post{Title: "Foo"})
+
+	post, err := openRepo(t, path).GetPost(1)
+
+	if err != nil || post.Title != "Foo" {
+		t.Fatalf("Expected post 1 to survive reopening; Got: '%v', '%v'", post, err)
+	}
+}
+
 func TestInstantiatingRepo_GeneratesTable(t *testing.T) {
-	_, conn := setup()
+	_, conn := setup(t)
 
 	row, err := conn.Query(`SELECT name FROM sqlite_master WHERE type='table' AND name='posts';`)
 	if err != nil {
@@ -47,7 +77,7 @@ func TestInstantiatingRepo_GeneratesTable(t *testing.T) {
 }
 
 func TestAfterInstantiatingRepo_CanInsertPost(t *testing.T) {
-	_, conn := setup()
+	_, conn := setup(t)
 
 	_, err := conn.Exec(`INSERT INTO posts (title, content, likes, dislikes) VALUES('Foo', 'Bar', 2, 1);`)
 	if err != nil {
@@ -80,7 +110,7 @@ var examplePosts = []entities.Post{
 }
 
 func TestGetPosts_ReturnsAllPosts(t *testing.T) {
-	repo, conn := setup()
+	repo, conn := setup(t)
 
 	insertExamplePosts(conn)
 
@@ -95,7 +125,7 @@ func TestGetPosts_ReturnsAllPosts(t *testing.T) {
 }
 
 func TestGetPost_ReturnsNotFound_IfNoMatch(t *testing.T) {
-	repo, conn := setup()
+	repo, conn := setup(t)
 
 	insertExamplePosts(conn)
 
@@ -107,7 +137,7 @@ func TestGetPost_ReturnsNotFound_IfNoMatch(t *testing.T) {
 }
 
 func TestGetPost_ReturnsCorrectPost_WithGoodID(t *testing.T) {
-	repo, conn := setup()
+	repo, conn := setup(t)
 
 	insertExamplePosts(conn)
 
@@ -123,11 +153,11 @@ func TestGetPost_ReturnsCorrectPost_WithGoodID(t *testing.T) {
 }
 
 func TestSavePost_InsertsPostInDB(t *testing.T) {
-	repo, conn := setup()
+	repo, conn := setup(t)
 
 	insertExamplePosts(conn)
 
-	err := repo.SavePost(entities.Post{
+	_, err := repo.SavePost(entities.Post{
 		Title:    "Foo",
 		Content:  "Bar",
 		Likes:    1,
@@ -155,7 +185,7 @@ func TestSavePost_InsertsPostInDB(t *testing.T) {
 }
 
 func TestDeletePost_DeletesCorrectPost(t *testing.T) {
-	repo, conn := setup()
+	repo, conn := setup(t)
 	insertExamplePosts(conn)
 
 	err := repo.DeletePost(2)
@@ -190,7 +220,7 @@ func TestUpdatePost_ReturnsNotFound_WithBadID(t *testing.T) {
 
 	for _, id := range badIDs {
 		t.Run(fmt.Sprint(id), func(t *testing.T) {
-			repo, conn := setup()
+			repo, conn := setup(t)
 			insertExamplePosts(conn)
 
 			err := repo.UpdatePost(id, entities.Post{Title: "Foo"})
@@ -206,7 +236,7 @@ func TestUpdatePost_UpdatesCorrectPost_WithData(t *testing.T) {
 	goodIDs := []int{1, 2, 3}
 	for _, id := range goodIDs {
 		t.Run(fmt.Sprintf("Post %d", id), func(t *testing.T) {
-			repo, conn := setup()
+			repo, conn := setup(t)
 			insertExamplePosts(conn)
 
 			updateData := examplePosts[id-1]
@@ -245,3 +275,112 @@ func insertExamplePosts(conn *sql.DB) {
 		insertPost(conn, post)
 	}
 }
+
+func TestWithinTx_CommitsChanges_WhenFnSucceeds(t *testing.T) {
+	repo, conn := setup(t)
+	insertExamplePosts(conn)
+
+	err := repo.WithinTx(func(tx interfaces.Repository) error {
+		return tx.DeletePost(2)
+	})
+
+	if err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	if _, err := repo.GetPost(2); err != useCases.ErrNotFound {
+		t.Fatalf("Expected deleted post to not be found; Got: '%v'", err)
+	}
+}
+
+func TestWithinTx_RollsBackChanges_WhenFnFails(t *testing.T) {
+	repo, conn := setup(t)
+	insertExamplePosts(conn)
+
+	errFn := errors.New("fn failed")
+	err := repo.WithinTx(func(tx interfaces.Repository) error {
+		if err := tx.DeletePost(2); err != nil {
+			return err
+		}
+		return errFn
+	})
+
+	if err != errFn {
+		t.Fatalf("Expected error from fn; Got: '%v'", err)
+	}
+	post, err := repo.GetPost(2)
+	if err != nil {
+		t.Fatalf("Expected post 2 to survive rollback; Got: '%v'", err)
+	}
+	if diff := cmp.Diff(examplePosts[1], post); diff != "" {
+		t.Fatalf("Expected post 2 to be unchanged; \n%s", diff)
+	}
+}
+
+func TestSqliteRepo_Conformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repository {
+		return openRepo(t, filepath.Join(t.TempDir(), "posts.db"))
+	})
+}
+
+func TestInstantiatingRepo_ReturnsErrors(t *testing.T) {
+	notADatabase := filepath.Join(t.TempDir(), "posts.db")
+	os.WriteFile(notADatabase, []byte("this is not a database, just some bytes padding it out"), 0644)
+
+	for _, path := range []string{t.TempDir(), notADatabase} {
+		if _, err := db.NewSqliteRepo(path); err == nil {
+			t.Fatalf("Expected an error opening %s", path)
+		}
+	}
+}
+
+func TestInstantiatingRepo_UpgradesOlderDatabases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "posts.db")
+	conn, _ := sql.Open("sqlite3", path)
+	conn.Exec(`CREATE TABLE posts (id INTEGER PRIMARY KEY, title TEXT, content TEXT, likes INTEGER, dislikes INTEGER);`)
+	conn.Exec(`INSERT INTO posts (title, content, likes, dislikes) VALUES ('Hello', 'World', 3, 1), ('Hello', '', 0, 0);`)
+	conn.Close()
+
+	repo := openRepo(t, path)
+	// and again, once the columns exist
+	repo = openRepo(t, path)
+
+	posts, err := repo.GetSortedPosts(ranking.SortTop)
+	if err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	expected := []entities.Post{
+		{ID: 1, Slug: "hello", Title: "Hello", Content: "World", Likes: 3, Dislikes: 1},
+		{ID: 2, Slug: "hello-2", Title: "Hello"},
+	}
+	if diff := cmp.Diff(expected, posts); diff != "" {
+		t.Fatalf("Expected older posts to be slugged and scored: \n%s", diff)
+	}
+	if changes, _ := repo.GetChangesSince(0, 10); len(changes) != 2 {
+		t.Fatalf("Expected older posts in the change feed; Got: '%v'", changes)
+	}
+
+	// the rebuilt table keeps its indexes and triggers, and no longer
+	// reuses the ID of the newest post once it is deleted
+	repo.DeletePost(2)
+	if _, err := repo.SavePost(entities.Post{Title: "Hello"}); err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	if post, err := repo.GetPostBySlug("hello-2"); err != nil || post.ID != 3 {
+		t.Fatalf("Expected a fresh ID for the new post; Got: '%v', '%v'", post, err)
+	}
+	changes, _ := repo.GetChangesSince(0, 10)
+	if len(changes) != 3 || !changes[1].Deleted || changes[2].Post.ID != 3 {
+		t.Fatalf("Expected the delete and save in the change feed; Got: '%v'", changes)
+	}
+}
+
+func TestGetPost_ReturnsScanErrors(t *testing.T) {
+	repo, conn := setup(t)
+	conn.Exec(`INSERT INTO posts (title, content, likes, dislikes) VALUES (NULL, 'Bar', 0, 0);`)
+
+	post, err := repo.GetPost(1)
+
+	if err == nil || err == useCases.ErrNotFound {
+		t.Fatalf("Expected the scan error; Got: '%v', '%v'", post, err)
+	}
+}
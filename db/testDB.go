@@ -4,6 +4,7 @@ import (
 	"errors"
 
 	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/interfaces"
 	"github.com/steve-kaufman/postsService/useCases"
 )
 
@@ -20,8 +21,12 @@ func (BadRepository) GetPost(id int) (entities.Post, error) {
 	return entities.Post{}, ErrBad
 }
 
-func (BadRepository) SavePost(post entities.Post) error {
-	return ErrBad
+func (BadRepository) GetPostBySlug(slug string) (entities.Post, error) {
+	return entities.Post{}, ErrBad
+}
+
+func (BadRepository) SavePost(post entities.Post) (entities.Post, error) {
+	return entities.Post{}, ErrBad
 }
 
 func (BadRepository) DeletePost(id int) error {
@@ -32,6 +37,14 @@ func (BadRepository) UpdatePost(id int, data entities.Post) error {
 	return ErrBad
 }
 
+func (BadRepository) WithinTx(fn func(repo interfaces.Repository) error) error {
+	return ErrBad
+}
+
+func (BadRepository) GetChangesSince(seq int64, limit int) ([]entities.Change, error) {
+	return nil, ErrBad
+}
+
 // GoodRepository is a quasi-functional in-memory repository for the useCases
 type GoodRepository struct {
 	posts         []entities.Post
@@ -57,9 +70,21 @@ func (repo GoodRepository) GetPost(id int) (entities.Post, error) {
 	return repo.posts[id-1], nil
 }
 
-func (repo *GoodRepository) SavePost(post entities.Post) error {
+func (repo GoodRepository) GetPostBySlug(slug string) (entities.Post, error) {
+	for _, post := range repo.posts {
+		if post.Slug == slug {
+			return post, nil
+		}
+	}
+	return entities.Post{}, useCases.ErrNotFound
+}
+
+// SavePost records post and returns it with the ID it would have been
+// given after the existing posts
+func (repo *GoodRepository) SavePost(post entities.Post) (entities.Post, error) {
 	repo.SavedPost = post
-	return nil
+	post.ID = len(repo.posts) + 1
+	return post, nil
 }
 
 func (repo *GoodRepository) DeletePost(id int) error {
@@ -78,3 +103,19 @@ func (repo *GoodRepository) UpdatePost(id int, post entities.Post) error {
 	repo.UpdatedPost = post
 	return nil
 }
+
+func (repo *GoodRepository) WithinTx(fn func(repo interfaces.Repository) error) error {
+	return fn(repo)
+}
+
+// GetChangesSince reports each post as changed once, with its position as
+// its sequence number
+func (repo GoodRepository) GetChangesSince(seq int64, limit int) ([]entities.Change, error) {
+	changes := []entities.Change{}
+	for i, post := range repo.posts {
+		if int64(i+1) > seq && len(changes) < limit {
+			changes = append(changes, entities.Change{Seq: int64(i + 1), Post: post})
+		}
+	}
+	return changes, nil
+}
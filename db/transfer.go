@@ -0,0 +1,46 @@
+package db
+
+import (
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/transfer"
+)
+
+// EachPost calls fn with every post in ID order, reading them one row at
+// a time, and stops at the first error fn returns
+func (repo SqliteRepo) EachPost(fn func(post entities.Post) error) error {
+	rows, err := repo.conn.Query(`SELECT ` + postColumns + ` FROM posts ORDER BY id;`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		post, err := mapToPost(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(post); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ImportPost stores post with its likes and dislikes, and under post.ID if
+// keepID is set. Imports record no outbox events.
+func (repo SqliteRepo) ImportPost(post entities.Post, keepID bool) error {
+	return repo.inTx(func(tx *SqliteRepo) error {
+		if keepID {
+			var taken bool
+			err := tx.conn.QueryRow(`SELECT EXISTS (SELECT 1 FROM posts WHERE id = ?)`, post.ID).Scan(&taken)
+			if err != nil {
+				return err
+			}
+			if taken {
+				return transfer.ErrIDTaken
+			}
+		}
+		_, err := tx.insertPost(post, keepID)
+		return err
+	})
+}
@@ -0,0 +1,60 @@
+package db_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/transfer"
+)
+
+func TestEachPost_StreamsPostsInIDOrder(t *testing.T) {
+	repo := openRepo(t, filepath.Join(t.TempDir(), "posts.db"))
+	repo.ImportPost(entities.Post{ID: 5, Title: "Five"}, true)
+	repo.ImportPost(entities.Post{ID: 2, Title: "Two"}, true)
+	repo.SavePost(entities.Post{Title: "Six"})
+
+	var ids []int
+	repo.EachPost(func(post entities.Post) error {
+		ids = append(ids, post.ID)
+		return nil
+	})
+	if diff := cmp.Diff([]int{2, 5, 6}, ids); diff != "" {
+		t.Fatalf("Expected posts in ID order: \n%s", diff)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err := repo.EachPost(func(post entities.Post) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Fatalf("Expected EachPost to stop at the first error; Got %d calls, '%v'", calls, err)
+	}
+}
+
+func TestImportPost_KeepsVotesAndOptionallyIDs(t *testing.T) {
+	repo := openRepo(t, filepath.Join(t.TempDir(), "posts.db"))
+
+	if err := repo.ImportPost(entities.Post{ID: 9, Title: "Kept", Likes: 4, Dislikes: 1}, true); err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	if err := repo.ImportPost(entities.Post{ID: 9, Title: "Taken"}, true); err != transfer.ErrIDTaken {
+		t.Fatalf("Expected ErrIDTaken; Got: '%v'", err)
+	}
+	if err := repo.ImportPost(entities.Post{ID: 9, Title: "Kept"}, false); err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+
+	posts, _ := repo.GetPosts()
+	expected := []entities.Post{
+		{ID: 9, Slug: "kept", Title: "Kept", Likes: 4, Dislikes: 1},
+		{ID: 10, Slug: "kept-2", Title: "Kept"},
+	}
+	if diff := cmp.Diff(expected, posts); diff != "" {
+		t.Fatalf("Expected imported posts: \n%s", diff)
+	}
+}
@@ -0,0 +1,18 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// IsTransient reports whether err is SQLite failing to get a lock, which
+// another try may. Every other error, including constraint and I/O
+// errors, is permanent.
+func IsTransient(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+}
@@ -0,0 +1,56 @@
+package db_test
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/steve-kaufman/postsService/db"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/interfaces"
+)
+
+func TestIsTransient_WhenAnotherWriterHoldsTheLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "posts.db")
+	holder := openRepo(t, path)
+	waiter, err := db.OpenSqliteRepo(path, db.Options{BusyTimeout: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var busy error
+	holder.WithinTx(func(repo interfaces.Repository) error {
+		_, busy = waiter.SavePost(entities.Post{Title: "Foo"})
+		return nil
+	})
+
+	if busy == nil || !db.IsTransient(busy) {
+		t.Fatalf("Expected a transient error; Got: '%v'", busy)
+	}
+}
+
+func TestIsTransient_IsFalseForOtherErrors(t *testing.T) {
+	for _, err := range []error{nil, errors.New("disk I/O error"), sql.ErrNoRows, db.ErrBad} {
+		if db.IsTransient(err) {
+			t.Fatalf("Expected '%v' to be permanent", err)
+		}
+	}
+}
+
+func TestOpenSqliteRepo_EnablesWAL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "posts.db")
+	if _, err := db.OpenSqliteRepo(path, db.Options{WAL: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, _ := sql.Open("sqlite3", path)
+	defer conn.Close()
+	var mode string
+	conn.QueryRow(`PRAGMA journal_mode`).Scan(&mode)
+
+	if mode != "wal" {
+		t.Fatalf("Expected journal mode wal; Got: '%s'", mode)
+	}
+}
@@ -0,0 +1,9 @@
+package entities
+
+// Change is the latest state of a post as of sequence number Seq. A
+// deleted post is reported once as a tombstone carrying only its ID.
+type Change struct {
+	Seq     int64
+	Post    Post
+	Deleted bool
+}
@@ -1,11 +1,17 @@
 package entities
 
 type Post struct {
-	ID       int
+	ID int
+	// Slug names the post in URLs. Repositories that support slugs
+	// generate it from the title; it can't be set directly.
+	Slug     string
 	Title    string
 	Content  string
 	Likes    int
 	Dislikes int
+	// ContentHTML is Content rendered from Markdown. The read use cases
+	// fill it in; repositories don't store it.
+	ContentHTML string
 }
 
 func FormatAndValidateNewPost(post Post) (Post, error) {
@@ -28,5 +34,7 @@ func validatePost(post Post) error {
 func formatNewPost(post Post) Post {
 	post.Likes = 0
 	post.Dislikes = 0
+	post.Slug = ""
+	post.ContentHTML = ""
 	return post
 }
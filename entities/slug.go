@@ -0,0 +1,74 @@
+package entities
+
+import (
+	"strings"
+	"unicode"
+)
+
+const maxSlugLength = 60
+
+// transliterations spells letters without an ASCII base in ASCII
+var transliterations = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "ae", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'æ': "ae", 'ç': "c", 'ć': "c", 'č': "c", 'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ğ': "g", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'į': "i", 'ı': "i",
+	'ł': "l", 'ľ': "l", 'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "oe", 'ø': "o", 'ō': "o", 'ő': "o", 'œ': "oe",
+	'ř': "r", 'ś': "s", 'š': "s", 'ş': "s", 'ß': "ss", 'ť': "t", 'ţ': "t", 'þ': "th",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "ue", 'ū': "u", 'ů': "u", 'ű': "u", 'ų': "u",
+	'ý': "y", 'ÿ': "y", 'ź': "z", 'ż': "z", 'ž': "z",
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya", 'є': "ye", 'і': "i", 'ї': "yi", 'ґ': "g",
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th",
+	'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p",
+	'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o",
+	'ά': "a", 'έ': "e", 'ή': "i", 'ί': "i", 'ϊ': "i", 'ΐ': "i", 'ό': "o", 'ύ': "y", 'ϋ': "y", 'ΰ': "y", 'ώ': "o",
+}
+
+// Slugify turns a title into the lowercase ASCII words of a URL slug, such
+// as "creme-brulee-for-beginners". Letters it can't transliterate are
+// dropped. A slug is never empty and never all digits, so it can't be
+// mistaken for an ID.
+func Slugify(title string) string {
+	var slug strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		word := ""
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			word = string(r)
+		case transliterations[r] != "":
+			word = transliterations[r]
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r):
+			// untransliterated letters and combining marks don't split words
+			continue
+		default:
+			dash = slug.Len() > 0
+			continue
+		}
+		if dash {
+			slug.WriteByte('-')
+			dash = false
+		}
+		slug.WriteString(word)
+	}
+
+	s := slug.String()
+	if len(s) > maxSlugLength {
+		s = s[:maxSlugLength]
+		if cut := strings.LastIndexByte(s, '-'); cut > 0 {
+			s = s[:cut]
+		}
+	}
+	if s == "" {
+		return "post"
+	}
+	if strings.Trim(s, "0123456789") == "" {
+		return "post-" + s
+	}
+	return s
+}
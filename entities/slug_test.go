@@ -0,0 +1,38 @@
+package entities_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/steve-kaufman/postsService/entities"
+)
+
+func TestSlugify(t *testing.T) {
+	testCases := map[string]string{
+		"Hello, World!":                "hello-world",
+		"  Go 1.16: what's new?  ":     "go-1-16-what-s-new",
+		"Crème brûlée für Anfänger":    "creme-brulee-fuer-anfaenger",
+		"Smørrebrød & Straße":          "smorrebrod-strasse",
+		"Привет, мир":                  "privet-mir",
+		"Καλημέρα κόσμε":               "kalimera-kosme",
+		"日本語 only":                     "only",
+		"!!!":                          "post",
+		"2021":                         "post-2021",
+		"Café (decomposed)":           "cafe-decomposed",
+		"multiple---dashes___and   so": "multiple-dashes-and-so",
+	}
+
+	for title, expected := range testCases {
+		if slug := entities.Slugify(title); slug != expected {
+			t.Fatalf("Expected '%s' to slugify to '%s'; Got: '%s'", title, expected, slug)
+		}
+	}
+}
+
+func TestSlugify_CutsLongTitlesAtAWord(t *testing.T) {
+	slug := entities.Slugify(strings.Repeat("word ", 30))
+
+	if len(slug) > 60 || strings.HasSuffix(slug, "-") || strings.HasSuffix(slug, "wor") {
+		t.Fatalf("Expected at most 60 characters of whole words; Got: '%s'", slug)
+	}
+}
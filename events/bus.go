@@ -0,0 +1,167 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var ErrHandlerPanicked = errors.New("event handler panicked")
+
+// Handler reacts to a published event
+type Handler func(event Event) error
+
+// HandlerError reports that one subscriber failed to handle an event
+type HandlerError struct {
+	Subscriber string
+	Event      Event
+	Err        error
+}
+
+func (err *HandlerError) Error() string {
+	return fmt.Sprintf("subscriber %q handling %s: %v", err.Subscriber, err.Event.EventName(), err.Err)
+}
+
+func (err *HandlerError) Unwrap() error {
+	return err.Err
+}
+
+// Bus delivers every published event to all of its subscribers. A failing
+// or panicking subscriber never affects the publisher or other subscribers;
+// its error is passed to the bus's error reporter instead.
+type Bus struct {
+	mu       sync.RWMutex
+	subs     map[int]*subscription
+	nextID   int
+	onError  func(err *HandlerError)
+	inFlight sync.WaitGroup
+}
+
+type subscription struct {
+	name    string
+	handler Handler
+	// queue is nil for synchronous subscribers
+	queue chan Event
+	done  chan struct{}
+
+	// mu keeps queue from being closed while an event is sent on it
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewBus returns a Bus that reports handler failures to onError, which may
+// be nil to ignore them. onError may be called from several goroutines.
+func NewBus(onError func(err *HandlerError)) *Bus {
+	return &Bus{subs: map[int]*subscription{}, onError: onError}
+}
+
+// Subscribe registers a handler that runs on the publisher's goroutine
+// before Publish returns. The returned func unsubscribes it; it must not be
+// called from inside a handler.
+func (bus *Bus) Subscribe(name string, handler Handler) func() {
+	return bus.add(&subscription{name: name, handler: handler})
+}
+
+// SubscribeAsync registers a handler that runs on its own goroutine,
+// receiving events in the order they were published. Up to buffer events
+// are queued; beyond that Publish blocks until the handler catches up.
+// The returned func unsubscribes it after its queue has drained.
+func (bus *Bus) SubscribeAsync(name string, handler Handler, buffer int) func() {
+	sub := &subscription{
+		name:    name,
+		handler: handler,
+		queue:   make(chan Event, buffer),
+		done:    make(chan struct{}),
+	}
+	go bus.drain(sub)
+	return bus.add(sub)
+}
+
+// Publish delivers event to the subscribers registered when it is called.
+// It holds no lock while delivering, so handlers may publish and others
+// may subscribe while it waits on a full queue.
+func (bus *Bus) Publish(event Event) {
+	bus.mu.RLock()
+	subs := make([]*subscription, 0, len(bus.subs))
+	for _, sub := range bus.subs {
+		subs = append(subs, sub)
+	}
+	bus.mu.RUnlock()
+
+	for _, sub := range subs {
+		if sub.queue != nil {
+			bus.enqueue(sub, event)
+			continue
+		}
+		bus.deliver(sub, event)
+	}
+}
+
+// enqueue queues event for an asynchronous subscriber, unless it has
+// unsubscribed since Publish looked
+func (bus *Bus) enqueue(sub *subscription, event Event) {
+	sub.mu.RLock()
+	defer sub.mu.RUnlock()
+	if sub.closed {
+		return
+	}
+	bus.inFlight.Add(1)
+	sub.queue <- event
+}
+
+// Wait blocks until every event queued for asynchronous subscribers so far
+// has been handled
+func (bus *Bus) Wait() {
+	bus.inFlight.Wait()
+}
+
+func (bus *Bus) add(sub *subscription) func() {
+	bus.mu.Lock()
+	id := bus.nextID
+	bus.nextID++
+	bus.subs[id] = sub
+	bus.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { bus.remove(id) })
+	}
+}
+
+func (bus *Bus) remove(id int) {
+	bus.mu.Lock()
+	sub := bus.subs[id]
+	delete(bus.subs, id)
+	bus.mu.Unlock()
+
+	if sub.queue != nil {
+		sub.mu.Lock()
+		sub.closed = true
+		close(sub.queue)
+		sub.mu.Unlock()
+		<-sub.done
+	}
+}
+
+func (bus *Bus) drain(sub *subscription) {
+	defer close(sub.done)
+	for event := range sub.queue {
+		bus.deliver(sub, event)
+		bus.inFlight.Done()
+	}
+}
+
+func (bus *Bus) deliver(sub *subscription, event Event) {
+	if err := callHandler(sub.handler, event); err != nil && bus.onError != nil {
+		bus.onError(&HandlerError{Subscriber: sub.name, Event: event, Err: err})
+	}
+}
+
+func callHandler(handler Handler, event Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrHandlerPanicked, r)
+		}
+	}()
+	return handler(event)
+}
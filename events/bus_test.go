@@ -0,0 +1,178 @@
+package events_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/events"
+)
+
+var created = events.PostCreated{Post: entities.Post{ID: 1, Title: "Foo"}}
+var deleted = events.PostDeleted{Post: entities.Post{ID: 1, Title: "Foo"}}
+
+type errorCollector struct {
+	mu     sync.Mutex
+	errors []*events.HandlerError
+}
+
+func (collector *errorCollector) report(err *events.HandlerError) {
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	collector.errors = append(collector.errors, err)
+}
+
+func TestPublish_DeliversToSyncSubscribersBeforeReturning(t *testing.T) {
+	bus := events.NewBus(nil)
+	var received []events.Event
+	bus.Subscribe("sync", func(event events.Event) error {
+		received = append(received, event)
+		return nil
+	})
+
+	bus.Publish(created)
+	bus.Publish(deleted)
+
+	if diff := cmp.Diff([]events.Event{created, deleted}, received); diff != "" {
+		t.Fatalf("Expected both events to be delivered: \n%s", diff)
+	}
+}
+
+func TestPublish_DeliversToAsyncSubscribersInOrder(t *testing.T) {
+	bus := events.NewBus(nil)
+	recorder := new(events.Recorder)
+	bus.SubscribeAsync("async", func(event events.Event) error {
+		recorder.Publish(event)
+		return nil
+	}, 1)
+
+	for i := 0; i < 10; i++ {
+		bus.Publish(events.PostCreated{Post: entities.Post{ID: i}})
+	}
+	bus.Wait()
+
+	received := recorder.Events()
+	if len(received) != 10 {
+		t.Fatalf("Expected 10 events; Got: %d", len(received))
+	}
+	for i, event := range received {
+		if event.(events.PostCreated).Post.ID != i {
+			t.Fatalf("Expected event %d in order; Got: '%v'", i, event)
+		}
+	}
+}
+
+func TestPublish_ReportsErrorsPerSubscriber(t *testing.T) {
+	collector := new(errorCollector)
+	bus := events.NewBus(collector.report)
+	errFailed := errors.New("failed")
+	bus.Subscribe("failing", func(event events.Event) error {
+		return errFailed
+	})
+	bus.Subscribe("working", func(event events.Event) error {
+		return nil
+	})
+
+	bus.Publish(created)
+
+	if len(collector.errors) != 1 {
+		t.Fatalf("Expected one error; Got: '%v'", collector.errors)
+	}
+	err := collector.errors[0]
+	if err.Subscriber != "failing" || err.Event != created || !errors.Is(err, errFailed) {
+		t.Fatalf("Expected error from 'failing' handling PostCreated; Got: '%v'", err)
+	}
+}
+
+func TestPublish_IsolatesPanickingSubscribers(t *testing.T) {
+	collector := new(errorCollector)
+	bus := events.NewBus(collector.report)
+	recorder := new(events.Recorder)
+	bus.Subscribe("panicking", func(event events.Event) error {
+		panic("boom")
+	})
+	bus.SubscribeAsync("panicking async", func(event events.Event) error {
+		panic("boom")
+	}, 0)
+	bus.Subscribe("working", func(event events.Event) error {
+		recorder.Publish(event)
+		return nil
+	})
+
+	bus.Publish(created)
+	bus.Wait()
+
+	if diff := cmp.Diff([]events.Event{created}, recorder.Events()); diff != "" {
+		t.Fatalf("Expected working subscriber to receive event: \n%s", diff)
+	}
+	if len(collector.errors) != 2 {
+		t.Fatalf("Expected two errors; Got: '%v'", collector.errors)
+	}
+	for _, err := range collector.errors {
+		if !errors.Is(err, events.ErrHandlerPanicked) {
+			t.Fatalf("Expected ErrHandlerPanicked; Got: '%v'", err)
+		}
+	}
+}
+
+func TestUnsubscribe_StopsDelivery(t *testing.T) {
+	bus := events.NewBus(nil)
+	syncRecorder := new(events.Recorder)
+	asyncRecorder := new(events.Recorder)
+	unsubscribeSync := bus.Subscribe("sync", func(event events.Event) error {
+		syncRecorder.Publish(event)
+		return nil
+	})
+	unsubscribeAsync := bus.SubscribeAsync("async", func(event events.Event) error {
+		asyncRecorder.Publish(event)
+		return nil
+	}, 10)
+
+	bus.Publish(created)
+	unsubscribeSync()
+	unsubscribeAsync()
+	bus.Publish(deleted)
+
+	if diff := cmp.Diff([]events.Event{created}, syncRecorder.Events()); diff != "" {
+		t.Fatalf("Expected only first event for sync subscriber: \n%s", diff)
+	}
+	if diff := cmp.Diff([]events.Event{created}, asyncRecorder.Events()); diff != "" {
+		t.Fatalf("Expected only first event for async subscriber: \n%s", diff)
+	}
+}
+
+func TestSubscribe_DoesNotWaitForAPublishBlockedOnAFullQueue(t *testing.T) {
+	bus := events.NewBus(nil)
+	gate := make(chan struct{})
+	bus.SubscribeAsync("slow", func(event events.Event) error {
+		<-gate
+		return nil
+	}, 0)
+	// taken by the handler, which then waits at the gate
+	bus.Publish(created)
+	published := make(chan struct{})
+	go func() {
+		bus.Publish(deleted)
+		close(published)
+	}()
+
+	subscribed := make(chan struct{})
+	go func() {
+		bus.Subscribe("late", func(event events.Event) error {
+			return nil
+		})
+		close(subscribed)
+	}()
+
+	select {
+	case <-subscribed:
+	case <-time.After(time.Second):
+		t.Fatal("Expected Subscribe not to wait for a blocked Publish")
+	}
+	close(gate)
+	<-published
+	bus.Wait()
+}
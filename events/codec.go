@@ -0,0 +1,37 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+var ErrUnknownEvent = errors.New("unknown event")
+
+// Marshal encodes event as JSON. Together with event.EventName() it is
+// everything Unmarshal needs to rebuild the event.
+func Marshal(event Event) ([]byte, error) {
+	return json.Marshal(event)
+}
+
+// Unmarshal rebuilds an event from its name and the output of Marshal
+func Unmarshal(name string, data []byte) (Event, error) {
+	switch name {
+	case PostCreated{}.EventName():
+		var event PostCreated
+		err := json.Unmarshal(data, &event)
+		return event, err
+	case PostUpdated{}.EventName():
+		var event PostUpdated
+		err := json.Unmarshal(data, &event)
+		return event, err
+	case PostDeleted{}.EventName():
+		var event PostDeleted
+		err := json.Unmarshal(data, &event)
+		return event, err
+	case PostVoted{}.EventName():
+		var event PostVoted
+		err := json.Unmarshal(data, &event)
+		return event, err
+	}
+	return nil, ErrUnknownEvent
+}
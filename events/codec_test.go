@@ -0,0 +1,43 @@
+package events_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/events"
+)
+
+func TestMarshal_RoundTripsEveryEvent(t *testing.T) {
+	post := entities.Post{ID: 1, Title: "Foo", Content: "Bar", Likes: 2, Dislikes: 1}
+	allEvents := []events.Event{
+		events.PostCreated{Post: post},
+		events.PostUpdated{Before: post, After: entities.Post{ID: 1, Title: "Baz"}},
+		events.PostDeleted{Post: post},
+		events.PostVoted{Post: post, Liked: true},
+	}
+
+	for _, event := range allEvents {
+		t.Run(event.EventName(), func(t *testing.T) {
+			data, err := events.Marshal(event)
+			if err != nil {
+				t.Fatalf("Expected no error; Got: '%v'", err)
+			}
+
+			decoded, err := events.Unmarshal(event.EventName(), data)
+
+			if err != nil {
+				t.Fatalf("Expected no error; Got: '%v'", err)
+			}
+			if diff := cmp.Diff(event, decoded); diff != "" {
+				t.Fatalf("Expected event to round trip: \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUnmarshal_ReturnsErrUnknownEvent(t *testing.T) {
+	if _, err := events.Unmarshal("post.exploded", []byte("{}")); err != events.ErrUnknownEvent {
+		t.Fatalf("Expected ErrUnknownEvent; Got: '%v'", err)
+	}
+}
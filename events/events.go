@@ -0,0 +1,56 @@
+// Package events defines the domain events emitted by the use cases and an
+// in-process bus for delivering them to subscribers.
+package events
+
+import "github.com/steve-kaufman/postsService/entities"
+
+// Event is something that happened to a post after a use case succeeded
+type Event interface {
+	EventName() string
+}
+
+type Publisher interface {
+	Publish(event Event)
+}
+
+type PostCreated struct {
+	Post entities.Post
+}
+
+// PostUpdated carries the post as it was before and after the update
+type PostUpdated struct {
+	Before entities.Post
+	After  entities.Post
+}
+
+type PostDeleted struct {
+	Post entities.Post
+}
+
+// PostVoted carries the post with its counts after a like, when Liked is
+// true, or a dislike
+type PostVoted struct {
+	Post  entities.Post
+	Liked bool
+}
+
+func (PostCreated) EventName() string { return "post.created" }
+func (PostUpdated) EventName() string { return "post.updated" }
+func (PostDeleted) EventName() string { return "post.deleted" }
+func (PostVoted) EventName() string   { return "post.voted" }
+
+// Discard is a Publisher that drops every event, for callers whose events
+// are delivered some other way
+var Discard Publisher = discard{}
+
+type discard struct{}
+
+func (discard) Publish(event Event) {}
+
+// Names lists the name of every event type
+var Names = []string{
+	PostCreated{}.EventName(),
+	PostUpdated{}.EventName(),
+	PostDeleted{}.EventName(),
+	PostVoted{}.EventName(),
+}
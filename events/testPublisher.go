@@ -0,0 +1,21 @@
+package events
+
+import "sync"
+
+// Recorder is a Publisher that remembers every event published to it
+type Recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (recorder *Recorder) Publish(event Event) {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.events = append(recorder.events, event)
+}
+
+func (recorder *Recorder) Events() []Event {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	return append([]Event(nil), recorder.events...)
+}
@@ -0,0 +1,149 @@
+// Package eventsourcing stores posts as streams of events in SQLite and
+// rebuilds the current state of a post by folding its stream.
+package eventsourcing
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/steve-kaufman/postsService/entities"
+)
+
+var ErrUnknownEvent = errors.New("unknown event type")
+
+// Event is one change in the history of a post
+type Event interface {
+	EventType() string
+	apply(state *postState)
+}
+
+type PostCreated struct {
+	Title    string
+	Content  string
+	Likes    int
+	Dislikes int
+}
+
+type PostTitleChanged struct {
+	Title string
+}
+
+type PostContentChanged struct {
+	Content string
+}
+
+// PostLiked records a change in likes; Delta is negative when likes are removed
+type PostLiked struct {
+	Delta int
+}
+
+// PostDisliked records a change in dislikes; Delta is negative when
+// dislikes are removed
+type PostDisliked struct {
+	Delta int
+}
+
+type PostDeleted struct{}
+
+func (PostCreated) EventType() string        { return "PostCreated" }
+func (PostTitleChanged) EventType() string   { return "PostTitleChanged" }
+func (PostContentChanged) EventType() string { return "PostContentChanged" }
+func (PostLiked) EventType() string          { return "PostLiked" }
+func (PostDisliked) EventType() string       { return "PostDisliked" }
+func (PostDeleted) EventType() string        { return "PostDeleted" }
+
+func (e PostCreated) apply(state *postState) {
+	state.Post.Title = e.Title
+	state.Post.Content = e.Content
+	state.Post.Likes = e.Likes
+	state.Post.Dislikes = e.Dislikes
+}
+
+func (e PostTitleChanged) apply(state *postState) {
+	state.Post.Title = e.Title
+}
+
+func (e PostContentChanged) apply(state *postState) {
+	state.Post.Content = e.Content
+}
+
+func (e PostLiked) apply(state *postState) {
+	state.Post.Likes += e.Delta
+}
+
+func (e PostDisliked) apply(state *postState) {
+	state.Post.Dislikes += e.Delta
+}
+
+func (PostDeleted) apply(state *postState) {
+	state.Deleted = true
+}
+
+// RecordedEvent is an Event as it was stored in a post's stream
+type RecordedEvent struct {
+	PostID     int
+	Version    int
+	RecordedAt time.Time
+	Event      Event
+}
+
+// postState is the result of folding a stream, and what snapshots store
+type postState struct {
+	Post    entities.Post
+	Version int
+	Deleted bool
+
+	snapshotVersion int
+}
+
+func (state *postState) apply(e Event) {
+	e.apply(state)
+	state.Version++
+}
+
+// changesBetween returns the events that turn original into updated
+func changesBetween(original, updated entities.Post) []Event {
+	var changes []Event
+	if updated.Title != original.Title {
+		changes = append(changes, PostTitleChanged{Title: updated.Title})
+	}
+	if updated.Content != original.Content {
+		changes = append(changes, PostContentChanged{Content: updated.Content})
+	}
+	if updated.Likes != original.Likes {
+		changes = append(changes, PostLiked{Delta: updated.Likes - original.Likes})
+	}
+	if updated.Dislikes != original.Dislikes {
+		changes = append(changes, PostDisliked{Delta: updated.Dislikes - original.Dislikes})
+	}
+	return changes
+}
+
+func decodeEvent(eventType string, data []byte) (Event, error) {
+	switch eventType {
+	case "PostCreated":
+		var created PostCreated
+		err := json.Unmarshal(data, &created)
+		return created, err
+	case "PostTitleChanged":
+		var changed PostTitleChanged
+		err := json.Unmarshal(data, &changed)
+		return changed, err
+	case "PostContentChanged":
+		var changed PostContentChanged
+		err := json.Unmarshal(data, &changed)
+		return changed, err
+	case "PostLiked":
+		var liked PostLiked
+		err := json.Unmarshal(data, &liked)
+		return liked, err
+	case "PostDisliked":
+		var disliked PostDisliked
+		err := json.Unmarshal(data, &disliked)
+		return disliked, err
+	case "PostDeleted":
+		return PostDeleted{}, nil
+	}
+	return nil, ErrUnknownEvent
+}
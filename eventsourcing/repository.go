@@ -0,0 +1,351 @@
+package eventsourcing
+
+import (
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/interfaces"
+	"github.com/steve-kaufman/postsService/useCases"
+)
+
+// DefaultSnapshotEvery is how many events a stream may grow past its last
+// snapshot before a new snapshot is taken
+const DefaultSnapshotEvery = 50
+
+const schema = `
+CREATE TABLE IF NOT EXISTS events (
+	seq INTEGER PRIMARY KEY AUTOINCREMENT,
+	post_id INTEGER NOT NULL,
+	version INTEGER NOT NULL,
+	type TEXT NOT NULL,
+	data TEXT NOT NULL,
+	recorded_at TIMESTAMP NOT NULL,
+	UNIQUE (post_id, version)
+);
+CREATE TABLE IF NOT EXISTS snapshots (
+	post_id INTEGER PRIMARY KEY,
+	version INTEGER NOT NULL,
+	data TEXT NOT NULL
+);`
+
+// sqlConn is satisfied by both *sql.DB and *sql.Tx
+type sqlConn interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Repository implements the repository interfaces on top of an event store.
+// Every mutation appends events; reads fold them back into posts.
+type Repository struct {
+	SnapshotEvery int
+
+	db   *sql.DB
+	conn sqlConn
+}
+
+func NewRepository(path string) (*Repository, error) {
+	conn, err := sql.Open("sqlite3", path+"?_txlock=immediate")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Exec(schema); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	repo := new(Repository)
+	repo.SnapshotEvery = DefaultSnapshotEvery
+	repo.db = conn
+	repo.conn = conn
+	return repo, nil
+}
+
+func (repo *Repository) Close() error {
+	return repo.db.Close()
+}
+
+func (repo *Repository) WithinTx(fn func(repo interfaces.Repository) error) error {
+	if repo.db == nil {
+		// already inside a transaction
+		return fn(repo)
+	}
+
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(&Repository{SnapshotEvery: repo.SnapshotEvery, conn: tx}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (repo *Repository) GetPosts() ([]entities.Post, error) {
+	states, err := repo.loadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	posts := []entities.Post{}
+	for _, state := range states {
+		if !state.Deleted {
+			posts = append(posts, state.Post)
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].ID < posts[j].ID
+	})
+	return posts, nil
+}
+
+func (repo *Repository) GetPost(id int) (entities.Post, error) {
+	state, err := repo.loadLive(id)
+	if err != nil {
+		return entities.Post{}, err
+	}
+	return state.Post, nil
+}
+
+func (repo *Repository) SavePost(post entities.Post) (entities.Post, error) {
+	var saved entities.Post
+	err := repo.inTx(func(tx *Repository) error {
+		id, err := tx.nextPostID()
+		if err != nil {
+			return err
+		}
+		state := &postState{Post: entities.Post{ID: id}}
+		err = tx.append(state, PostCreated{
+			Title:    post.Title,
+			Content:  post.Content,
+			Likes:    post.Likes,
+			Dislikes: post.Dislikes,
+		})
+		saved = state.Post
+		return err
+	})
+	if err != nil {
+		return entities.Post{}, err
+	}
+	return saved, nil
+}
+
+func (repo *Repository) DeletePost(id int) error {
+	return repo.inTx(func(tx *Repository) error {
+		state, err := tx.loadLive(id)
+		if err != nil {
+			return err
+		}
+		return tx.append(&state, PostDeleted{})
+	})
+}
+
+func (repo *Repository) UpdatePost(id int, data entities.Post) error {
+	return repo.inTx(func(tx *Repository) error {
+		state, err := tx.loadLive(id)
+		if err != nil {
+			return err
+		}
+		data.ID = id
+		return tx.append(&state, changesBetween(state.Post, data)...)
+	})
+}
+
+// History returns every event in a post's stream, oldest first, including
+// those of a deleted post
+func (repo *Repository) History(id int) ([]RecordedEvent, error) {
+	rows, err := repo.conn.Query(`SELECT version, type, data, recorded_at FROM events
+		WHERE post_id = ? ORDER BY version`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []RecordedEvent{}
+	for rows.Next() {
+		recorded := RecordedEvent{PostID: id}
+		var eventType, data string
+		if err := rows.Scan(&recorded.Version, &eventType, &data, &recorded.RecordedAt); err != nil {
+			return nil, err
+		}
+		if recorded.Event, err = decodeEvent(eventType, []byte(data)); err != nil {
+			return nil, err
+		}
+		history = append(history, recorded)
+	}
+	if len(history) == 0 {
+		return nil, useCases.ErrNotFound
+	}
+	return history, rows.Err()
+}
+
+func (repo *Repository) inTx(fn func(tx *Repository) error) error {
+	return repo.WithinTx(func(tx interfaces.Repository) error {
+		return fn(tx.(*Repository))
+	})
+}
+
+func (repo *Repository) nextPostID() (int, error) {
+	var id int
+	err := repo.conn.QueryRow(`SELECT COALESCE(MAX(post_id), 0) + 1 FROM events`).Scan(&id)
+	return id, err
+}
+
+// append records events at the end of the post's stream, applying each to
+// state, and takes a snapshot if the stream has grown far enough
+func (repo *Repository) append(state *postState, events ...Event) error {
+	for _, e := range events {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		state.apply(e)
+
+		_, err = repo.conn.Exec(`INSERT INTO events (post_id, version, type, data, recorded_at)
+			VALUES (?, ?, ?, ?, ?)`,
+			state.Post.ID,
+			state.Version,
+			e.EventType(),
+			string(data),
+			time.Now().UTC(),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if repo.SnapshotEvery > 0 && state.Version-state.snapshotVersion >= repo.SnapshotEvery {
+		return repo.snapshot(state)
+	}
+	return nil
+}
+
+func (repo *Repository) snapshot(state *postState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	_, err = repo.conn.Exec(`INSERT OR REPLACE INTO snapshots (post_id, version, data) VALUES (?, ?, ?)`,
+		state.Post.ID,
+		state.Version,
+		string(data),
+	)
+	if err == nil {
+		state.snapshotVersion = state.Version
+	}
+	return err
+}
+
+func (repo *Repository) loadLive(id int) (postState, error) {
+	state, err := repo.load(id)
+	if err != nil {
+		return postState{}, err
+	}
+	if state.Version == 0 || state.Deleted {
+		return postState{}, useCases.ErrNotFound
+	}
+	return state, nil
+}
+
+// load folds a post's stream, starting from its latest snapshot if any
+func (repo *Repository) load(id int) (postState, error) {
+	state := postState{Post: entities.Post{ID: id}}
+
+	var data string
+	err := repo.conn.QueryRow(`SELECT data FROM snapshots WHERE post_id = ?`, id).Scan(&data)
+	if err != nil && err != sql.ErrNoRows {
+		return postState{}, err
+	}
+	if err == nil {
+		if err := json.Unmarshal([]byte(data), &state); err != nil {
+			return postState{}, err
+		}
+		state.snapshotVersion = state.Version
+	}
+
+	rows, err := repo.conn.Query(`SELECT type, data FROM events
+		WHERE post_id = ? AND version > ? ORDER BY version`, id, state.Version)
+	if err != nil {
+		return postState{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scanAndApply(rows, &state); err != nil {
+			return postState{}, err
+		}
+	}
+	return state, rows.Err()
+}
+
+// loadAll folds every stream, using snapshots so only the events after
+// each post's latest snapshot are read
+func (repo *Repository) loadAll() (map[int]*postState, error) {
+	states := map[int]*postState{}
+
+	snapshots, err := repo.conn.Query(`SELECT post_id, data FROM snapshots`)
+	if err != nil {
+		return nil, err
+	}
+	defer snapshots.Close()
+	for snapshots.Next() {
+		state := new(postState)
+		var id int
+		var data string
+		if err := snapshots.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(data), state); err != nil {
+			return nil, err
+		}
+		states[id] = state
+	}
+	if err := snapshots.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err := repo.conn.Query(`SELECT e.post_id, e.type, e.data FROM events e
+		LEFT JOIN snapshots s ON s.post_id = e.post_id
+		WHERE e.version > COALESCE(s.version, 0)
+		ORDER BY e.post_id, e.version`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var eventType, data string
+		if err := rows.Scan(&id, &eventType, &data); err != nil {
+			return nil, err
+		}
+		e, err := decodeEvent(eventType, []byte(data))
+		if err != nil {
+			return nil, err
+		}
+		state, ok := states[id]
+		if !ok {
+			state = &postState{Post: entities.Post{ID: id}}
+			states[id] = state
+		}
+		state.apply(e)
+	}
+	return states, rows.Err()
+}
+
+func scanAndApply(rows *sql.Rows, state *postState) error {
+	var eventType, data string
+	if err := rows.Scan(&eventType, &data); err != nil {
+		return err
+	}
+	e, err := decodeEvent(eventType, []byte(data))
+	if err != nil {
+		return err
+	}
+	state.apply(e)
+	return nil
+}
@@ -0,0 +1,116 @@
+package eventsourcing_test
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/eventsourcing"
+	"github.com/steve-kaufman/postsService/repotest"
+	"github.com/steve-kaufman/postsService/useCases"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setup(t *testing.T) (*eventsourcing.Repository, *sql.DB) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "events.db")
+	repo, err := eventsourcing.NewRepository(path)
+	if err != nil {
+		t.Fatalf("Expected no error opening store; Got: '%v'", err)
+	}
+	conn, _ := sql.Open("sqlite3", path)
+	t.Cleanup(func() {
+		conn.Close()
+		repo.Close()
+	})
+	return repo, conn
+}
+
+func TestRepository_Conformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repository {
+		repo, _ := setup(t)
+		return repo
+	})
+}
+
+func TestHistory_RecordsEveryChange(t *testing.T) {
+	repo, _ := setup(t)
+
+	repo.SavePost(entities.Post{Title: "Foo", Content: "Bar"})
+	repo.UpdatePost(1, entities.Post{Title: "Baz", Content: "Bar", Likes: 3})
+	repo.UpdatePost(1, entities.Post{Title: "Baz", Content: "Qux", Likes: 2, Dislikes: 1})
+	repo.DeletePost(1)
+
+	history, err := repo.History(1)
+	if err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+
+	expected := []eventsourcing.Event{
+		eventsourcing.PostCreated{Title: "Foo", Content: "Bar"},
+		eventsourcing.PostTitleChanged{Title: "Baz"},
+		eventsourcing.PostLiked{Delta: 3},
+		eventsourcing.PostContentChanged{Content: "Qux"},
+		eventsourcing.PostLiked{Delta: -1},
+		eventsourcing.PostDisliked{Delta: 1},
+		eventsourcing.PostDeleted{},
+	}
+	events := []eventsourcing.Event{}
+	for i, recorded := range history {
+		if recorded.Version != i+1 {
+			t.Fatalf("Expected event %d to have version %d; Got: %d", i, i+1, recorded.Version)
+		}
+		if recorded.RecordedAt.IsZero() {
+			t.Fatalf("Expected event %d to have a timestamp", i)
+		}
+		events = append(events, recorded.Event)
+	}
+	if diff := cmp.Diff(expected, events); diff != "" {
+		t.Fatalf("Expected history to match: \n%s", diff)
+	}
+}
+
+func TestHistory_ReturnsErrNotFound_ForUnknownPost(t *testing.T) {
+	repo, _ := setup(t)
+
+	if _, err := repo.History(1); err != useCases.ErrNotFound {
+		t.Fatalf("Expected ErrNotFound; Got: '%v'", err)
+	}
+}
+
+func TestSnapshots_AreTakenAndUsedWhenRebuilding(t *testing.T) {
+	repo, conn := setup(t)
+	repo.SnapshotEvery = 3
+
+	repo.SavePost(entities.Post{Title: "Foo"})
+	for i := 1; i <= 4; i++ {
+		repo.UpdatePost(1, entities.Post{Title: "Foo", Likes: i})
+	}
+
+	var version int
+	if err := conn.QueryRow(`SELECT version FROM snapshots WHERE post_id = 1`).Scan(&version); err != nil {
+		t.Fatalf("Expected a snapshot; Got: '%v'", err)
+	}
+	if version != 3 {
+		t.Fatalf("Expected snapshot at version 3; Got: %d", version)
+	}
+
+	// events covered by the snapshot are no longer needed to rebuild the post
+	conn.Exec(`DELETE FROM events WHERE post_id = 1 AND version <= 3`)
+
+	expected := entities.Post{ID: 1, Title: "Foo", Likes: 4}
+	post, err := repo.GetPost(1)
+	if err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	if diff := cmp.Diff(expected, post); diff != "" {
+		t.Fatalf("Expected post rebuilt from snapshot: \n%s", diff)
+	}
+	posts, _ := repo.GetPosts()
+	if diff := cmp.Diff([]entities.Post{expected}, posts); diff != "" {
+		t.Fatalf("Expected posts rebuilt from snapshot: \n%s", diff)
+	}
+}
@@ -0,0 +1,192 @@
+// Package feeds renders the newest posts as RSS 2.0 and Atom 1.0 feeds.
+package feeds
+
+import (
+	"encoding/xml"
+	"strconv"
+	"time"
+
+	"github.com/steve-kaufman/postsService/entities"
+)
+
+// Entry is a post along with the times a feed reports for it
+type Entry struct {
+	Post      entities.Post
+	Published time.Time
+	Updated   time.Time
+}
+
+// Source returns up to limit of the newest posts, newest first
+type Source interface {
+	RecentPosts(limit int) ([]Entry, error)
+}
+
+// Channel describes the feed as a whole
+type Channel struct {
+	Title       string
+	Description string
+	// Author is required by Atom, which has no per-post authors to fall
+	// back on here
+	Author string
+	// Link is the site's URL without a trailing slash. Each post links to
+	// Link/posts/{id}.
+	Link string
+	// Authority names the feed's owner in the tag: URIs (RFC 4151) that
+	// identify the feed and its posts, as a domain they held on a date,
+	// e.g. "example.com,2021". Unlike Link, it must never change.
+	Authority string
+	// Started is when the feed began, which a feed without posts reports
+	// as its last update
+	Started time.Time
+}
+
+func (channel Channel) postURL(id int) string {
+	return channel.Link + "/posts/" + strconv.Itoa(id)
+}
+
+func (channel Channel) feedTag() string {
+	return "tag:" + channel.Authority + ":posts"
+}
+
+func (channel Channel) postTag(id int) string {
+	return channel.feedTag() + "/" + strconv.Itoa(id)
+}
+
+// updated is when the feed last changed, as far as its entries tell
+func (channel Channel) updated(entries []Entry) time.Time {
+	if updated := lastUpdated(entries); !updated.IsZero() {
+		return updated
+	}
+	if !channel.Started.IsZero() {
+		return channel.Started
+	}
+	return time.Now()
+}
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// RenderRSS returns entries as an RSS 2.0 document
+func RenderRSS(channel Channel, entries []Entry) ([]byte, error) {
+	doc := rss{Version: "2.0", Channel: rssChannel{
+		Title:       channel.Title,
+		Link:        channel.Link,
+		Description: channel.Description,
+		Items:       []rssItem{},
+	}}
+	if updated := lastUpdated(entries); !updated.IsZero() {
+		doc.Channel.LastBuildDate = updated.UTC().Format(time.RFC1123Z)
+	}
+	for _, entry := range entries {
+		url := channel.postURL(entry.Post.ID)
+		doc.Channel.Items = append(doc.Channel.Items, rssItem{
+			Title:       entry.Post.Title,
+			Link:        url,
+			Description: entry.Post.Content,
+			GUID:        rssGUID{Value: channel.postTag(entry.Post.ID)},
+			PubDate:     entry.Published.UTC().Format(time.RFC1123Z),
+		})
+	}
+	return marshal(doc)
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title     string      `xml:"title"`
+	ID        string      `xml:"id"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published"`
+	Link      atomLink    `xml:"link"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// RenderAtom returns entries as an Atom 1.0 document
+func RenderAtom(channel Channel, entries []Entry) ([]byte, error) {
+	doc := atomFeed{
+		Title:   channel.Title,
+		ID:      channel.feedTag(),
+		Updated: channel.updated(entries).UTC().Format(time.RFC3339),
+		Author:  atomAuthor{Name: channel.Author},
+		Links: []atomLink{
+			{Href: channel.Link + "/"},
+			{Href: channel.Link + "/feed.atom", Rel: "self"},
+		},
+	}
+	for _, entry := range entries {
+		url := channel.postURL(entry.Post.ID)
+		doc.Entries = append(doc.Entries, atomEntry{
+			Title:     entry.Post.Title,
+			ID:        channel.postTag(entry.Post.ID),
+			Updated:   entry.Updated.UTC().Format(time.RFC3339),
+			Published: entry.Published.UTC().Format(time.RFC3339),
+			Link:      atomLink{Href: url},
+			Content:   atomContent{Type: "text", Value: entry.Post.Content},
+		})
+	}
+	return marshal(doc)
+}
+
+func marshal(doc interface{}) ([]byte, error) {
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+func lastUpdated(entries []Entry) time.Time {
+	var last time.Time
+	for _, entry := range entries {
+		if entry.Updated.After(last) {
+			last = entry.Updated
+		}
+	}
+	return last
+}
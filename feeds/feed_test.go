@@ -0,0 +1,151 @@
+package feeds_test
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/feeds"
+)
+
+var channel = feeds.Channel{
+	Title:       "Posts & more",
+	Description: "The newest posts",
+	Author:      "Posts Service",
+	Link:        "https://example.com",
+	Authority:   "example.com,2021",
+	Started:     time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC),
+}
+
+var published = time.Date(2021, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+var exampleEntries = []feeds.Entry{
+	{
+		Post:      entities.Post{ID: 2, Title: `<script>alert("x")</script>`, Content: "Tom & Jerry"},
+		Published: published.Add(time.Hour),
+		Updated:   published.Add(3 * time.Hour),
+	},
+	{
+		Post:      entities.Post{ID: 1, Title: "Post 1", Content: "Content of Post 1"},
+		Published: published,
+		Updated:   published,
+	},
+}
+
+func TestRenderRSS(t *testing.T) {
+	body, err := feeds.RenderRSS(channel, exampleEntries)
+	if err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	if strings.Contains(string(body), "<script>") || !strings.Contains(string(body), "Tom &amp; Jerry") {
+		t.Fatalf("Expected markup in posts to be escaped; Got:\n%s", body)
+	}
+
+	var doc struct {
+		Version string `xml:"version,attr"`
+		Channel struct {
+			Title         string `xml:"title"`
+			LastBuildDate string `xml:"lastBuildDate"`
+			Items         []struct {
+				Title   string `xml:"title"`
+				Link    string `xml:"link"`
+				GUID    string `xml:"guid"`
+				PubDate string `xml:"pubDate"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("Expected well-formed XML; Got: '%v'", err)
+	}
+
+	if doc.Version != "2.0" || doc.Channel.Title != "Posts & more" {
+		t.Fatalf("Expected an RSS 2.0 channel; Got: '%+v'", doc)
+	}
+	if doc.Channel.LastBuildDate != "Tue, 01 Jun 2021 15:00:00 +0000" {
+		t.Fatalf("Expected the latest update as lastBuildDate; Got: '%s'", doc.Channel.LastBuildDate)
+	}
+	item := doc.Channel.Items[0]
+	if item.Title != `<script>alert("x")</script>` {
+		t.Fatalf("Expected title to round-trip; Got: '%s'", item.Title)
+	}
+	if item.GUID != "tag:example.com,2021:posts/2" || item.Link != "https://example.com/posts/2" {
+		t.Fatalf("Expected GUID and link derived from the post ID; Got: '%s' and '%s'", item.GUID, item.Link)
+	}
+	if item.PubDate != "Tue, 01 Jun 2021 13:00:00 +0000" {
+		t.Fatalf("Expected RFC 1123 pubDate; Got: '%s'", item.PubDate)
+	}
+}
+
+func TestRenderAtom(t *testing.T) {
+	body, err := feeds.RenderAtom(channel, exampleEntries)
+	if err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+
+	var doc struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		ID      string   `xml:"id"`
+		Updated string   `xml:"updated"`
+		Author  string   `xml:"author>name"`
+		Entries []struct {
+			ID        string `xml:"id"`
+			Title     string `xml:"title"`
+			Updated   string `xml:"updated"`
+			Published string `xml:"published"`
+			Content   string `xml:"content"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("Expected a well-formed Atom feed; Got: '%v'", err)
+	}
+
+	if doc.ID != "tag:example.com,2021:posts" {
+		t.Fatalf("Expected a tag URI as the feed's ID; Got: '%s'", doc.ID)
+	}
+	if doc.Updated != "2021-06-01T15:00:00Z" || doc.Author != "Posts Service" {
+		t.Fatalf("Expected feed updated and author; Got: '%+v'", doc)
+	}
+	type entry struct{ ID, Title, Updated, Published, Content string }
+	expected := []entry{
+		{"tag:example.com,2021:posts/2", `<script>alert("x")</script>`, "2021-06-01T15:00:00Z", "2021-06-01T13:00:00Z", "Tom & Jerry"},
+		{"tag:example.com,2021:posts/1", "Post 1", "2021-06-01T12:00:00Z", "2021-06-01T12:00:00Z", "Content of Post 1"},
+	}
+	got := []entry{}
+	for _, e := range doc.Entries {
+		got = append(got, entry{e.ID, e.Title, e.Updated, e.Published, e.Content})
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Fatalf("Expected entries to match: \n%s", diff)
+	}
+}
+
+func TestRender_EmptyFeeds(t *testing.T) {
+	for name, render := range map[string]func(feeds.Channel, []feeds.Entry) ([]byte, error){
+		"rss":  feeds.RenderRSS,
+		"atom": feeds.RenderAtom,
+	} {
+		body, err := render(channel, nil)
+		if err != nil {
+			t.Fatalf("Expected no error rendering empty %s; Got: '%v'", name, err)
+		}
+		var v interface{}
+		if err := xml.Unmarshal(body, &v); err != nil {
+			t.Fatalf("Expected well-formed empty %s; Got: '%v'", name, err)
+		}
+	}
+}
+
+func TestRenderAtom_EmptyFeedReportsWhenItStarted(t *testing.T) {
+	body, _ := feeds.RenderAtom(channel, nil)
+
+	var doc struct {
+		Updated string `xml:"updated"`
+	}
+	xml.Unmarshal(body, &doc)
+	if doc.Updated != "2021-01-01T00:00:00Z" {
+		t.Fatalf("Expected the channel's start as updated; Got: '%s'", doc.Updated)
+	}
+}
@@ -0,0 +1,66 @@
+package feeds
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// Handler serves the newest posts at /feed.rss and /feed.atom.
+//
+// Conditional GETs are answered from an ETag of the rendered feed. There is
+// no Last-Modified: deleting a post changes the feed without any post's
+// updated time moving forward.
+type Handler struct {
+	Channel Channel
+	// Limit is how many posts each feed shows
+	Limit int
+
+	source Source
+}
+
+// NewHandler serves channel's feeds. A channel without a Started time is
+// taken to start now, so an empty feed's ETag holds steady.
+func NewHandler(source Source, channel Channel) *Handler {
+	if channel.Started.IsZero() {
+		channel.Started = time.Now()
+	}
+	return &Handler{Channel: channel, Limit: 20, source: source}
+}
+
+func (handler *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var render func(Channel, []Entry) ([]byte, error)
+	var contentType string
+	switch r.URL.Path {
+	case "/feed.rss":
+		render, contentType = RenderRSS, "application/rss+xml; charset=utf-8"
+	case "/feed.atom":
+		render, contentType = RenderAtom, "application/atom+xml; charset=utf-8"
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	entries, err := handler.source.RecentPosts(handler.Limit)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	body, err := render(handler.Channel, entries)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	// ServeContent answers If-None-Match with 304 and handles HEAD
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+}
@@ -0,0 +1,100 @@
+package feeds_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/feeds"
+)
+
+type fakeSource struct {
+	entries []feeds.Entry
+	err     error
+	limit   int
+}
+
+func (source *fakeSource) RecentPosts(limit int) ([]feeds.Entry, error) {
+	source.limit = limit
+	return source.entries, source.err
+}
+
+func serve(handler http.Handler, method, path string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, nil)
+	for name, values := range header {
+		r.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestHandler_ServesBothFeeds(t *testing.T) {
+	source := &fakeSource{entries: exampleEntries}
+	handler := feeds.NewHandler(source, channel)
+	handler.Limit = 5
+
+	testCases := map[string]string{
+		"/feed.rss":  "application/rss+xml; charset=utf-8",
+		"/feed.atom": "application/atom+xml; charset=utf-8",
+	}
+	for path, contentType := range testCases {
+		w := serve(handler, http.MethodGet, path, nil)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200 for %s; Got: %d", path, w.Code)
+		}
+		if got := w.Header().Get("Content-Type"); got != contentType {
+			t.Fatalf("Expected '%s'; Got: '%s'", contentType, got)
+		}
+		if w.Header().Get("ETag") == "" {
+			t.Fatalf("Expected an ETag for %s", path)
+		}
+		if source.limit != 5 {
+			t.Fatalf("Expected the feed limit to be used; Got: %d", source.limit)
+		}
+	}
+}
+
+func TestHandler_AnswersConditionalGets(t *testing.T) {
+	source := &fakeSource{entries: exampleEntries}
+	handler := feeds.NewHandler(source, channel)
+	etag := serve(handler, http.MethodGet, "/feed.atom", nil).Header().Get("ETag")
+
+	w := serve(handler, http.MethodGet, "/feed.atom", http.Header{"If-None-Match": {etag}})
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Fatalf("Expected an empty 304; Got: %d with %d bytes", w.Code, w.Body.Len())
+	}
+
+	source.entries = append([]feeds.Entry{{Post: entities.Post{ID: 3, Title: "New"}}}, exampleEntries...)
+	w = serve(handler, http.MethodGet, "/feed.atom", http.Header{"If-None-Match": {etag}})
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Fatalf("Expected a new feed with a new ETag; Got: %d", w.Code)
+	}
+}
+
+func TestHandler_RejectsOtherRequests(t *testing.T) {
+	testCases := []struct {
+		name     string
+		source   *fakeSource
+		method   string
+		path     string
+		expected int
+	}{
+		{"Unknown path", &fakeSource{}, http.MethodGet, "/feed.json", http.StatusNotFound},
+		{"Wrong method", &fakeSource{}, http.MethodPost, "/feed.rss", http.StatusMethodNotAllowed},
+		{"Failing source", &fakeSource{err: errors.New("boom")}, http.MethodGet, "/feed.rss", http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := serve(feeds.NewHandler(tc.source, channel), tc.method, tc.path, nil)
+
+			if w.Code != tc.expected {
+				t.Fatalf("Expected %d; Got: %d", tc.expected, w.Code)
+			}
+		})
+	}
+}
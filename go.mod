@@ -1,8 +1,10 @@
 module github.com/steve-kaufman/postsService
 
-go 1.16
+go 1.21
 
 require (
-	github.com/google/go-cmp v0.5.5 // indirect
-	github.com/mattn/go-sqlite3 v1.14.7 // indirect
+	github.com/google/go-cmp v0.5.5
+	github.com/mattn/go-sqlite3 v1.14.7
 )
+
+require golang.org/x/xerrors v0.0.0-20191204190536-9bdfabe68543 // indirect
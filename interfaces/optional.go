@@ -0,0 +1,56 @@
+package interfaces
+
+import (
+	"errors"
+
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/feeds"
+	"github.com/steve-kaufman/postsService/ranking"
+)
+
+// The functions below call the optional interfaces of a repository a
+// decorator wraps, so the decorator passes on whatever its backend can do.
+
+// SortedPosts returns the posts in by's order. backend sorts them when it
+// is a SortedPostsGetter; otherwise what posts returns is sorted in memory.
+// A decorator passes its backend as both, unless it has a cheaper way to
+// get every post.
+func SortedPosts(backend interface{}, posts PostsGetter, by ranking.Sort) ([]entities.Post, error) {
+	if sorter, ok := backend.(SortedPostsGetter); ok {
+		return sorter.GetSortedPosts(by)
+	}
+	all, err := posts.GetPosts()
+	if err != nil {
+		return nil, err
+	}
+	sorted := append([]entities.Post{}, all...)
+	ranking.SortPosts(sorted, by, nil)
+	return sorted, nil
+}
+
+// PostBySlug calls backend's GetPostBySlug, or returns
+// errors.ErrUnsupported when it has none
+func PostBySlug(backend interface{}, slug string) (entities.Post, error) {
+	if getter, ok := backend.(SlugGetter); ok {
+		return getter.GetPostBySlug(slug)
+	}
+	return entities.Post{}, errors.ErrUnsupported
+}
+
+// ChangesSince calls backend's GetChangesSince, or returns
+// errors.ErrUnsupported when it has none
+func ChangesSince(backend interface{}, seq int64, limit int) ([]entities.Change, error) {
+	if getter, ok := backend.(ChangesGetter); ok {
+		return getter.GetChangesSince(seq, limit)
+	}
+	return nil, errors.ErrUnsupported
+}
+
+// RecentPosts calls backend's RecentPosts, or returns
+// errors.ErrUnsupported when it is not a feeds.Source
+func RecentPosts(backend interface{}, limit int) ([]feeds.Entry, error) {
+	if source, ok := backend.(feeds.Source); ok {
+		return source.RecentPosts(limit)
+	}
+	return nil, errors.ErrUnsupported
+}
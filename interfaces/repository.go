@@ -1,17 +1,35 @@
 package interfaces
 
-import "github.com/steve-kaufman/postsService/entities"
+import (
+	"time"
+
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/ranking"
+)
 
 type PostsGetter interface {
 	GetPosts() ([]entities.Post, error)
 }
 
+// SlugGetter returns the post with slug, or that used to have it
+type SlugGetter interface {
+	GetPostBySlug(slug string) (entities.Post, error)
+}
+
+// SortedPostsGetter is implemented by repositories that can order posts
+// themselves, typically by an index
+type SortedPostsGetter interface {
+	GetSortedPosts(by ranking.Sort) ([]entities.Post, error)
+}
+
 type PostGetter interface {
 	GetPost(id int) (entities.Post, error)
 }
 
+// PostSaver stores a new post and returns it as stored, with the ID and
+// any slug the repository gave it
 type PostSaver interface {
-	SavePost(post entities.Post) error
+	SavePost(post entities.Post) (entities.Post, error)
 }
 
 type PostDeleter interface {
@@ -21,3 +39,35 @@ type PostDeleter interface {
 type PostUpdater interface {
 	UpdatePost(id int, data entities.Post) error
 }
+
+type Repository interface {
+	PostsGetter
+	PostGetter
+	PostSaver
+	PostDeleter
+	PostUpdater
+}
+
+// TxRunner runs fn against a repository whose calls all happen in one
+// transaction. The transaction is committed if fn returns nil and rolled
+// back otherwise.
+type TxRunner interface {
+	WithinTx(fn func(repo Repository) error) error
+}
+
+// ChangesGetter returns, in sequence order, up to limit changes made after
+// sequence number seq
+type ChangesGetter interface {
+	GetChangesSince(seq int64, limit int) ([]entities.Change, error)
+}
+
+// TrendingGetter returns the IDs of the posts trending over window, most
+// trending first
+type TrendingGetter interface {
+	GetTrending(window time.Duration) ([]int, error)
+}
+
+// ContentRenderer returns a post's content as HTML that is safe to embed
+type ContentRenderer interface {
+	RenderContent(post entities.Post) string
+}
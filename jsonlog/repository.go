@@ -0,0 +1,426 @@
+// Package jsonlog is a pure-Go storage backend that keeps every post in
+// memory and persists changes to an append-only JSON-lines log.
+//
+// Each line of the log is one committed entry. A line that was only
+// partially written before a crash is discarded when the log is reopened.
+package jsonlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/interfaces"
+	"github.com/steve-kaufman/postsService/useCases"
+)
+
+var ErrCorrupt = errors.New("log is corrupt before its final line")
+
+// DefaultCompactAfter is the number of entries a log may reach before it
+// is considered for compaction
+const DefaultCompactAfter = 1000
+
+// entry is a single line of the log
+type entry struct {
+	NextID int  `json:"next_id,omitempty"`
+	Ops    []op `json:"ops,omitempty"`
+}
+
+type op struct {
+	Put    *entities.Post `json:"put,omitempty"`
+	Delete int            `json:"delete,omitempty"`
+}
+
+type Repository struct {
+	// CompactAfter is the number of entries after which the log is
+	// rewritten, provided most of them no longer describe live posts
+	CompactAfter int
+
+	mu      sync.RWMutex
+	path    string
+	file    *os.File
+	size    int64
+	entries int
+	posts   map[int]entities.Post
+	nextID  int
+}
+
+// NewRepository opens the log at path, creating it if needed, and replays
+// it into memory
+func NewRepository(path string) (*Repository, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := &Repository{
+		CompactAfter: DefaultCompactAfter,
+		path:         path,
+		file:         file,
+		posts:        map[int]entities.Post{},
+		nextID:       1,
+	}
+	if err := repo.replay(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return repo, nil
+}
+
+func (repo *Repository) Close() error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	return repo.file.Close()
+}
+
+func (repo *Repository) GetPosts() ([]entities.Post, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	return repo.getPosts(), nil
+}
+
+func (repo *Repository) GetPost(id int) (entities.Post, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	return repo.getPost(id)
+}
+
+func (repo *Repository) SavePost(post entities.Post) (entities.Post, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	o := repo.planSave(post)
+	if err := repo.commit([]op{o}); err != nil {
+		return entities.Post{}, err
+	}
+	return *o.Put, nil
+}
+
+func (repo *Repository) DeletePost(id int) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	o, err := repo.planDelete(id)
+	if err != nil {
+		return err
+	}
+	return repo.commit([]op{o})
+}
+
+func (repo *Repository) UpdatePost(id int, data entities.Post) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	o, err := repo.planUpdate(id, data)
+	if err != nil {
+		return err
+	}
+	return repo.commit([]op{o})
+}
+
+// WithinTx applies fn's changes in memory as it makes them and writes them
+// to the log as a single entry once it succeeds. If fn or the write fails,
+// the changes are undone.
+func (repo *Repository) WithinTx(fn func(repo interfaces.Repository) error) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	tx := &txRepository{repo: repo, nextID: repo.nextID}
+	if err := fn(tx); err != nil {
+		tx.rollback()
+		return err
+	}
+	if len(tx.ops) == 0 {
+		return nil
+	}
+	if err := repo.appendEntry(entry{Ops: tx.ops}); err != nil {
+		tx.rollback()
+		return err
+	}
+	repo.maybeCompact()
+	return nil
+}
+
+// Compact rewrites the log so it holds exactly one put per live post
+func (repo *Repository) Compact() error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	return repo.compact()
+}
+
+// commit writes ops to the log and only then applies them
+func (repo *Repository) commit(ops []op) error {
+	if err := repo.appendEntry(entry{Ops: ops}); err != nil {
+		return err
+	}
+	for _, o := range ops {
+		repo.apply(o)
+	}
+	repo.maybeCompact()
+	return nil
+}
+
+func (repo *Repository) getPosts() []entities.Post {
+	posts := make([]entities.Post, 0, len(repo.posts))
+	for _, post := range repo.posts {
+		posts = append(posts, post)
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].ID < posts[j].ID
+	})
+	return posts
+}
+
+func (repo *Repository) getPost(id int) (entities.Post, error) {
+	post, ok := repo.posts[id]
+	if !ok {
+		return entities.Post{}, useCases.ErrNotFound
+	}
+	return post, nil
+}
+
+func (repo *Repository) planSave(post entities.Post) op {
+	post.ID = repo.nextID
+	return op{Put: &post}
+}
+
+func (repo *Repository) planDelete(id int) (op, error) {
+	if _, ok := repo.posts[id]; !ok {
+		return op{}, useCases.ErrNotFound
+	}
+	return op{Delete: id}, nil
+}
+
+func (repo *Repository) planUpdate(id int, data entities.Post) (op, error) {
+	if _, ok := repo.posts[id]; !ok {
+		return op{}, useCases.ErrNotFound
+	}
+	data.ID = id
+	return op{Put: &data}, nil
+}
+
+// apply changes the in-memory state and returns the op that undoes it
+func (repo *Repository) apply(o op) op {
+	if o.Put != nil {
+		id := o.Put.ID
+		undo := op{Delete: id}
+		if old, ok := repo.posts[id]; ok {
+			undo = op{Put: &old}
+		}
+		repo.posts[id] = *o.Put
+		if id >= repo.nextID {
+			repo.nextID = id + 1
+		}
+		return undo
+	}
+
+	old := repo.posts[o.Delete]
+	delete(repo.posts, o.Delete)
+	return op{Put: &old}
+}
+
+func (repo *Repository) replay() error {
+	reader := bufio.NewReader(repo.file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// anything left over is a torn final line
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		var e entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			if _, err := reader.Peek(1); err != io.EOF {
+				return ErrCorrupt
+			}
+			break
+		}
+		repo.applyEntry(e)
+		repo.size += int64(len(line))
+		repo.entries++
+	}
+
+	if err := repo.file.Truncate(repo.size); err != nil {
+		return err
+	}
+	_, err := repo.file.Seek(repo.size, io.SeekStart)
+	return err
+}
+
+func (repo *Repository) applyEntry(e entry) {
+	if e.NextID > repo.nextID {
+		repo.nextID = e.NextID
+	}
+	for _, o := range e.Ops {
+		repo.apply(o)
+	}
+}
+
+// appendEntry writes e as one line and fsyncs it. A failed write is cut
+// back off the log so the next entry starts on a clean line.
+func (repo *Repository) appendEntry(e entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if _, err := repo.file.Write(line); err != nil {
+		repo.truncateTo(repo.size)
+		return err
+	}
+	if err := repo.file.Sync(); err != nil {
+		repo.truncateTo(repo.size)
+		return err
+	}
+	repo.size += int64(len(line))
+	repo.entries++
+	return nil
+}
+
+func (repo *Repository) truncateTo(size int64) {
+	repo.file.Truncate(size)
+	repo.file.Seek(size, io.SeekStart)
+}
+
+func (repo *Repository) maybeCompact() {
+	if repo.CompactAfter <= 0 || repo.entries < repo.CompactAfter {
+		return
+	}
+	if repo.entries < 2*len(repo.posts) {
+		return
+	}
+	// a failed compaction leaves the existing log in place, which is
+	// still complete, so the error is not the caller's concern
+	repo.compact()
+}
+
+// compact writes a fresh log next to the current one and renames it into
+// place, so a crash part way through leaves the old log untouched
+func (repo *Repository) compact() error {
+	tmpPath := repo.path + ".compact"
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath)
+
+	size, entries, err := repo.writeSnapshot(tmp)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := os.Rename(tmpPath, repo.path); err != nil {
+		tmp.Close()
+		return err
+	}
+	syncDir(filepath.Dir(repo.path))
+
+	repo.file.Close()
+	repo.file = tmp
+	repo.size = size
+	repo.entries = entries
+	_, err = repo.file.Seek(size, io.SeekStart)
+	return err
+}
+
+func (repo *Repository) writeSnapshot(w io.Writer) (int64, int, error) {
+	buf := bufio.NewWriter(w)
+	var size int64
+	entries := 0
+
+	write := func(e entry) error {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		n, err := buf.Write(append(line, '\n'))
+		size += int64(n)
+		entries++
+		return err
+	}
+
+	if err := write(entry{NextID: repo.nextID}); err != nil {
+		return 0, 0, err
+	}
+	for _, post := range repo.getPosts() {
+		post := post
+		if err := write(entry{Ops: []op{{Put: &post}}}); err != nil {
+			return 0, 0, err
+		}
+	}
+	return size, entries, buf.Flush()
+}
+
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
+
+// txRepository applies changes straight to the repository while
+// remembering how to undo them
+type txRepository struct {
+	repo   *Repository
+	nextID int
+	ops    []op
+	undo   []op
+}
+
+func (tx *txRepository) GetPosts() ([]entities.Post, error) {
+	return tx.repo.getPosts(), nil
+}
+
+func (tx *txRepository) GetPost(id int) (entities.Post, error) {
+	return tx.repo.getPost(id)
+}
+
+func (tx *txRepository) SavePost(post entities.Post) (entities.Post, error) {
+	o := tx.repo.planSave(post)
+	tx.record(o)
+	return *o.Put, nil
+}
+
+func (tx *txRepository) DeletePost(id int) error {
+	o, err := tx.repo.planDelete(id)
+	if err != nil {
+		return err
+	}
+	tx.record(o)
+	return nil
+}
+
+func (tx *txRepository) UpdatePost(id int, data entities.Post) error {
+	o, err := tx.repo.planUpdate(id, data)
+	if err != nil {
+		return err
+	}
+	tx.record(o)
+	return nil
+}
+
+func (tx *txRepository) record(o op) {
+	tx.ops = append(tx.ops, o)
+	tx.undo = append(tx.undo, tx.repo.apply(o))
+}
+
+func (tx *txRepository) rollback() {
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		tx.repo.apply(tx.undo[i])
+	}
+	tx.repo.nextID = tx.nextID
+}
@@ -0,0 +1,147 @@
+package jsonlog_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/jsonlog"
+	"github.com/steve-kaufman/postsService/repotest"
+)
+
+func open(t *testing.T, path string) *jsonlog.Repository {
+	t.Helper()
+	repo, err := jsonlog.NewRepository(path)
+	if err != nil {
+		t.Fatalf("Expected no error opening log; Got: '%v'", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+func seed(t *testing.T, repo *jsonlog.Repository) {
+	t.Helper()
+	for _, post := range repotest.ExamplePosts {
+		if _, err := repo.SavePost(post); err != nil {
+			t.Fatalf("Expected no error; Got: '%v'", err)
+		}
+	}
+}
+
+func expectPosts(t *testing.T, repo *jsonlog.Repository, expected []entities.Post) {
+	t.Helper()
+	posts, err := repo.GetPosts()
+	if err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	if diff := cmp.Diff(expected, posts); diff != "" {
+		t.Fatalf("Expected posts to match: \n%s", diff)
+	}
+}
+
+func TestRepository_Conformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repository {
+		return open(t, filepath.Join(t.TempDir(), "posts.log"))
+	})
+}
+
+func TestReopening_RestoresPosts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "posts.log")
+	repo := open(t, path)
+	seed(t, repo)
+	repo.DeletePost(1)
+	repo.UpdatePost(2, entities.Post{Title: "Foo"})
+	repo.Close()
+
+	reopened := open(t, path)
+
+	expectPosts(t, reopened, []entities.Post{{ID: 2, Title: "Foo"}, repotest.ExamplePosts[2]})
+}
+
+func TestReopening_DiscardsTornFinalLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "posts.log")
+	repo := open(t, path)
+	seed(t, repo)
+	repo.Close()
+
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"ops":[{"delete":1`)
+	f.Close()
+
+	reopened := open(t, path)
+	expectPosts(t, reopened, repotest.ExamplePosts)
+
+	if _, err := reopened.SavePost(entities.Post{Title: "Foo"}); err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	reopened.Close()
+
+	expectPosts(t, open(t, path), append(repotest.ExamplePosts[:3:3], entities.Post{ID: 4, Title: "Foo"}))
+}
+
+func TestReopening_ReturnsErrCorrupt_ForBadLineBeforeTheEnd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "posts.log")
+	repo := open(t, path)
+	seed(t, repo)
+	repo.Close()
+
+	data, _ := os.ReadFile(path)
+	lines := strings.SplitAfter(string(data), "\n")
+	lines[1] = "garbage\n"
+	os.WriteFile(path, []byte(strings.Join(lines, "")), 0644)
+
+	_, err := jsonlog.NewRepository(path)
+
+	if err != jsonlog.ErrCorrupt {
+		t.Fatalf("Expected ErrCorrupt; Got: '%v'", err)
+	}
+}
+
+func TestCompact_ShrinksLogAndKeepsPosts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "posts.log")
+	repo := open(t, path)
+	repo.CompactAfter = 0
+	seed(t, repo)
+	for i := 0; i < 50; i++ {
+		repo.UpdatePost(1, entities.Post{Title: "Foo"})
+	}
+	repo.DeletePost(3)
+	before, _ := os.Stat(path)
+
+	if err := repo.Compact(); err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+
+	after, _ := os.Stat(path)
+	if after.Size() >= before.Size() {
+		t.Fatalf("Expected log to shrink from %d bytes; Got: %d", before.Size(), after.Size())
+	}
+	repo.SavePost(entities.Post{Title: "Bar"})
+	repo.Close()
+
+	expected := []entities.Post{{ID: 1, Title: "Foo"}, repotest.ExamplePosts[1], {ID: 4, Title: "Bar"}}
+	expectPosts(t, open(t, path), expected)
+}
+
+func TestWrites_CompactLogAutomatically(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "posts.log")
+	repo := open(t, path)
+	repo.CompactAfter = 10
+	seed(t, repo)
+
+	for i := 0; i < 20; i++ {
+		repo.UpdatePost(2, entities.Post{Title: "Foo"})
+	}
+
+	data, _ := os.ReadFile(path)
+	if lines := strings.Count(string(data), "\n"); lines >= 10 {
+		t.Fatalf("Expected log to have been compacted; Got: %d lines", lines)
+	}
+	repo.Close()
+
+	expected := []entities.Post{repotest.ExamplePosts[0], {ID: 2, Title: "Foo"}, repotest.ExamplePosts[2]}
+	expectPosts(t, open(t, path), expected)
+}
@@ -0,0 +1,209 @@
+package live
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const writeWait = 10 * time.Second
+
+// outgoingQueue is how many frames may wait for a slow client before it is
+// disconnected
+const outgoingQueue = 64
+
+type outgoing struct {
+	opcode  byte
+	payload []byte
+}
+
+type conn struct {
+	hub       *Hub
+	netConn   net.Conn
+	reader    *bufio.Reader
+	out       chan outgoing
+	limiter   *limiter
+	closeOnce sync.Once
+
+	// subscribed is the posts the connection is subscribed to, guarded by
+	// hub.mu
+	subscribed map[int]bool
+}
+
+// upgrade performs the opening handshake from RFC 6455 section 4.2
+func upgrade(w http.ResponseWriter, r *http.Request) (net.Conn, *bufio.Reader, bool) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return nil, nil, false
+	}
+	if !headerHasToken(r.Header, "Connection", "upgrade") || !headerHasToken(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "websocket upgrade required", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusUpgradeRequired)
+		return nil, nil, false
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !validKey(key) {
+		http.Error(w, "invalid Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket unsupported", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	netConn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, nil, false
+	}
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		netConn.Close()
+		return nil, nil, false
+	}
+	return netConn, rw.Reader, true
+}
+
+func headerHasToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func validKey(key string) bool {
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	return err == nil && len(decoded) == 16
+}
+
+// readMessage returns the next complete data message, answering pings and
+// reassembling fragmented messages along the way
+func (c *conn) readMessage() ([]byte, error) {
+	var message bytes.Buffer
+	fragmented := false
+
+	for {
+		c.netConn.SetReadDeadline(time.Now().Add(c.hub.PongWait))
+		f, err := readFrame(c.reader, c.hub.MaxMessage)
+		if err != nil {
+			return nil, err
+		}
+
+		switch f.opcode {
+		case opPing:
+			c.queue(outgoing{opcode: opPong, payload: f.payload})
+			continue
+		case opPong:
+			// the read deadline has already been extended
+			continue
+		case opClose:
+			return nil, errClosedByPeer
+		case opText, opBinary:
+			if fragmented {
+				return nil, ErrProtocol
+			}
+		case opContinuation:
+			if !fragmented {
+				return nil, ErrProtocol
+			}
+		default:
+			return nil, ErrProtocol
+		}
+
+		if message.Len()+len(f.payload) > c.hub.MaxMessage {
+			return nil, ErrMessageTooBig
+		}
+		message.Write(f.payload)
+		if f.fin {
+			return message.Bytes(), nil
+		}
+		fragmented = true
+	}
+}
+
+var errClosedByPeer = errors.New("closed by peer")
+
+// writeLoop is the only writer to the connection. It also sends pings, and
+// closes the connection once a close frame has been written.
+func (c *conn) writeLoop() {
+	ping := time.NewTicker(c.hub.PingInterval)
+	defer ping.Stop()
+	defer c.netConn.Close()
+
+	for {
+		var msg outgoing
+		select {
+		case msg = <-c.out:
+		case <-ping.C:
+			msg = outgoing{opcode: opPing}
+		}
+
+		c.netConn.SetWriteDeadline(time.Now().Add(writeWait))
+		if err := writeFrame(c.netConn, msg.opcode, msg.payload); err != nil {
+			return
+		}
+		if msg.opcode == opClose {
+			return
+		}
+	}
+}
+
+// queue hands a frame to the writer, disconnecting clients that have
+// fallen too far behind
+func (c *conn) queue(msg outgoing) {
+	select {
+	case c.out <- msg:
+	default:
+		c.close(closePolicyViolation, "too slow")
+	}
+}
+
+func (c *conn) sendJSON(v interface{}) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	c.queue(outgoing{opcode: opText, payload: payload})
+}
+
+// close starts the closing handshake; the writer drops the connection
+// after sending the close frame
+func (c *conn) close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		select {
+		case c.out <- outgoing{opcode: opClose, payload: closePayload(code, reason)}:
+		default:
+			c.netConn.Close()
+		}
+	})
+}
+
+func closeCodeFor(err error) int {
+	switch err {
+	case errClosedByPeer:
+		return closeNormal
+	case ErrProtocol:
+		return closeProtocolError
+	case ErrMessageTooBig:
+		return closeTooBig
+	}
+	return closeGoingAway
+}
@@ -0,0 +1,133 @@
+package live
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// The subset of RFC 6455 a server needs: reading masked client frames and
+// writing unmasked server frames.
+
+var ErrProtocol = errors.New("websocket protocol error")
+var ErrMessageTooBig = errors.New("websocket message too big")
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// Close status codes from RFC 6455 section 7.4.1
+const (
+	closeNormal          = 1000
+	closeGoingAway       = 1001
+	closeProtocolError   = 1002
+	closePolicyViolation = 1008
+	closeTooBig          = 1009
+)
+
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+type frame struct {
+	fin     bool
+	opcode  byte
+	payload []byte
+}
+
+func (f frame) isControl() bool {
+	return f.opcode&0x8 != 0
+}
+
+// acceptKey returns the Sec-WebSocket-Accept value for a client's key
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// readFrame reads one client frame, rejecting unmasked frames and payloads
+// longer than maxPayload
+func readFrame(r *bufio.Reader, maxPayload int) (frame, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return frame{}, err
+	}
+
+	f := frame{fin: header[0]&0x80 != 0, opcode: header[0] & 0x0f}
+	if header[0]&0x70 != 0 {
+		// no extensions were negotiated, so RSV bits must be clear
+		return frame{}, ErrProtocol
+	}
+	if header[1]&0x80 == 0 {
+		return frame{}, ErrProtocol
+	}
+
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return frame{}, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return frame{}, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+
+	if f.isControl() && (length > 125 || !f.fin) {
+		return frame{}, ErrProtocol
+	}
+	if length > uint64(maxPayload) {
+		return frame{}, ErrMessageTooBig
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(r, mask[:]); err != nil {
+		return frame{}, err
+	}
+	f.payload = make([]byte, length)
+	if _, err := io.ReadFull(r, f.payload); err != nil {
+		return frame{}, err
+	}
+	for i := range f.payload {
+		f.payload[i] ^= mask[i%4]
+	}
+	return f, nil
+}
+
+// writeFrame writes payload as a single unmasked, final frame
+func writeFrame(w io.Writer, opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode}
+	switch length := len(payload); {
+	case length <= 125:
+		header = append(header, byte(length))
+	case length <= 0xffff:
+		header = append(header, 126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(length))
+	default:
+		header = append(header, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(length))
+	}
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+func closePayload(code int, reason string) []byte {
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	return append(payload, reason...)
+}
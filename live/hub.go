@@ -0,0 +1,289 @@
+// Package live serves a WebSocket endpoint for live vote counters. Clients
+// subscribe to post IDs, receive Likes/Dislikes updates as they change and
+// can vote over the same connection.
+//
+// Client messages:
+//
+//	{"type":"subscribe","ids":[1,2]}
+//	{"type":"unsubscribe","ids":[1]}
+//	{"type":"like","id":1}
+//	{"type":"dislike","id":1}
+//
+// Server messages:
+//
+//	{"type":"counts","id":1,"likes":3,"dislikes":1}  current counts of a subscribed post
+//	{"type":"voted","id":1,"likes":4,"dislikes":1}   reply to the client's own vote
+//	{"type":"deleted","id":1}                        a subscribed post was deleted
+//	{"type":"error","id":1,"error":"post not found"}
+package live
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/events"
+	"github.com/steve-kaufman/postsService/interfaces"
+	"github.com/steve-kaufman/postsService/render"
+	"github.com/steve-kaufman/postsService/useCases"
+)
+
+type Repository interface {
+	interfaces.PostGetter
+	interfaces.TxRunner
+}
+
+type clientMessage struct {
+	Type string `json:"type"`
+	ID   int    `json:"id"`
+	IDs  []int  `json:"ids"`
+}
+
+type countsMessage struct {
+	Type     string `json:"type"`
+	ID       int    `json:"id"`
+	Likes    int    `json:"likes"`
+	Dislikes int    `json:"dislikes"`
+}
+
+type postMessage struct {
+	Type string `json:"type"`
+	ID   int    `json:"id"`
+}
+
+type errorMessage struct {
+	Type  string `json:"type"`
+	ID    int    `json:"id,omitempty"`
+	Error string `json:"error"`
+}
+
+// Hub serves WebSocket connections and pushes counter updates it receives
+// through Handle to the connections subscribed to each post
+type Hub struct {
+	PingInterval time.Duration
+	// PongWait is how long a connection may stay silent, including not
+	// answering pings, before it is closed
+	PongWait   time.Duration
+	MaxMessage int
+	// Rate and Burst limit the messages each connection may send
+	Rate  float64
+	Burst int
+	// MaxSubscribeIDs is how many IDs one subscribe message may list, and
+	// MaxSubscriptions how many posts one connection may be subscribed to
+	MaxSubscribeIDs  int
+	MaxSubscriptions int
+
+	repo      Repository
+	publisher events.Publisher
+
+	mu   sync.Mutex
+	subs map[int]map[*conn]bool
+	// broadcasts counts the messages sent to each subscribed post's
+	// subscribers, so subscribe can tell whether one went out while it
+	// was reading the post
+	broadcasts map[int]uint64
+}
+
+// NewHub returns a Hub that votes through repo and publishes the resulting
+// events to publisher. The hub's own Handle should be subscribed to the
+// same events for voters to see each other's votes.
+func NewHub(repo Repository, publisher events.Publisher) *Hub {
+	return &Hub{
+		PingInterval: 30 * time.Second,
+		PongWait:     60 * time.Second,
+		MaxMessage:   4096,
+		Rate:         5,
+		Burst:        10,
+
+		MaxSubscribeIDs:  100,
+		MaxSubscriptions: 500,
+
+		repo:       repo,
+		publisher:  publisher,
+		subs:       map[int]map[*conn]bool{},
+		broadcasts: map[int]uint64{},
+	}
+}
+
+// Handle pushes counter changes and deletions to subscribers. It fits
+// events.Bus.Subscribe.
+func (hub *Hub) Handle(event events.Event) error {
+	switch e := event.(type) {
+	case events.PostVoted:
+		hub.broadcast(e.Post.ID, counts("counts", e.Post))
+	case events.PostUpdated:
+		if e.Before.Likes != e.After.Likes || e.Before.Dislikes != e.After.Dislikes {
+			hub.broadcast(e.After.ID, counts("counts", e.After))
+		}
+	case events.PostDeleted:
+		hub.broadcast(e.Post.ID, postMessage{Type: "deleted", ID: e.Post.ID})
+		hub.mu.Lock()
+		for c := range hub.subs[e.Post.ID] {
+			hub.remove(c, e.Post.ID)
+		}
+		hub.mu.Unlock()
+	}
+	return nil
+}
+
+func (hub *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	netConn, reader, ok := upgrade(w, r)
+	if !ok {
+		return
+	}
+
+	c := &conn{
+		hub:     hub,
+		netConn: netConn,
+		reader:  reader,
+		out:     make(chan outgoing, outgoingQueue),
+		limiter: newLimiter(hub.Rate, hub.Burst),
+
+		subscribed: map[int]bool{},
+	}
+	go c.writeLoop()
+	defer hub.unsubscribeAll(c)
+
+	for {
+		message, err := c.readMessage()
+		if err != nil {
+			c.close(closeCodeFor(err), "")
+			return
+		}
+		hub.handleMessage(c, message)
+	}
+}
+
+func (hub *Hub) handleMessage(c *conn, message []byte) {
+	if !c.limiter.allow(time.Now()) {
+		c.sendJSON(errorMessage{Type: "error", Error: "rate limited"})
+		return
+	}
+
+	var msg clientMessage
+	if err := json.Unmarshal(message, &msg); err != nil {
+		c.sendJSON(errorMessage{Type: "error", Error: "invalid message"})
+		return
+	}
+
+	switch msg.Type {
+	case "subscribe":
+		if len(msg.IDs) > hub.MaxSubscribeIDs {
+			c.sendJSON(errorMessage{Type: "error", Error: "too many ids"})
+			return
+		}
+		for _, id := range msg.IDs {
+			hub.subscribe(c, id)
+		}
+	case "unsubscribe":
+		for _, id := range msg.IDs {
+			hub.unsubscribe(c, id)
+		}
+	case "like":
+		hub.vote(c, msg.ID, useCases.LikePost)
+	case "dislike":
+		hub.vote(c, msg.ID, useCases.DislikePost)
+	default:
+		c.sendJSON(errorMessage{Type: "error", Error: "unknown message type"})
+	}
+}
+
+type voteFunc func(interfaces.TxRunner, events.Publisher, interfaces.ContentRenderer, int) (entities.Post, error)
+
+func (hub *Hub) vote(c *conn, id int, vote voteFunc) {
+	post, err := vote(hub.repo, hub.publisher, render.Skip, id)
+	if err != nil {
+		c.sendJSON(errorMessage{Type: "error", ID: id, Error: useCases.Public(err).Error()})
+		return
+	}
+	c.sendJSON(counts("voted", post))
+}
+
+// subscribe sends the post's current counts and then any changes to it.
+// The post is read without the lock, so a change broadcast between the
+// read and registering c would be missed; reading it again once c is
+// registered catches that, unless a broadcast since has already sent c
+// something newer.
+func (hub *Hub) subscribe(c *conn, id int) {
+	hub.mu.Lock()
+	full := !c.subscribed[id] && len(c.subscribed) >= hub.MaxSubscriptions
+	hub.mu.Unlock()
+	if full {
+		c.sendJSON(errorMessage{Type: "error", ID: id, Error: "too many subscriptions"})
+		return
+	}
+
+	post, err := useCases.GetOnePost(hub.repo, render.Skip, id)
+	if err != nil {
+		c.sendJSON(errorMessage{Type: "error", ID: id, Error: useCases.Public(err).Error()})
+		return
+	}
+
+	hub.mu.Lock()
+	if hub.subs[id] == nil {
+		hub.subs[id] = map[*conn]bool{}
+	}
+	hub.subs[id][c] = true
+	c.subscribed[id] = true
+	broadcasts := hub.broadcasts[id]
+	c.sendJSON(counts("counts", post))
+	hub.mu.Unlock()
+
+	latest, err := useCases.GetOnePost(hub.repo, render.Skip, id)
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	// c may have unsubscribed, been told of a deletion or been sent newer
+	// counts in the meantime
+	if !hub.subs[id][c] || hub.broadcasts[id] != broadcasts {
+		return
+	}
+	switch {
+	case errors.Is(err, useCases.ErrNotFound):
+		c.sendJSON(postMessage{Type: "deleted", ID: id})
+		hub.remove(c, id)
+	case err == nil && (latest.Likes != post.Likes || latest.Dislikes != post.Dislikes):
+		c.sendJSON(counts("counts", latest))
+	}
+}
+
+func (hub *Hub) unsubscribe(c *conn, id int) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	hub.remove(c, id)
+}
+
+// remove unsubscribes c from id; the caller holds hub.mu
+func (hub *Hub) remove(c *conn, id int) {
+	delete(c.subscribed, id)
+	delete(hub.subs[id], c)
+	if len(hub.subs[id]) == 0 {
+		delete(hub.subs, id)
+		delete(hub.broadcasts, id)
+	}
+}
+
+func (hub *Hub) unsubscribeAll(c *conn) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	for id := range c.subscribed {
+		hub.remove(c, id)
+	}
+}
+
+func (hub *Hub) broadcast(id int, v interface{}) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	if len(hub.subs[id]) > 0 {
+		hub.broadcasts[id]++
+	}
+	for c := range hub.subs[id] {
+		c.sendJSON(v)
+	}
+}
+
+func counts(messageType string, post entities.Post) countsMessage {
+	return countsMessage{Type: messageType, ID: post.ID, Likes: post.Likes, Dislikes: post.Dislikes}
+}
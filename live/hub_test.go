@@ -0,0 +1,398 @@
+package live_test
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/events"
+	"github.com/steve-kaufman/postsService/live"
+	"github.com/steve-kaufman/postsService/memory"
+)
+
+var examplePosts = []entities.Post{
+	{ID: 1, Title: "Post 1", Likes: 2, Dislikes: 1},
+	{ID: 2, Title: "Post 2", Likes: 5, Dislikes: 2},
+}
+
+// client speaks just enough RFC 6455 to drive the hub
+type client struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+type serverFrame struct {
+	opcode  byte
+	payload []byte
+}
+
+func setup(t *testing.T) (*live.Hub, *httptest.Server, *memory.Repository) {
+	repo := memory.NewRepository(examplePosts...)
+	bus := events.NewBus(nil)
+	hub := live.NewHub(repo, bus)
+	bus.Subscribe("live", hub.Handle)
+	server := httptest.NewServer(hub)
+	t.Cleanup(server.Close)
+	return hub, server, repo
+}
+
+func dial(t *testing.T, server *httptest.Server) *client {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatalf("Expected no error dialing; Got: '%v'", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	fmt.Fprint(conn, "GET /live HTTP/1.1\r\n"+
+		"Host: example.com\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"+
+		"Sec-WebSocket-Version: 13\r\n\r\n")
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("Expected handshake response; Got: '%v'", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected 101; Got: %d", resp.StatusCode)
+	}
+	// the example key and accept value from RFC 6455 section 1.3
+	if accept := resp.Header.Get("Sec-WebSocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Expected RFC 6455 accept key; Got: '%s'", accept)
+	}
+	return &client{t: t, conn: conn, reader: reader}
+}
+
+func (c *client) writeFrame(fin bool, opcode byte, payload []byte) {
+	c.t.Helper()
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+	frame := []byte{first}
+	switch {
+	case len(payload) <= 125:
+		frame = append(frame, 0x80|byte(len(payload)))
+	default:
+		frame = append(frame, 0x80|126, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(len(payload)))
+	}
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		c.t.Fatalf("Expected no error writing frame; Got: '%v'", err)
+	}
+}
+
+func (c *client) send(message string) {
+	c.t.Helper()
+	c.writeFrame(true, 0x1, []byte(message))
+}
+
+func (c *client) readFrame() serverFrame {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		c.t.Fatalf("Expected a frame; Got: '%v'", err)
+	}
+	length := int(header[1] & 0x7f)
+	if length == 126 {
+		var ext [2]byte
+		io.ReadFull(c.reader, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, length)
+	io.ReadFull(c.reader, payload)
+	return serverFrame{opcode: header[0] & 0x0f, payload: payload}
+}
+
+// receive returns the next text message, skipping pings
+func (c *client) receive() map[string]interface{} {
+	c.t.Helper()
+	for {
+		f := c.readFrame()
+		if f.opcode == 0x9 {
+			continue
+		}
+		if f.opcode != 0x1 {
+			c.t.Fatalf("Expected a text frame; Got opcode %d: '%s'", f.opcode, f.payload)
+		}
+		var msg map[string]interface{}
+		json.Unmarshal(f.payload, &msg)
+		return msg
+	}
+}
+
+func (c *client) expect(expected string) {
+	c.t.Helper()
+	var want map[string]interface{}
+	json.Unmarshal([]byte(expected), &want)
+	if diff := cmp.Diff(want, c.receive()); diff != "" {
+		c.t.Fatalf("Expected message %s: \n%s", expected, diff)
+	}
+}
+
+func TestSubscribe_SendsCurrentCountsThenUpdates(t *testing.T) {
+	_, server, _ := setup(t)
+	watcher := dial(t, server)
+	voter := dial(t, server)
+
+	watcher.send(`{"type":"subscribe","ids":[1,3]}`)
+	watcher.expect(`{"type":"counts","id":1,"likes":2,"dislikes":1}`)
+	watcher.expect(`{"type":"error","id":3,"error":"post not found"}`)
+
+	voter.send(`{"type":"like","id":1}`)
+	voter.expect(`{"type":"voted","id":1,"likes":3,"dislikes":1}`)
+	watcher.expect(`{"type":"counts","id":1,"likes":3,"dislikes":1}`)
+
+	voter.send(`{"type":"dislike","id":2}`)
+	voter.expect(`{"type":"voted","id":2,"likes":5,"dislikes":3}`)
+	voter.send(`{"type":"dislike","id":1}`)
+	voter.expect(`{"type":"voted","id":1,"likes":3,"dislikes":2}`)
+	// nothing for post 2, which the watcher never subscribed to
+	watcher.expect(`{"type":"counts","id":1,"likes":3,"dislikes":2}`)
+}
+
+func TestUnsubscribe_StopsUpdates(t *testing.T) {
+	_, server, _ := setup(t)
+	c := dial(t, server)
+
+	c.send(`{"type":"subscribe","ids":[1,2]}`)
+	c.expect(`{"type":"counts","id":1,"likes":2,"dislikes":1}`)
+	c.expect(`{"type":"counts","id":2,"likes":5,"dislikes":2}`)
+	c.send(`{"type":"unsubscribe","ids":[1]}`)
+
+	c.send(`{"type":"like","id":1}`)
+	c.expect(`{"type":"voted","id":1,"likes":3,"dislikes":1}`)
+	c.send(`{"type":"like","id":2}`)
+	c.expect(`{"type":"counts","id":2,"likes":6,"dislikes":2}`)
+	c.expect(`{"type":"voted","id":2,"likes":6,"dislikes":2}`)
+}
+
+func TestHub_ReportsDeletedPosts(t *testing.T) {
+	hub, server, _ := setup(t)
+	c := dial(t, server)
+	c.send(`{"type":"subscribe","ids":[2]}`)
+	c.expect(`{"type":"counts","id":2,"likes":5,"dislikes":2}`)
+
+	hub.Handle(events.PostDeleted{Post: examplePosts[1]})
+
+	c.expect(`{"type":"deleted","id":2}`)
+}
+
+func TestHub_RejectsBadMessages(t *testing.T) {
+	_, server, _ := setup(t)
+	c := dial(t, server)
+
+	c.send(`not json`)
+	c.expect(`{"type":"error","error":"invalid message"}`)
+	c.send(`{"type":"shout"}`)
+	c.expect(`{"type":"error","error":"unknown message type"}`)
+	c.send(`{"type":"like","id":9}`)
+	c.expect(`{"type":"error","id":9,"error":"post not found"}`)
+}
+
+func TestHub_ReassemblesFragmentedMessages(t *testing.T) {
+	_, server, _ := setup(t)
+	c := dial(t, server)
+
+	c.writeFrame(false, 0x1, []byte(`{"type":"sub`))
+	c.writeFrame(true, 0x9, []byte("mid-message ping"))
+	c.writeFrame(true, 0x0, []byte(`scribe","ids":[1]}`))
+
+	if f := c.readFrame(); f.opcode != 0xA || string(f.payload) != "mid-message ping" {
+		t.Fatalf("Expected pong echoing the ping; Got opcode %d: '%s'", f.opcode, f.payload)
+	}
+	c.expect(`{"type":"counts","id":1,"likes":2,"dislikes":1}`)
+}
+
+func TestHub_RateLimitsEachConnection(t *testing.T) {
+	hub, server, repo := setup(t)
+	hub.Rate = 0.001
+	hub.Burst = 3
+	c := dial(t, server)
+	other := dial(t, server)
+
+	for i := 0; i < 3; i++ {
+		c.send(`{"type":"like","id":1}`)
+		c.receive()
+	}
+	c.send(`{"type":"like","id":1}`)
+	c.expect(`{"type":"error","error":"rate limited"}`)
+
+	other.send(`{"type":"like","id":1}`)
+	other.expect(`{"type":"voted","id":1,"likes":6,"dislikes":1}`)
+	if post, _ := repo.GetPost(1); post.Likes != 6 {
+		t.Fatalf("Expected rate limited vote not to count; Got: %d likes", post.Likes)
+	}
+}
+
+func TestHub_PingsAndClosesSilentConnections(t *testing.T) {
+	hub, server, _ := setup(t)
+	hub.PingInterval = 20 * time.Millisecond
+	hub.PongWait = 100 * time.Millisecond
+	c := dial(t, server)
+
+	if f := c.readFrame(); f.opcode != 0x9 {
+		t.Fatalf("Expected a ping; Got opcode %d", f.opcode)
+	}
+	for {
+		f := c.readFrame()
+		if f.opcode == 0x9 {
+			continue
+		}
+		if f.opcode != 0x8 || binary.BigEndian.Uint16(f.payload) != 1001 {
+			t.Fatalf("Expected close 1001 after pong wait; Got opcode %d: '%v'", f.opcode, f.payload)
+		}
+		return
+	}
+}
+
+func TestHub_EchoesCloseFrames(t *testing.T) {
+	_, server, _ := setup(t)
+	c := dial(t, server)
+
+	c.writeFrame(true, 0x8, []byte{0x03, 0xe8})
+
+	if f := c.readFrame(); f.opcode != 0x8 || binary.BigEndian.Uint16(f.payload) != 1000 {
+		t.Fatalf("Expected close 1000; Got opcode %d: '%v'", f.opcode, f.payload)
+	}
+}
+
+func TestHub_ClosesUnmaskedFramesAsProtocolErrors(t *testing.T) {
+	_, server, _ := setup(t)
+	c := dial(t, server)
+
+	c.conn.Write([]byte{0x81, 0x02, '{', '}'})
+
+	if f := c.readFrame(); f.opcode != 0x8 || binary.BigEndian.Uint16(f.payload) != 1002 {
+		t.Fatalf("Expected close 1002; Got opcode %d: '%v'", f.opcode, f.payload)
+	}
+}
+
+func TestHub_RejectsNonWebSocketRequests(t *testing.T) {
+	_, server, _ := setup(t)
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected 400; Got: %d", resp.StatusCode)
+	}
+}
+
+// racingRepo runs the next of during each time post id is read, to stand
+// in for changes landing while the reader subscribes
+type racingRepo struct {
+	*memory.Repository
+	id     int
+	during []func()
+}
+
+func (repo *racingRepo) GetPost(id int) (entities.Post, error) {
+	post, err := repo.Repository.GetPost(id)
+	if id == repo.id && len(repo.during) > 0 {
+		during := repo.during[0]
+		repo.during = repo.during[1:]
+		during()
+	}
+	return post, err
+}
+
+func setupRacing(t *testing.T, id int, during ...func(hub *live.Hub, repo *memory.Repository)) *client {
+	repo := &racingRepo{Repository: memory.NewRepository(examplePosts...), id: id}
+	hub := live.NewHub(repo, events.NewBus(nil))
+	for _, fn := range during {
+		fn := fn
+		repo.during = append(repo.during, func() { fn(hub, repo.Repository) })
+	}
+	server := httptest.NewServer(hub)
+	t.Cleanup(server.Close)
+	return dial(t, server)
+}
+
+// like likes post 1 and tells hub
+func like(hub *live.Hub, repo *memory.Repository) {
+	post, _ := repo.GetPost(1)
+	post.Likes++
+	repo.UpdatePost(1, post)
+	hub.Handle(events.PostVoted{Post: post, Liked: true})
+}
+
+func TestSubscribe_CatchesChangesMadeWhileReading(t *testing.T) {
+	// would deadlock if subscribe held the hub's lock while reading
+	c := setupRacing(t, 1, like)
+
+	c.send(`{"type":"subscribe","ids":[1]}`)
+
+	c.expect(`{"type":"counts","id":1,"likes":2,"dislikes":1}`)
+	c.expect(`{"type":"counts","id":1,"likes":3,"dislikes":1}`)
+}
+
+func TestSubscribe_CatchesDeletionsMadeWhileReading(t *testing.T) {
+	c := setupRacing(t, 2, func(hub *live.Hub, repo *memory.Repository) {
+		repo.DeletePost(2)
+		hub.Handle(events.PostDeleted{Post: examplePosts[1]})
+	})
+
+	c.send(`{"type":"subscribe","ids":[2]}`)
+
+	c.expect(`{"type":"counts","id":2,"likes":5,"dislikes":2}`)
+	c.expect(`{"type":"deleted","id":2}`)
+}
+
+func TestSubscribe_DoesNotResendOlderCountsThanABroadcast(t *testing.T) {
+	// the second read sees 3 likes, then a vote broadcasts 4 before
+	// subscribe gets to send what it read
+	c := setupRacing(t, 1, like, like)
+
+	c.send(`{"type":"subscribe","ids":[1]}`)
+
+	c.expect(`{"type":"counts","id":1,"likes":2,"dislikes":1}`)
+	c.expect(`{"type":"counts","id":1,"likes":4,"dislikes":1}`)
+	c.send(`{"type":"shout"}`)
+	c.expect(`{"type":"error","error":"unknown message type"}`)
+}
+
+func TestSubscribe_LimitsIDs(t *testing.T) {
+	hub, server, _ := setup(t)
+	hub.MaxSubscribeIDs = 2
+	hub.MaxSubscriptions = 1
+	c := dial(t, server)
+
+	c.send(`{"type":"subscribe","ids":[1,2,3]}`)
+	c.expect(`{"type":"error","error":"too many ids"}`)
+
+	c.send(`{"type":"subscribe","ids":[1,2]}`)
+	c.expect(`{"type":"counts","id":1,"likes":2,"dislikes":1}`)
+	c.expect(`{"type":"error","id":2,"error":"too many subscriptions"}`)
+
+	// subscribing again to a post doesn't take another slot, and
+	// unsubscribing frees one
+	c.send(`{"type":"subscribe","ids":[1]}`)
+	c.expect(`{"type":"counts","id":1,"likes":2,"dislikes":1}`)
+	c.send(`{"type":"unsubscribe","ids":[1]}`)
+	c.send(`{"type":"subscribe","ids":[2]}`)
+	c.expect(`{"type":"counts","id":2,"likes":5,"dislikes":2}`)
+}
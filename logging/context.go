@@ -0,0 +1,36 @@
+// Package logging writes structured logs with log/slog: an access log for
+// HTTP requests, and a repository decorator that logs the errors the use
+// cases turn into ErrInternal.
+package logging
+
+import (
+	"context"
+	"log/slog"
+)
+
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	requestIDKey
+)
+
+// WithLogger returns ctx carrying logger, for FromContext to find
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// FromContext returns the logger Middleware attached to a request, which
+// adds its request ID to every entry, or slog.Default outside a request
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// RequestID returns the ID Middleware gave the request ctx belongs to
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
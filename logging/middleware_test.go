@@ -0,0 +1,118 @@
+package logging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/steve-kaufman/postsService/logging"
+)
+
+// newLogger logs JSON at debug level without the times and durations that
+// change from run to run
+func newLogger() (*slog.Logger, *bytes.Buffer) {
+	var out bytes.Buffer
+	handler := slog.NewJSONHandler(&out, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.TimeKey || attr.Key == "duration" {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	})
+	return slog.New(handler), &out
+}
+
+func entries(t *testing.T, out *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var logged []map[string]interface{}
+	decoder := json.NewDecoder(out)
+	for decoder.More() {
+		var entry map[string]interface{}
+		if err := decoder.Decode(&entry); err != nil {
+			t.Fatalf("Expected JSON log entries; Got: '%v'", err)
+		}
+		logged = append(logged, entry)
+	}
+	return logged
+}
+
+func TestMiddleware_LogsRequestsWithTheirID(t *testing.T) {
+	logger, out := newLogger()
+	handler := logging.Middleware(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logging.FromContext(r.Context()).Info("handling", "request_id_seen", logging.RequestID(r.Context()))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/posts", nil)
+	r.Header.Set("X-Request-ID", "abc-123")
+	r.RemoteAddr = "192.0.2.1:1234"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if id := w.Header().Get("X-Request-ID"); id != "abc-123" {
+		t.Fatalf("Expected the request ID to be echoed; Got: '%s'", id)
+	}
+	expected := []map[string]interface{}{
+		{"level": "INFO", "msg": "handling", "request_id": "abc-123", "request_id_seen": "abc-123"},
+		{
+			"level": "INFO", "msg": "request", "request_id": "abc-123",
+			"method": "POST", "path": "/posts", "status": 201.0, "bytes": 5.0, "remote": "192.0.2.1:1234",
+		},
+	}
+	if diff := cmp.Diff(expected, entries(t, out)); diff != "" {
+		t.Fatalf("Expected log entries: \n%s", diff)
+	}
+}
+
+func TestMiddleware_ReplacesMissingOrUnsafeIDs(t *testing.T) {
+	for _, sent := range []string{"", "has spaces", "new\nline", string(make([]byte, 65))} {
+		logger, _ := newLogger()
+		handler := logging.Middleware(logger, http.NotFoundHandler())
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-Request-ID", sent)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if id := w.Header().Get("X-Request-ID"); len(id) != 16 || id == sent {
+			t.Fatalf("Expected a generated ID in place of '%q'; Got: '%s'", sent, id)
+		}
+	}
+}
+
+func TestMiddleware_LogsServerErrorsAtErrorLevel(t *testing.T) {
+	logger, out := newLogger()
+	handler := logging.Middleware(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/posts/1", nil))
+
+	logged := entries(t, out)
+	if len(logged) != 1 || logged[0]["level"] != "ERROR" || logged[0]["status"] != 500.0 {
+		t.Fatalf("Expected one error entry; Got: '%v'", logged)
+	}
+}
+
+func TestMiddleware_PassesFlushesThrough(t *testing.T) {
+	logger, _ := newLogger()
+	flushed := false
+	handler := logging.Middleware(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		flushed = true
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/events", nil))
+
+	if !flushed || !w.Flushed {
+		t.Fatal("Expected the flush to reach the underlying writer")
+	}
+}
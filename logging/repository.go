@@ -0,0 +1,139 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/feeds"
+	"github.com/steve-kaufman/postsService/interfaces"
+	"github.com/steve-kaufman/postsService/ranking"
+	"github.com/steve-kaufman/postsService/useCases"
+)
+
+// Backend is the repository a Repository logs calls to
+type Backend interface {
+	interfaces.Repository
+	interfaces.TxRunner
+}
+
+// Repository logs each failed call to its backend with the method, the
+// post ID when there is one, and the backend's own error. ErrNotFound is
+// logged at debug level, as are successful calls; other errors at error
+// level.
+type Repository struct {
+	txRepository
+	runner interfaces.TxRunner
+}
+
+type txRepository struct {
+	logger  *slog.Logger
+	backend interfaces.Repository
+}
+
+func NewRepository(backend Backend, logger *slog.Logger) *Repository {
+	return &Repository{txRepository{logger, backend}, backend}
+}
+
+// For returns the repository logging with the request ID of ctx and the
+// name of the use case about to run
+func (repo *Repository) For(ctx context.Context, useCase string) *Repository {
+	logger := repo.logger
+	if id := RequestID(ctx); id != "" {
+		logger = logger.With("request_id", id)
+	}
+	return &Repository{txRepository{logger.With("use_case", useCase), repo.backend}, repo.runner}
+}
+
+func (repo *Repository) WithinTx(fn func(repo interfaces.Repository) error) error {
+	start := time.Now()
+	err := repo.runner.WithinTx(func(inner interfaces.Repository) error {
+		return fn(&txRepository{repo.logger, inner})
+	})
+	repo.log("WithinTx", start, err)
+	return err
+}
+
+func (repo *Repository) GetSortedPosts(by ranking.Sort) ([]entities.Post, error) {
+	start := time.Now()
+	posts, err := interfaces.SortedPosts(repo.backend, repo.backend, by)
+	repo.log("GetSortedPosts", start, err, slog.String("sort", string(by)))
+	return posts, err
+}
+
+func (repo *Repository) GetPostBySlug(slug string) (entities.Post, error) {
+	start := time.Now()
+	post, err := interfaces.PostBySlug(repo.backend, slug)
+	repo.log("GetPostBySlug", start, err, slog.String("slug", slug))
+	return post, err
+}
+
+func (repo *Repository) GetChangesSince(seq int64, limit int) ([]entities.Change, error) {
+	start := time.Now()
+	changes, err := interfaces.ChangesSince(repo.backend, seq, limit)
+	repo.log("GetChangesSince", start, err, slog.Int64("seq", seq))
+	return changes, err
+}
+
+func (repo *Repository) RecentPosts(limit int) ([]feeds.Entry, error) {
+	start := time.Now()
+	entries, err := interfaces.RecentPosts(repo.backend, limit)
+	repo.log("RecentPosts", start, err)
+	return entries, err
+}
+
+func (repo *txRepository) GetPosts() ([]entities.Post, error) {
+	start := time.Now()
+	posts, err := repo.backend.GetPosts()
+	repo.log("GetPosts", start, err)
+	return posts, err
+}
+
+func (repo *txRepository) GetPost(id int) (entities.Post, error) {
+	start := time.Now()
+	post, err := repo.backend.GetPost(id)
+	repo.log("GetPost", start, err, slog.Int("post_id", id))
+	return post, err
+}
+
+func (repo *txRepository) SavePost(post entities.Post) (entities.Post, error) {
+	start := time.Now()
+	saved, err := repo.backend.SavePost(post)
+	repo.log("SavePost", start, err)
+	return saved, err
+}
+
+func (repo *txRepository) DeletePost(id int) error {
+	start := time.Now()
+	err := repo.backend.DeletePost(id)
+	repo.log("DeletePost", start, err, slog.Int("post_id", id))
+	return err
+}
+
+func (repo *txRepository) UpdatePost(id int, data entities.Post) error {
+	start := time.Now()
+	err := repo.backend.UpdatePost(id, data)
+	repo.log("UpdatePost", start, err, slog.Int("post_id", id))
+	return err
+}
+
+func (repo *txRepository) log(method string, start time.Time, err error, attrs ...slog.Attr) {
+	level := slog.LevelDebug
+	message := "repository call"
+	if err != nil && !errors.Is(err, useCases.ErrNotFound) {
+		level = slog.LevelError
+		message = "repository call failed"
+	}
+	ctx := context.Background()
+	if !repo.logger.Enabled(ctx, level) {
+		return
+	}
+	attrs = append([]slog.Attr{slog.String("method", method)}, attrs...)
+	attrs = append(attrs, slog.Duration("duration", time.Since(start)))
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	repo.logger.LogAttrs(ctx, level, message, attrs...)
+}
@@ -0,0 +1,82 @@
+package logging_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/steve-kaufman/postsService/db"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/events"
+	"github.com/steve-kaufman/postsService/logging"
+	"github.com/steve-kaufman/postsService/memory"
+	"github.com/steve-kaufman/postsService/render"
+	"github.com/steve-kaufman/postsService/repotest"
+	"github.com/steve-kaufman/postsService/useCases"
+)
+
+func TestRepository_LogsTheErrorBehindErrInternal(t *testing.T) {
+	logger, out := newLogger()
+	repo := logging.NewRepository(new(db.BadRepository), logger)
+	var ctx context.Context
+
+	handler := logging.Middleware(slog.New(slog.NewTextHandler(io.Discard, nil)),
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx = r.Context()
+		}))
+	r := httptest.NewRequest(http.MethodGet, "/posts/7", nil)
+	r.Header.Set("X-Request-ID", "req-1")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	_, err := useCases.DeletePost(repo.For(ctx, "DeletePost"), new(events.Recorder), 7)
+	if !errors.Is(err, useCases.ErrInternal) {
+		t.Fatalf("Expected ErrInternal; Got: '%v'", err)
+	}
+
+	expected := []map[string]interface{}{{
+		"level": "ERROR", "msg": "repository call failed",
+		"request_id": "req-1", "use_case": "DeletePost",
+		"method": "WithinTx", "error": db.ErrBad.Error(),
+	}}
+	if diff := cmp.Diff(expected, entries(t, out)); diff != "" {
+		t.Fatalf("Expected the cause to be logged: \n%s", diff)
+	}
+}
+
+func TestRepository_LogsCallsInsideTransactions(t *testing.T) {
+	logger, out := newLogger()
+	repo := logging.NewRepository(memory.NewRepository(entities.Post{ID: 1, Title: "Post 1"}), logger)
+
+	useCases.LikePost(repo.For(context.Background(), "LikePost"), new(events.Recorder), render.Skip, 1)
+	useCases.GetOnePost(repo.For(context.Background(), "GetOnePost"), render.Skip, 2)
+
+	expected := []map[string]interface{}{
+		{"level": "DEBUG", "msg": "repository call", "use_case": "LikePost", "method": "GetPost", "post_id": 1.0},
+		{"level": "DEBUG", "msg": "repository call", "use_case": "LikePost", "method": "UpdatePost", "post_id": 1.0},
+		{"level": "DEBUG", "msg": "repository call", "use_case": "LikePost", "method": "WithinTx"},
+		{
+			"level": "DEBUG", "msg": "repository call", "use_case": "GetOnePost",
+			"method": "GetPost", "post_id": 2.0, "error": useCases.ErrNotFound.Error(),
+		},
+	}
+	if diff := cmp.Diff(expected, entries(t, out)); diff != "" {
+		t.Fatalf("Expected log entries: \n%s", diff)
+	}
+}
+
+func TestRepository_Conformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repository {
+		return logging.NewRepository(memory.NewRepository(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	})
+}
+
+func TestRepository_Forwarding(t *testing.T) {
+	repotest.RunForwarding(t, func(t *testing.T, backend repotest.Repository) repotest.Repository {
+		return logging.NewRepository(backend, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	})
+}
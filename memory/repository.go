@@ -0,0 +1,143 @@
+// Package memory keeps posts in process memory, for development, demos
+// and tests. postsd uses it with -storage=memory.
+package memory
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/interfaces"
+	"github.com/steve-kaufman/postsService/useCases"
+)
+
+// Repository is an in-memory implementation of every interface in the
+// interfaces package. It is safe for concurrent use.
+type Repository struct {
+	mu    sync.RWMutex
+	store *store
+}
+
+// NewRepository returns a Repository seeded with posts. Seeded posts keep
+// their IDs unless they have none, in which case one is assigned.
+func NewRepository(posts ...entities.Post) *Repository {
+	s := newStore()
+	for _, post := range posts {
+		s.insert(post)
+	}
+	return &Repository{store: s}
+}
+
+func (repo *Repository) GetPosts() ([]entities.Post, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	return repo.store.GetPosts()
+}
+
+func (repo *Repository) GetPost(id int) (entities.Post, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	return repo.store.GetPost(id)
+}
+
+func (repo *Repository) SavePost(post entities.Post) (entities.Post, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	return repo.store.SavePost(post)
+}
+
+func (repo *Repository) DeletePost(id int) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	return repo.store.DeletePost(id)
+}
+
+func (repo *Repository) UpdatePost(id int, data entities.Post) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	return repo.store.UpdatePost(id, data)
+}
+
+// WithinTx holds the write lock for the duration of fn and runs it against
+// a copy of the posts, which replaces the original only if fn succeeds.
+func (repo *Repository) WithinTx(fn func(repo interfaces.Repository) error) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	tx := repo.store.clone()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	repo.store = tx
+	return nil
+}
+
+// store holds the posts without any locking of its own; Repository guards it
+type store struct {
+	posts  map[int]entities.Post
+	nextID int
+}
+
+func newStore() *store {
+	return &store{posts: map[int]entities.Post{}, nextID: 1}
+}
+
+func (s *store) clone() *store {
+	c := &store{posts: make(map[int]entities.Post, len(s.posts)), nextID: s.nextID}
+	for id, post := range s.posts {
+		c.posts[id] = post
+	}
+	return c
+}
+
+func (s *store) insert(post entities.Post) entities.Post {
+	if post.ID == 0 {
+		post.ID = s.nextID
+	}
+	if post.ID >= s.nextID {
+		s.nextID = post.ID + 1
+	}
+	s.posts[post.ID] = post
+	return post
+}
+
+func (s *store) GetPosts() ([]entities.Post, error) {
+	posts := make([]entities.Post, 0, len(s.posts))
+	for _, post := range s.posts {
+		posts = append(posts, post)
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].ID < posts[j].ID
+	})
+	return posts, nil
+}
+
+func (s *store) GetPost(id int) (entities.Post, error) {
+	post, ok := s.posts[id]
+	if !ok {
+		return entities.Post{}, useCases.ErrNotFound
+	}
+	return post, nil
+}
+
+func (s *store) SavePost(post entities.Post) (entities.Post, error) {
+	post.ID = 0
+	return s.insert(post), nil
+}
+
+func (s *store) DeletePost(id int) error {
+	if _, ok := s.posts[id]; !ok {
+		return useCases.ErrNotFound
+	}
+	delete(s.posts, id)
+	return nil
+}
+
+func (s *store) UpdatePost(id int, data entities.Post) error {
+	if _, ok := s.posts[id]; !ok {
+		return useCases.ErrNotFound
+	}
+	data.ID = id
+	s.posts[id] = data
+	return nil
+}
@@ -0,0 +1,148 @@
+package memory_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/interfaces"
+	"github.com/steve-kaufman/postsService/memory"
+	"github.com/steve-kaufman/postsService/repotest"
+	"github.com/steve-kaufman/postsService/useCases"
+)
+
+var examplePosts = []entities.Post{
+	{
+		ID:       1,
+		Title:    "Post 1",
+		Content:  "Content of Post 1",
+		Likes:    2,
+		Dislikes: 1,
+	},
+	{
+		ID:       2,
+		Title:    "Post 2",
+		Content:  "Content of Post 2",
+		Likes:    5,
+		Dislikes: 2,
+	},
+	{
+		ID:       3,
+		Title:    "Post 3",
+		Content:  "Content of Post 3",
+		Likes:    0,
+		Dislikes: 10,
+	},
+}
+
+func TestGetPosts_ReturnsSeededPostsInIDOrder(t *testing.T) {
+	repo := memory.NewRepository(examplePosts[2], examplePosts[0], examplePosts[1])
+
+	posts, err := repo.GetPosts()
+
+	if err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	if diff := cmp.Diff(examplePosts, posts); diff != "" {
+		t.Fatalf("Expected example posts; Got: \n%s", diff)
+	}
+}
+
+func TestSavePost_AssignsNextID(t *testing.T) {
+	repo := memory.NewRepository(examplePosts...)
+
+	saved, err := repo.SavePost(entities.Post{ID: 1, Title: "Foo", Content: "Bar"})
+
+	if err != nil || saved.ID != 4 {
+		t.Fatalf("Expected post 4 back; Got: '%v', '%v'", saved, err)
+	}
+	post, err := repo.GetPost(4)
+	if err != nil {
+		t.Fatalf("Expected saved post to have ID 4; Got: '%v'", err)
+	}
+	if diff := cmp.Diff(entities.Post{ID: 4, Title: "Foo", Content: "Bar"}, post); diff != "" {
+		t.Fatalf("Expected saved post; Got: \n%s", diff)
+	}
+}
+
+func TestDeletePost_RemovesPost(t *testing.T) {
+	repo := memory.NewRepository(examplePosts...)
+
+	if err := repo.DeletePost(2); err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+
+	if _, err := repo.GetPost(2); err != useCases.ErrNotFound {
+		t.Fatalf("Expected ErrNotFound; Got: '%v'", err)
+	}
+	if err := repo.DeletePost(2); err != useCases.ErrNotFound {
+		t.Fatalf("Expected second delete to return ErrNotFound; Got: '%v'", err)
+	}
+}
+
+func TestUpdatePost_ReplacesOnlyThatPost(t *testing.T) {
+	repo := memory.NewRepository(examplePosts...)
+
+	err := repo.UpdatePost(2, entities.Post{Title: "Foo", Content: "Bar"})
+
+	if err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	posts, _ := repo.GetPosts()
+	expectedPosts := []entities.Post{examplePosts[0], {ID: 2, Title: "Foo", Content: "Bar"}, examplePosts[2]}
+	if diff := cmp.Diff(expectedPosts, posts); diff != "" {
+		t.Fatalf("Expected only post 2 to change; Got: \n%s", diff)
+	}
+}
+
+func TestWithinTx_DiscardsChanges_WhenFnFails(t *testing.T) {
+	repo := memory.NewRepository(examplePosts...)
+
+	errFn := errors.New("fn failed")
+	err := repo.WithinTx(func(tx interfaces.Repository) error {
+		tx.DeletePost(1)
+		tx.SavePost(entities.Post{Title: "Foo"})
+		return errFn
+	})
+
+	if err != errFn {
+		t.Fatalf("Expected error from fn; Got: '%v'", err)
+	}
+	posts, _ := repo.GetPosts()
+	if diff := cmp.Diff(examplePosts, posts); diff != "" {
+		t.Fatalf("Expected posts to be unchanged; Got: \n%s", diff)
+	}
+}
+
+func TestSavePost_IsSafeForConcurrentUse(t *testing.T) {
+	repo := memory.NewRepository()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			repo.SavePost(entities.Post{Title: "Foo"})
+			repo.GetPosts()
+		}()
+	}
+	wg.Wait()
+
+	posts, _ := repo.GetPosts()
+	if len(posts) != 100 {
+		t.Fatalf("Expected 100 posts; Got: %d", len(posts))
+	}
+	for i, post := range posts {
+		if post.ID != i+1 {
+			t.Fatalf("Expected IDs 1 through 100; Got: %d at index %d", post.ID, i)
+		}
+	}
+}
+
+func TestRepository_Conformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repository {
+		return memory.NewRepository()
+	})
+}
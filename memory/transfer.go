@@ -0,0 +1,35 @@
+package memory
+
+import (
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/transfer"
+)
+
+// EachPost calls fn with every post in ID order, and stops at the first
+// error fn returns. fn sees the posts as they were when EachPost began.
+func (repo *Repository) EachPost(fn func(post entities.Post) error) error {
+	posts, err := repo.GetPosts()
+	if err != nil {
+		return err
+	}
+	for _, post := range posts {
+		if err := fn(post); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportPost stores post with its likes and dislikes, and under post.ID if
+// keepID is set
+func (repo *Repository) ImportPost(post entities.Post, keepID bool) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if !keepID {
+		post.ID = 0
+	} else if _, taken := repo.store.posts[post.ID]; taken {
+		return transfer.ErrIDTaken
+	}
+	repo.store.insert(post)
+	return nil
+}
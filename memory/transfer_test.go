@@ -0,0 +1,37 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/memory"
+	"github.com/steve-kaufman/postsService/transfer"
+)
+
+func TestImportPost_KeepsVotesAndOptionallyIDs(t *testing.T) {
+	repo := memory.NewRepository()
+
+	if err := repo.ImportPost(entities.Post{ID: 9, Title: "Kept", Likes: 4, Dislikes: 1}, true); err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	if err := repo.ImportPost(entities.Post{ID: 9, Title: "Taken"}, true); err != transfer.ErrIDTaken {
+		t.Fatalf("Expected ErrIDTaken; Got: '%v'", err)
+	}
+	if err := repo.ImportPost(entities.Post{ID: 9, Title: "Kept"}, false); err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+
+	var posts []entities.Post
+	repo.EachPost(func(post entities.Post) error {
+		posts = append(posts, post)
+		return nil
+	})
+	expected := []entities.Post{
+		{ID: 9, Title: "Kept", Likes: 4, Dislikes: 1},
+		{ID: 10, Title: "Kept"},
+	}
+	if diff := cmp.Diff(expected, posts); diff != "" {
+		t.Fatalf("Expected imported posts in ID order: \n%s", diff)
+	}
+}
@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/ranking"
+	"github.com/steve-kaufman/postsService/trending"
+	"github.com/steve-kaufman/postsService/useCases"
+)
+
+// Recorder counts the calls, errors and latencies of the use cases and
+// repository methods it wraps, and serves them at /metrics
+type Recorder struct {
+	*Registry
+	Now func() time.Time
+
+	useCaseCalls    *Counter
+	useCaseErrors   *Counter
+	useCaseDuration *Histogram
+	repoCalls       *Counter
+	repoErrors      *Counter
+	repoDuration    *Histogram
+}
+
+func NewRecorder() *Recorder {
+	registry := NewRegistry()
+	return &Recorder{
+		Registry: registry,
+		Now:      time.Now,
+
+		useCaseCalls: registry.Counter("posts_use_case_calls_total",
+			"Use case calls.", "use_case"),
+		useCaseErrors: registry.Counter("posts_use_case_errors_total",
+			"Use case calls that returned an error, by error.", "use_case", "error"),
+		useCaseDuration: registry.Histogram("posts_use_case_duration_seconds",
+			"How long use cases took.", DefaultBuckets, "use_case"),
+		repoCalls: registry.Counter("posts_repository_calls_total",
+			"Repository method calls.", "method"),
+		repoErrors: registry.Counter("posts_repository_errors_total",
+			"Repository method calls that returned an error, by error.", "method", "error"),
+		repoDuration: registry.Histogram("posts_repository_duration_seconds",
+			"How long repository methods took.", DefaultBuckets, "method"),
+	}
+}
+
+// sentinels are the errors counted by name; any other error is "other"
+var sentinels = []struct {
+	err   error
+	label string
+}{
+	{useCases.ErrNotFound, "not_found"},
+	{useCases.ErrInternal, "internal"},
+	{useCases.ErrCantChangeLikes, "cant_change_likes"},
+	{entities.ErrNeedsTitle, "needs_title"},
+	{entities.ErrTooLong, "too_long"},
+	{ranking.ErrUnknownSort, "unknown_sort"},
+	{trending.ErrUnknownWindow, "unknown_window"},
+}
+
+func errorLabel(err error) string {
+	for _, sentinel := range sentinels {
+		if errors.Is(err, sentinel.err) {
+			return sentinel.label
+		}
+	}
+	return "other"
+}
+
+// observeUseCase is deferred with the start time and the named error the
+// use case returns
+func (recorder *Recorder) observeUseCase(name string, start time.Time, err *error) {
+	recorder.useCaseCalls.Inc(name)
+	recorder.useCaseDuration.Observe(recorder.Now().Sub(start).Seconds(), name)
+	if *err != nil {
+		recorder.useCaseErrors.Inc(name, errorLabel(*err))
+	}
+}
+
+func (recorder *Recorder) observeRepository(method string, start time.Time, err *error) {
+	recorder.repoCalls.Inc(method)
+	recorder.repoDuration.Observe(recorder.Now().Sub(start).Seconds(), method)
+	if *err != nil {
+		recorder.repoErrors.Inc(method, errorLabel(*err))
+	}
+}
@@ -0,0 +1,115 @@
+package metrics_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steve-kaufman/postsService/db"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/events"
+	"github.com/steve-kaufman/postsService/memory"
+	"github.com/steve-kaufman/postsService/metrics"
+	"github.com/steve-kaufman/postsService/ranking"
+	"github.com/steve-kaufman/postsService/render"
+	"github.com/steve-kaufman/postsService/repotest"
+)
+
+// newRecorder returns a Recorder whose clock moves 3ms every time it is read
+func newRecorder() *metrics.Recorder {
+	recorder := metrics.NewRecorder()
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	recorder.Now = func() time.Time {
+		now = now.Add(3 * time.Millisecond)
+		return now
+	}
+	return recorder
+}
+
+func expectLines(t *testing.T, recorder *metrics.Recorder, lines ...string) {
+	t.Helper()
+	var out bytes.Buffer
+	recorder.WriteTo(&out)
+	exposition := out.String()
+	for _, line := range lines {
+		if !strings.Contains(exposition, line+"\n") {
+			t.Fatalf("Expected line '%s'; Got: \n%s", line, exposition)
+		}
+	}
+}
+
+func TestRecorder_CountsUseCasesAndErrorsBySentinel(t *testing.T) {
+	recorder := newRecorder()
+	u := recorder.UseCases()
+	repo := memory.NewRepository(entities.Post{ID: 1, Title: "Post 1"})
+
+	u.GetOnePost(repo, render.Skip, 1)
+	u.GetOnePost(repo, render.Skip, 2)
+	u.GetOnePost(new(db.BadRepository), render.Skip, 1)
+	u.CreatePost(repo, new(events.Recorder), render.Skip, entities.Post{})
+	u.UpdatePost(repo, new(events.Recorder), render.Skip, 1, entities.Post{Likes: 1})
+	u.GetAllPosts(repo, render.Skip, "best")
+	if post, err := u.LikePost(repo, new(events.Recorder), render.Skip, 1); err != nil || post.Likes != 1 {
+		t.Fatalf("Expected the use case's result; Got: '%v', '%v'", post, err)
+	}
+
+	expectLines(t, recorder,
+		`posts_use_case_calls_total{use_case="GetOnePost"} 3`,
+		`posts_use_case_errors_total{use_case="GetOnePost",error="not_found"} 1`,
+		`posts_use_case_errors_total{use_case="GetOnePost",error="internal"} 1`,
+		`posts_use_case_errors_total{use_case="CreatePost",error="needs_title"} 1`,
+		`posts_use_case_errors_total{use_case="UpdatePost",error="cant_change_likes"} 1`,
+		`posts_use_case_errors_total{use_case="GetAllPosts",error="unknown_sort"} 1`,
+		`posts_use_case_calls_total{use_case="LikePost"} 1`,
+		`posts_use_case_duration_seconds_bucket{use_case="LikePost",le="0.0025"} 0`,
+		`posts_use_case_duration_seconds_bucket{use_case="LikePost",le="0.005"} 1`,
+		`posts_use_case_duration_seconds_sum{use_case="LikePost"} 0.003`,
+	)
+}
+
+func TestRecorder_CountsRepositoryCallsInsideTransactions(t *testing.T) {
+	recorder := newRecorder()
+	repo := recorder.Repository(memory.NewRepository(entities.Post{ID: 1, Title: "Post 1"}))
+
+	if _, err := recorder.UseCases().DislikePost(repo, new(events.Recorder), render.Skip, 1); err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	repo.GetPost(7)
+	repo.SavePost(entities.Post{Title: "Post 2"})
+	repo.DeletePost(2)
+	posts, _ := repo.GetSortedPosts(ranking.SortNew)
+	if len(posts) != 1 {
+		t.Fatalf("Expected the backend's posts; Got: '%v'", posts)
+	}
+
+	expectLines(t, recorder,
+		`posts_repository_calls_total{method="WithinTx"} 1`,
+		`posts_repository_calls_total{method="GetPost"} 2`,
+		`posts_repository_calls_total{method="UpdatePost"} 1`,
+		`posts_repository_calls_total{method="SavePost"} 1`,
+		`posts_repository_calls_total{method="DeletePost"} 1`,
+		`posts_repository_calls_total{method="GetSortedPosts"} 1`,
+		`posts_repository_errors_total{method="GetPost",error="not_found"} 1`,
+		`posts_repository_duration_seconds_count{method="WithinTx"} 1`,
+	)
+}
+
+func TestRecorder_CountsUnknownErrorsAsOther(t *testing.T) {
+	recorder := newRecorder()
+	recorder.Repository(new(db.BadRepository)).GetPosts()
+
+	expectLines(t, recorder, `posts_repository_errors_total{method="GetPosts",error="other"} 1`)
+}
+
+func TestRepository_Conformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repository {
+		return metrics.NewRecorder().Repository(memory.NewRepository())
+	})
+}
+
+func TestRepository_Forwarding(t *testing.T) {
+	repotest.RunForwarding(t, func(t *testing.T, backend repotest.Repository) repotest.Repository {
+		return metrics.NewRecorder().Repository(backend)
+	})
+}
@@ -0,0 +1,223 @@
+// Package metrics counts calls, errors and latencies and serves them in
+// the Prometheus text exposition format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of a latency histogram
+var DefaultBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5}
+
+// Registry holds metric families and serves them at /metrics
+type Registry struct {
+	mu       sync.Mutex
+	families []*family
+}
+
+func NewRegistry() *Registry {
+	return new(Registry)
+}
+
+type family struct {
+	name    string
+	help    string
+	kind    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// series is one combination of label values. A counter only uses sum.
+type series struct {
+	values []string
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+type Counter struct {
+	family *family
+}
+
+type Histogram struct {
+	family *family
+}
+
+// Counter registers a counter whose series are told apart by labels
+func (registry *Registry) Counter(name, help string, labels ...string) *Counter {
+	return &Counter{registry.register(name, help, "counter", labels, nil)}
+}
+
+// Histogram registers a histogram with the given bucket upper bounds
+func (registry *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	sorted := append([]float64{}, buckets...)
+	sort.Float64s(sorted)
+	return &Histogram{registry.register(name, help, "histogram", labels, sorted)}
+}
+
+func (registry *Registry) register(name, help, kind string, labels []string, buckets []float64) *family {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	for _, existing := range registry.families {
+		if existing.name == name {
+			panic("metrics: " + name + " registered twice")
+		}
+	}
+	f := &family{name: name, help: help, kind: kind, labels: labels, buckets: buckets, series: map[string]*series{}}
+	registry.families = append(registry.families, f)
+	return f
+}
+
+// Inc adds one to the series with the given label values
+func (counter *Counter) Inc(values ...string) {
+	counter.Add(1, values...)
+}
+
+func (counter *Counter) Add(delta float64, values ...string) {
+	f := counter.family
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.get(values).sum += delta
+}
+
+func (histogram *Histogram) Observe(value float64, values ...string) {
+	f := histogram.family
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s := f.get(values)
+	if s.counts == nil {
+		s.counts = make([]uint64, len(f.buckets))
+	}
+	if i := sort.SearchFloat64s(f.buckets, value); i < len(f.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += value
+}
+
+func (f *family) get(values []string) *series {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{values: append([]string{}, values...)}
+		f.series[key] = s
+	}
+	return s
+}
+
+// WriteTo writes every family in the text exposition format, series sorted
+// by their label values
+func (registry *Registry) WriteTo(w io.Writer) (int64, error) {
+	registry.mu.Lock()
+	families := append([]*family{}, registry.families...)
+	registry.mu.Unlock()
+
+	counter := &countingWriter{w: w}
+	buffered := bufio.NewWriter(counter)
+	for _, f := range families {
+		f.write(buffered)
+	}
+	err := buffered.Flush()
+	return counter.n, err
+}
+
+func (registry *Registry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	registry.WriteTo(w)
+}
+
+func (f *family) write(w *bufio.Writer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.kind)
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := f.series[key]
+		if f.kind == "counter" {
+			fmt.Fprintf(w, "%s%s %s\n", f.name, f.labelPairs(s.values, ""), formatFloat(s.sum))
+			continue
+		}
+		var cumulative uint64
+		for i, bound := range f.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, f.labelPairs(s.values, formatFloat(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, f.labelPairs(s.values, "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", f.name, f.labelPairs(s.values, ""), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", f.name, f.labelPairs(s.values, ""), s.count)
+	}
+}
+
+// labelPairs formats values as {name="value",...}, adding le when given
+func (f *family) labelPairs(values []string, le string) string {
+	var pairs []string
+	for i, name := range f.labels {
+		pairs = append(pairs, name+`="`+escapeLabel(values[i])+`"`)
+	}
+	if le != "" {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,96 @@
+package metrics_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/steve-kaufman/postsService/metrics"
+)
+
+func TestRegistry_WritesTextExposition(t *testing.T) {
+	registry := metrics.NewRegistry()
+	requests := registry.Counter("requests_total", "Requests\nserved.", "path")
+	latency := registry.Histogram("latency_seconds", `Latency with a \ in it.`, []float64{1, 0.5}, "path")
+	plain := registry.Counter("plain_total", "No labels.")
+
+	requests.Inc("/b")
+	requests.Add(2, "/a")
+	requests.Inc(`/"quoted"`)
+	latency.Observe(0.25, "/a")
+	latency.Observe(0.5, "/a")
+	latency.Observe(3, "/a")
+	plain.Inc()
+
+	var out bytes.Buffer
+	n, err := registry.WriteTo(&out)
+	if err != nil || n != int64(out.Len()) {
+		t.Fatalf("Expected %d bytes written; Got: %d, '%v'", out.Len(), n, err)
+	}
+
+	expected := `# HELP requests_total Requests\nserved.
+# TYPE requests_total counter
+requests_total{path="/\"quoted\""} 1
+requests_total{path="/a"} 2
+requests_total{path="/b"} 1
+# HELP latency_seconds Latency with a \\ in it.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{path="/a",le="0.5"} 2
+latency_seconds_bucket{path="/a",le="1"} 2
+latency_seconds_bucket{path="/a",le="+Inf"} 3
+latency_seconds_sum{path="/a"} 3.75
+latency_seconds_count{path="/a"} 3
+# HELP plain_total No labels.
+# TYPE plain_total counter
+plain_total 1
+`
+	if diff := cmp.Diff(expected, out.String()); diff != "" {
+		t.Fatalf("Expected exposition: \n%s", diff)
+	}
+}
+
+func TestRegistry_PanicsOnMisuse(t *testing.T) {
+	registry := metrics.NewRegistry()
+	counter := registry.Counter("things_total", "Things.", "kind")
+
+	for name, misuse := range map[string]func(){
+		"duplicate name":  func() { registry.Counter("things_total", "Again.") },
+		"missing label":   func() { counter.Inc() },
+		"too many labels": func() { counter.Inc("a", "b") },
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("Expected a panic")
+				}
+			}()
+			misuse()
+		})
+	}
+}
+
+func TestRegistry_ServesMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	registry.Counter("things_total", "Things.").Inc()
+
+	w := httptest.NewRecorder()
+	registry.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200; Got: %d", w.Code)
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Fatalf("Expected the text format's content type; Got: '%s'", contentType)
+	}
+	if !bytes.Contains(w.Body.Bytes(), []byte("things_total 1\n")) {
+		t.Fatalf("Expected the counter; Got: '%s'", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	registry.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/metrics", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("Expected 405; Got: %d", w.Code)
+	}
+}
@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/feeds"
+	"github.com/steve-kaufman/postsService/interfaces"
+	"github.com/steve-kaufman/postsService/ranking"
+)
+
+// Backend is the repository a Repository measures
+type Backend interface {
+	interfaces.Repository
+	interfaces.TxRunner
+}
+
+// Repository records every call to its backend, including those made
+// inside WithinTx, which is itself timed as a whole
+type Repository struct {
+	txRepository
+	runner interfaces.TxRunner
+}
+
+// txRepository records calls to the repository a transaction runs against
+type txRepository struct {
+	recorder *Recorder
+	backend  interfaces.Repository
+}
+
+func (recorder *Recorder) Repository(backend Backend) *Repository {
+	return &Repository{txRepository{recorder, backend}, backend}
+}
+
+func (repo *Repository) WithinTx(fn func(repo interfaces.Repository) error) (err error) {
+	defer repo.recorder.observeRepository("WithinTx", repo.recorder.Now(), &err)
+	return repo.runner.WithinTx(func(inner interfaces.Repository) error {
+		return fn(&txRepository{repo.recorder, inner})
+	})
+}
+
+func (repo *Repository) GetSortedPosts(by ranking.Sort) (posts []entities.Post, err error) {
+	defer repo.recorder.observeRepository("GetSortedPosts", repo.recorder.Now(), &err)
+	return interfaces.SortedPosts(repo.backend, repo.backend, by)
+}
+
+func (repo *Repository) GetPostBySlug(slug string) (post entities.Post, err error) {
+	defer repo.recorder.observeRepository("GetPostBySlug", repo.recorder.Now(), &err)
+	return interfaces.PostBySlug(repo.backend, slug)
+}
+
+func (repo *Repository) GetChangesSince(seq int64, limit int) (changes []entities.Change, err error) {
+	defer repo.recorder.observeRepository("GetChangesSince", repo.recorder.Now(), &err)
+	return interfaces.ChangesSince(repo.backend, seq, limit)
+}
+
+func (repo *Repository) RecentPosts(limit int) (entries []feeds.Entry, err error) {
+	defer repo.recorder.observeRepository("RecentPosts", repo.recorder.Now(), &err)
+	return interfaces.RecentPosts(repo.backend, limit)
+}
+
+func (repo *txRepository) GetPosts() (posts []entities.Post, err error) {
+	defer repo.recorder.observeRepository("GetPosts", repo.recorder.Now(), &err)
+	return repo.backend.GetPosts()
+}
+
+func (repo *txRepository) GetPost(id int) (post entities.Post, err error) {
+	defer repo.recorder.observeRepository("GetPost", repo.recorder.Now(), &err)
+	return repo.backend.GetPost(id)
+}
+
+func (repo *txRepository) SavePost(post entities.Post) (saved entities.Post, err error) {
+	defer repo.recorder.observeRepository("SavePost", repo.recorder.Now(), &err)
+	return repo.backend.SavePost(post)
+}
+
+func (repo *txRepository) DeletePost(id int) (err error) {
+	defer repo.recorder.observeRepository("DeletePost", repo.recorder.Now(), &err)
+	return repo.backend.DeletePost(id)
+}
+
+func (repo *txRepository) UpdatePost(id int, data entities.Post) (err error) {
+	defer repo.recorder.observeRepository("UpdatePost", repo.recorder.Now(), &err)
+	return repo.backend.UpdatePost(id, data)
+}
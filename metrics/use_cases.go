@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/events"
+	"github.com/steve-kaufman/postsService/interfaces"
+	"github.com/steve-kaufman/postsService/useCases"
+)
+
+// UseCases has a method for each use case that calls it and records the
+// call under the use case's name
+type UseCases struct {
+	recorder *Recorder
+}
+
+func (recorder *Recorder) UseCases() UseCases {
+	return UseCases{recorder}
+}
+
+func (u UseCases) CreatePost(saver interfaces.PostSaver, publisher events.Publisher, renderer interfaces.ContentRenderer, post entities.Post) (created entities.Post, err error) {
+	defer u.recorder.observeUseCase("CreatePost", u.recorder.Now(), &err)
+	return useCases.CreatePost(saver, publisher, renderer, post)
+}
+
+func (u UseCases) GetAllPosts(getter interfaces.PostsGetter, renderer interfaces.ContentRenderer, sort string) (posts []entities.Post, err error) {
+	defer u.recorder.observeUseCase("GetAllPosts", u.recorder.Now(), &err)
+	return useCases.GetAllPosts(getter, renderer, sort)
+}
+
+func (u UseCases) GetOnePost(getter interfaces.PostGetter, renderer interfaces.ContentRenderer, id int) (post entities.Post, err error) {
+	defer u.recorder.observeUseCase("GetOnePost", u.recorder.Now(), &err)
+	return useCases.GetOnePost(getter, renderer, id)
+}
+
+func (u UseCases) GetPostBySlug(getter interfaces.SlugGetter, renderer interfaces.ContentRenderer, slug string) (post entities.Post, err error) {
+	defer u.recorder.observeUseCase("GetPostBySlug", u.recorder.Now(), &err)
+	return useCases.GetPostBySlug(getter, renderer, slug)
+}
+
+func (u UseCases) GetTrendingPosts(trends interfaces.TrendingGetter, getter interfaces.PostGetter, renderer interfaces.ContentRenderer, window time.Duration) (posts []entities.Post, err error) {
+	defer u.recorder.observeUseCase("GetTrendingPosts", u.recorder.Now(), &err)
+	return useCases.GetTrendingPosts(trends, getter, renderer, window)
+}
+
+func (u UseCases) GetChangesSince(getter interfaces.ChangesGetter, seq int64, limit int) (changes []entities.Change, next int64, err error) {
+	defer u.recorder.observeUseCase("GetChangesSince", u.recorder.Now(), &err)
+	return useCases.GetChangesSince(getter, seq, limit)
+}
+
+func (u UseCases) UpdatePost(runner interfaces.TxRunner, publisher events.Publisher, renderer interfaces.ContentRenderer, id int, updateData entities.Post) (updated entities.Post, err error) {
+	defer u.recorder.observeUseCase("UpdatePost", u.recorder.Now(), &err)
+	return useCases.UpdatePost(runner, publisher, renderer, id, updateData)
+}
+
+func (u UseCases) DeletePost(runner interfaces.TxRunner, publisher events.Publisher, id int) (deleted entities.Post, err error) {
+	defer u.recorder.observeUseCase("DeletePost", u.recorder.Now(), &err)
+	return useCases.DeletePost(runner, publisher, id)
+}
+
+func (u UseCases) LikePost(runner interfaces.TxRunner, publisher events.Publisher, renderer interfaces.ContentRenderer, id int) (voted entities.Post, err error) {
+	defer u.recorder.observeUseCase("LikePost", u.recorder.Now(), &err)
+	return useCases.LikePost(runner, publisher, renderer, id)
+}
+
+func (u UseCases) DislikePost(runner interfaces.TxRunner, publisher events.Publisher, renderer interfaces.ContentRenderer, id int) (voted entities.Post, err error) {
+	defer u.recorder.observeUseCase("DislikePost", u.recorder.Now(), &err)
+	return useCases.DislikePost(runner, publisher, renderer, id)
+}
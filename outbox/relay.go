@@ -0,0 +1,139 @@
+// Package outbox delivers events that were recorded in the same
+// transaction as the change that caused them. Delivery is at-least-once:
+// an event is only marked delivered after its delivery succeeds.
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/steve-kaufman/postsService/backoff"
+	"github.com/steve-kaufman/postsService/events"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusDead      = "dead"
+)
+
+// Message is an outbox row awaiting delivery
+type Message struct {
+	ID       int64
+	Event    events.Event
+	Attempts int
+}
+
+// Store is where outbox messages are kept, normally next to the posts
+type Store interface {
+	// PendingOutbox returns up to limit pending messages that are due at now,
+	// oldest first
+	PendingOutbox(now time.Time, limit int) ([]Message, error)
+	MarkOutboxDelivered(id int64) error
+	// MarkOutboxFailed records a failed attempt; the message is retried at
+	// retryAt, or never again if status is StatusDead
+	MarkOutboxFailed(id int64, status string, retryAt time.Time, cause error) error
+}
+
+// DeliverFunc sends an event on. A returned error means it will be retried.
+type DeliverFunc func(event events.Event) error
+
+// ToPublisher delivers to a Publisher, which cannot fail
+func ToPublisher(publisher events.Publisher) DeliverFunc {
+	return func(event events.Event) error {
+		publisher.Publish(event)
+		return nil
+	}
+}
+
+type Relay struct {
+	BatchSize    int
+	PollInterval time.Duration
+	// MaxAttempts is how many failed deliveries a message gets before it
+	// is marked dead
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// Now is the relay's clock
+	Now func() time.Time
+	// OnError is told when Run fails to relay a batch, which it retries
+	// at the next poll. It logs with slog's default logger unless
+	// replaced.
+	OnError func(err error)
+
+	store   Store
+	deliver DeliverFunc
+}
+
+func NewRelay(store Store, deliver DeliverFunc) *Relay {
+	return &Relay{
+		BatchSize:    100,
+		PollInterval: time.Second,
+		MaxAttempts:  10,
+		BaseBackoff:  time.Second,
+		MaxBackoff:   5 * time.Minute,
+		Now:          time.Now,
+		OnError:      logError,
+		store:        store,
+		deliver:      deliver,
+	}
+}
+
+// Run relays messages every PollInterval until ctx is done
+func (relay *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(relay.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := relay.RelayOnce()
+			if err != nil {
+				relay.OnError(err)
+				break
+			}
+			if n < relay.BatchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func logError(err error) {
+	slog.Error("outbox relay failed", "error", err)
+}
+
+// RelayOnce attempts one batch of due messages and returns how many it
+// attempted
+func (relay *Relay) RelayOnce() (int, error) {
+	messages, err := relay.store.PendingOutbox(relay.Now(), relay.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, message := range messages {
+		if err := relay.relay(message); err != nil {
+			return 0, err
+		}
+	}
+	return len(messages), nil
+}
+
+func (relay *Relay) relay(message Message) error {
+	cause := relay.deliver(message.Event)
+	if cause == nil {
+		return relay.store.MarkOutboxDelivered(message.ID)
+	}
+
+	attempts := message.Attempts + 1
+	if attempts >= relay.MaxAttempts {
+		return relay.store.MarkOutboxFailed(message.ID, StatusDead, time.Time{}, cause)
+	}
+	retryAt := relay.Now().Add(backoff.Exponential(relay.BaseBackoff, relay.MaxBackoff, attempts))
+	return relay.store.MarkOutboxFailed(message.ID, StatusPending, retryAt, cause)
+}
@@ -0,0 +1,159 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/steve-kaufman/postsService/db"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/events"
+	"github.com/steve-kaufman/postsService/outbox"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var errDelivery = errors.New("delivery failed")
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func setup(t *testing.T, deliver outbox.DeliverFunc) (*db.SqliteRepo, *outbox.Relay, *clock) {
+	repo, err := db.NewSqliteRepo(filepath.Join(t.TempDir(), "posts.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.EnableOutbox()
+	// ahead of the wall clock, so events recorded during the test are due
+	c := &clock{now: time.Now().Add(time.Minute)}
+	relay := outbox.NewRelay(repo, deliver)
+	relay.Now = c.Now
+	relay.BaseBackoff = time.Second
+	relay.MaxBackoff = 4 * time.Second
+	relay.MaxAttempts = 4
+	return repo, relay, c
+}
+
+func TestRelayOnce_DeliversPendingEventsInOrder(t *testing.T) {
+	recorder := new(events.Recorder)
+	repo, relay, _ := setup(t, outbox.ToPublisher(recorder))
+	repo.SavePost(entities.Post{Title: "Foo"})
+	repo.DeletePost(1)
+
+	n, err := relay.RelayOnce()
+
+	if err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	if n != 2 {
+		t.Fatalf("Expected 2 messages relayed; Got: %d", n)
+	}
+	expected := []events.Event{
+		events.PostCreated{Post: entities.Post{ID: 1, Slug: "foo", Title: "Foo"}},
+		events.PostDeleted{Post: entities.Post{ID: 1, Slug: "foo", Title: "Foo"}},
+	}
+	if diff := cmp.Diff(expected, recorder.Events()); diff != "" {
+		t.Fatalf("Expected events to be delivered: \n%s", diff)
+	}
+	if n, _ := relay.RelayOnce(); n != 0 {
+		t.Fatalf("Expected delivered events not to be relayed again; Got: %d", n)
+	}
+}
+
+func TestRelayOnce_RetriesWithBackoff(t *testing.T) {
+	failures := 2
+	delivered := 0
+	repo, relay, c := setup(t, func(event events.Event) error {
+		if failures > 0 {
+			failures--
+			return errDelivery
+		}
+		delivered++
+		return nil
+	})
+	repo.SavePost(entities.Post{Title: "Foo"})
+
+	steps := []struct {
+		advance  time.Duration
+		expected int
+	}{
+		{0, 1},                      // first attempt fails, retry in 1s
+		{999 * time.Millisecond, 0}, // not due yet
+		{time.Millisecond, 1},       // second attempt fails, retry in 2s
+		{time.Second, 0},            // not due yet
+		{time.Second, 1},            // third attempt succeeds
+		{time.Hour, 0},              // nothing left
+	}
+	for i, step := range steps {
+		c.now = c.now.Add(step.advance)
+		n, err := relay.RelayOnce()
+		if err != nil {
+			t.Fatalf("Step %d: Expected no error; Got: '%v'", i, err)
+		}
+		if n != step.expected {
+			t.Fatalf("Step %d: Expected %d attempts; Got: %d", i, step.expected, n)
+		}
+	}
+	if delivered != 1 {
+		t.Fatalf("Expected one delivery; Got: %d", delivered)
+	}
+}
+
+func TestRelayOnce_DeadLettersAfterMaxAttempts(t *testing.T) {
+	attempts := 0
+	repo, relay, c := setup(t, func(event events.Event) error {
+		attempts++
+		return errDelivery
+	})
+	repo.SavePost(entities.Post{Title: "Foo"})
+
+	for i := 0; i < 10; i++ {
+		relay.RelayOnce()
+		c.now = c.now.Add(time.Minute)
+	}
+
+	if attempts != 4 {
+		t.Fatalf("Expected 4 attempts before dead-lettering; Got: %d", attempts)
+	}
+	if messages, _ := repo.PendingOutbox(c.now, 100); len(messages) != 0 {
+		t.Fatalf("Expected no pending messages; Got: '%v'", messages)
+	}
+}
+
+type failingStore struct {
+	outbox.Store
+}
+
+func (failingStore) PendingOutbox(now time.Time, limit int) ([]outbox.Message, error) {
+	return nil, errDelivery
+}
+
+func TestRun_ReportsErrors(t *testing.T) {
+	relay := outbox.NewRelay(failingStore{}, outbox.ToPublisher(new(events.Recorder)))
+	relay.PollInterval = time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	reported := []error{}
+	relay.OnError = func(err error) {
+		reported = append(reported, err)
+		if len(reported) == 2 {
+			cancel()
+		}
+	}
+
+	err := relay.Run(ctx)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected Run to stop with the context; Got: '%v'", err)
+	}
+	if len(reported) != 2 || !errors.Is(reported[0], errDelivery) {
+		t.Fatalf("Expected each failed poll to be reported; Got: '%v'", reported)
+	}
+}
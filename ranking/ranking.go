@@ -0,0 +1,115 @@
+// Package ranking scores posts from their likes and dislikes so they can be
+// ordered by more than insertion.
+package ranking
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/steve-kaufman/postsService/entities"
+)
+
+type Sort string
+
+const (
+	SortNew           Sort = "new"
+	SortHot           Sort = "hot"
+	SortTop           Sort = "top"
+	SortControversial Sort = "controversial"
+)
+
+var ErrUnknownSort = errors.New("sort must be one of new, hot, top or controversial")
+
+// ParseSort accepts a sort name as given in a query string; empty means new
+func ParseSort(name string) (Sort, error) {
+	switch Sort(name) {
+	case "":
+		return SortNew, nil
+	case SortNew, SortHot, SortTop, SortControversial:
+		return Sort(name), nil
+	}
+	return "", ErrUnknownSort
+}
+
+// z for a 95% confidence interval
+const z = 1.96
+
+// Top is the lower bound of the Wilson score interval for the share of
+// likes, so a post with few votes needs a better ratio to rank high
+func Top(post entities.Post) float64 {
+	n := float64(post.Likes + post.Dislikes)
+	if n <= 0 {
+		return 0
+	}
+	p := float64(post.Likes) / n
+	return (p + z*z/(2*n) - z*math.Sqrt((p*(1-p)+z*z/(4*n))/n)) / (1 + z*z/n)
+}
+
+// hotEpoch and hotTimescale anchor Hot: a post needs ten times the net
+// votes to rank with one posted hotTimescale later
+var hotEpoch = time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+const hotTimescale = 45000 * time.Second
+
+// Hot weighs net votes on a log scale against the time the post was
+// created. Newer posts rank higher, so the score never needs recomputing
+// as time passes, only when votes change.
+func Hot(post entities.Post, created time.Time) float64 {
+	net := float64(post.Likes - post.Dislikes)
+	order := math.Log10(math.Max(math.Abs(net), 1))
+	sign := 0.0
+	if net > 0 {
+		sign = 1
+	} else if net < 0 {
+		sign = -1
+	}
+	age := created.Sub(hotEpoch).Seconds() / hotTimescale.Seconds()
+	return sign*order + age
+}
+
+// Controversial is high for posts with many votes split evenly between
+// likes and dislikes, and zero for posts with none of either
+func Controversial(post entities.Post) float64 {
+	if post.Likes <= 0 || post.Dislikes <= 0 {
+		return 0
+	}
+	magnitude := float64(post.Likes + post.Dislikes)
+	balance := float64(post.Dislikes) / float64(post.Likes)
+	if post.Likes < post.Dislikes {
+		balance = 1 / balance
+	}
+	return math.Pow(magnitude, balance)
+}
+
+// SortPosts orders posts in place for repositories that can't sort
+// themselves. created gives each post's creation time; new orders by ID
+// when it is nil. Ties keep their ID order.
+func SortPosts(posts []entities.Post, by Sort, created func(entities.Post) time.Time) {
+	if created == nil {
+		created = func(entities.Post) time.Time { return hotEpoch }
+	}
+	score := func(post entities.Post) float64 {
+		switch by {
+		case SortHot:
+			return Hot(post, created(post))
+		case SortTop:
+			return Top(post)
+		case SortControversial:
+			return Controversial(post)
+		}
+		return float64(created(post).UnixNano())
+	}
+
+	sort.SliceStable(posts, func(i, j int) bool {
+		a, b := score(posts[i]), score(posts[j])
+		if a != b {
+			return a > b
+		}
+		if by == SortNew {
+			return posts[i].ID > posts[j].ID
+		}
+		return posts[i].ID < posts[j].ID
+	})
+}
@@ -0,0 +1,118 @@
+package ranking_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/ranking"
+)
+
+func TestParseSort(t *testing.T) {
+	testCases := map[string]ranking.Sort{
+		"":              ranking.SortNew,
+		"new":           ranking.SortNew,
+		"hot":           ranking.SortHot,
+		"top":           ranking.SortTop,
+		"controversial": ranking.SortControversial,
+	}
+	for name, expected := range testCases {
+		if by, err := ranking.ParseSort(name); by != expected || err != nil {
+			t.Fatalf("Expected '%s' to parse as '%s'; Got: '%s', '%v'", name, expected, by, err)
+		}
+	}
+
+	if _, err := ranking.ParseSort("Hot"); err != ranking.ErrUnknownSort {
+		t.Fatalf("Expected ErrUnknownSort; Got: '%v'", err)
+	}
+}
+
+func TestTop_IsWilsonLowerBound(t *testing.T) {
+	testCases := []struct {
+		likes, dislikes int
+		expected        float64
+	}{
+		{likes: 0, dislikes: 0, expected: 0},
+		{likes: 0, dislikes: 10, expected: 0},
+		{likes: 1, dislikes: 0, expected: 0.2065},
+		{likes: 10, dislikes: 0, expected: 0.7225},
+		{likes: 60, dislikes: 40, expected: 0.5020},
+	}
+
+	for _, tc := range testCases {
+		score := ranking.Top(entities.Post{Likes: tc.likes, Dislikes: tc.dislikes})
+		if math.Abs(score-tc.expected) > 0.0001 {
+			t.Fatalf("Expected %d/%d to score %.4f; Got: %.4f", tc.likes, tc.dislikes, tc.expected, score)
+		}
+	}
+}
+
+func TestTop_FavorsConfidenceOverRatio(t *testing.T) {
+	few := ranking.Top(entities.Post{Likes: 2, Dislikes: 0})
+	many := ranking.Top(entities.Post{Likes: 90, Dislikes: 10})
+
+	if few >= many {
+		t.Fatalf("Expected 90/10 to outrank 2/0; Got: %f >= %f", few, many)
+	}
+}
+
+func TestHot_TradesVotesForRecency(t *testing.T) {
+	created := time.Date(2021, time.June, 1, 0, 0, 0, 0, time.UTC)
+	post := entities.Post{Likes: 11, Dislikes: 1}
+
+	older := ranking.Hot(post, created)
+	newer := ranking.Hot(entities.Post{Likes: 1}, created.Add(45000*time.Second))
+	if math.Abs(older-newer) > 1e-9 {
+		t.Fatalf("Expected +10 votes to be worth 45000s of age; Got: %f and %f", older, newer)
+	}
+	if ranking.Hot(post, created) <= ranking.Hot(entities.Post{Likes: 1, Dislikes: 11}, created) {
+		t.Fatal("Expected net likes to outrank net dislikes")
+	}
+}
+
+func TestControversial_FavorsBalancedVotes(t *testing.T) {
+	balanced := ranking.Controversial(entities.Post{Likes: 50, Dislikes: 50})
+	lopsided := ranking.Controversial(entities.Post{Likes: 90, Dislikes: 10})
+	oneSided := ranking.Controversial(entities.Post{Likes: 100})
+
+	if !(balanced > lopsided && lopsided > oneSided && oneSided == 0) {
+		t.Fatalf("Expected balanced > lopsided > one-sided = 0; Got: %f, %f, %f", balanced, lopsided, oneSided)
+	}
+	if ranking.Controversial(entities.Post{Likes: 10, Dislikes: 90}) != lopsided {
+		t.Fatal("Expected score not to depend on which side has more votes")
+	}
+}
+
+func TestSortPosts(t *testing.T) {
+	base := time.Date(2021, time.June, 1, 0, 0, 0, 0, time.UTC)
+	posts := []entities.Post{
+		{ID: 1, Likes: 30, Dislikes: 0},
+		{ID: 2, Likes: 20, Dislikes: 20},
+		{ID: 3, Likes: 3, Dislikes: 0},
+		{ID: 4, Likes: 0, Dislikes: 5},
+	}
+	created := func(post entities.Post) time.Time {
+		return base.Add(time.Duration(post.ID) * time.Hour)
+	}
+
+	testCases := map[ranking.Sort][]int{
+		ranking.SortNew:           {4, 3, 2, 1},
+		ranking.SortHot:           {1, 3, 2, 4},
+		ranking.SortTop:           {1, 3, 2, 4},
+		ranking.SortControversial: {2, 1, 3, 4},
+	}
+	for by, expectedIDs := range testCases {
+		sorted := append([]entities.Post{}, posts...)
+		ranking.SortPosts(sorted, by, created)
+
+		ids := []int{}
+		for _, post := range sorted {
+			ids = append(ids, post.ID)
+		}
+		if diff := cmp.Diff(expectedIDs, ids); diff != "" {
+			t.Fatalf("Expected %s order: \n%s", by, diff)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package render
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/events"
+)
+
+// Cache renders post content with Markdown and keeps the HTML for the size
+// most recently rendered posts. An entry is dropped when its post is
+// updated or deleted, and re-rendered if the content it was rendered from
+// no longer matches.
+type Cache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[int]*list.Element
+}
+
+type cacheEntry struct {
+	id      int
+	content string
+	html    string
+}
+
+func NewCache(size int) *Cache {
+	return &Cache{size: size, order: list.New(), entries: map[int]*list.Element{}}
+}
+
+func (cache *Cache) RenderContent(post entities.Post) string {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if element, ok := cache.entries[post.ID]; ok {
+		entry := element.Value.(*cacheEntry)
+		if entry.content == post.Content {
+			cache.order.MoveToFront(element)
+			return entry.html
+		}
+		cache.remove(post.ID)
+	}
+
+	html := Markdown(post.Content)
+	if cache.size <= 0 {
+		return html
+	}
+	cache.entries[post.ID] = cache.order.PushFront(&cacheEntry{id: post.ID, content: post.Content, html: html})
+	if cache.order.Len() > cache.size {
+		cache.remove(cache.order.Back().Value.(*cacheEntry).id)
+	}
+	return html
+}
+
+// Handle drops the entries of updated and deleted posts. It fits
+// events.Bus.Subscribe.
+func (cache *Cache) Handle(event events.Event) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	switch e := event.(type) {
+	case events.PostUpdated:
+		cache.remove(e.After.ID)
+	case events.PostDeleted:
+		cache.remove(e.Post.ID)
+	}
+	return nil
+}
+
+// Len returns the number of cached posts
+func (cache *Cache) Len() int {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.order.Len()
+}
+
+func (cache *Cache) remove(id int) {
+	if element, ok := cache.entries[id]; ok {
+		cache.order.Remove(element)
+		delete(cache.entries, id)
+	}
+}
+
+// Skip is a renderer that leaves ContentHTML empty, for callers that
+// don't show content
+var Skip skip
+
+type skip struct{}
+
+func (skip) RenderContent(post entities.Post) string { return "" }
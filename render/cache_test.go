@@ -0,0 +1,69 @@
+package render_test
+
+import (
+	"testing"
+
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/events"
+	"github.com/steve-kaufman/postsService/render"
+)
+
+func TestCache_RendersAndKeepsHTML(t *testing.T) {
+	cache := render.NewCache(10)
+	post := entities.Post{ID: 1, Content: "*hi*"}
+
+	if html := cache.RenderContent(post); html != "<p><em>hi</em></p>\n" {
+		t.Fatalf("Expected rendered Markdown; Got: '%s'", html)
+	}
+	cache.RenderContent(post)
+	if cache.Len() != 1 {
+		t.Fatalf("Expected one cached post; Got: %d", cache.Len())
+	}
+}
+
+func TestCache_RerendersChangedContent(t *testing.T) {
+	cache := render.NewCache(10)
+	cache.RenderContent(entities.Post{ID: 1, Content: "*old*"})
+
+	html := cache.RenderContent(entities.Post{ID: 1, Content: "**new**"})
+
+	if html != "<p><strong>new</strong></p>\n" {
+		t.Fatalf("Expected stale HTML to be replaced; Got: '%s'", html)
+	}
+}
+
+func TestCache_DropsUpdatedAndDeletedPosts(t *testing.T) {
+	cache := render.NewCache(10)
+	cache.RenderContent(entities.Post{ID: 1, Content: "a"})
+	cache.RenderContent(entities.Post{ID: 2, Content: "b"})
+	cache.RenderContent(entities.Post{ID: 3, Content: "c"})
+
+	cache.Handle(events.PostUpdated{After: entities.Post{ID: 1}})
+	cache.Handle(events.PostDeleted{Post: entities.Post{ID: 2}})
+	cache.Handle(events.PostVoted{Post: entities.Post{ID: 3}})
+
+	if cache.Len() != 1 {
+		t.Fatalf("Expected only the voted post to stay cached; Got: %d", cache.Len())
+	}
+}
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := render.NewCache(2)
+	cache.RenderContent(entities.Post{ID: 1, Content: "a"})
+	cache.RenderContent(entities.Post{ID: 2, Content: "b"})
+	cache.RenderContent(entities.Post{ID: 1, Content: "a"})
+	cache.RenderContent(entities.Post{ID: 3, Content: "c"})
+
+	if cache.Len() != 2 {
+		t.Fatalf("Expected the cache to stay at its size; Got: %d", cache.Len())
+	}
+	// post 2 was the least recently used, so deleting it changes nothing
+	cache.Handle(events.PostDeleted{Post: entities.Post{ID: 2}})
+	if cache.Len() != 2 {
+		t.Fatalf("Expected post 2 to have been evicted; Got: %d cached", cache.Len())
+	}
+	cache.Handle(events.PostDeleted{Post: entities.Post{ID: 1}})
+	if cache.Len() != 1 {
+		t.Fatalf("Expected post 1 to have stayed cached; Got: %d cached", cache.Len())
+	}
+}
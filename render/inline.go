@@ -0,0 +1,236 @@
+package render
+
+import (
+	"html"
+	"strings"
+)
+
+const punctuation = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+// renderInline writes text with code spans, emphasis, links and autolinks
+// turned into HTML and everything else escaped
+func renderInline(out *strings.Builder, s string) {
+	for i := 0; i < len(s); {
+		switch c := s[i]; c {
+		case '\\':
+			if i+1 < len(s) && strings.IndexByte(punctuation, s[i+1]) >= 0 {
+				out.WriteString(html.EscapeString(s[i+1 : i+2]))
+				i += 2
+				continue
+			}
+		case '`':
+			if n := codeSpan(out, s[i:]); n > 0 {
+				i += n
+				continue
+			}
+		case '*', '_':
+			if n := emphasis(out, s, i); n > 0 {
+				i += n
+				continue
+			}
+		case '[':
+			if n := link(out, s[i:]); n > 0 {
+				i += n
+				continue
+			}
+		case '<':
+			if n := autolink(out, s[i:]); n > 0 {
+				i += n
+				continue
+			}
+		}
+
+		// plain text up to the next character that may start something;
+		// entities in it are taken as written, as CommonMark does
+		end := i + 1
+		for end < len(s) && strings.IndexByte("\\`*_[<", s[end]) < 0 {
+			end++
+		}
+		out.WriteString(html.EscapeString(html.UnescapeString(s[i:end])))
+		i = end
+	}
+}
+
+func runLength(s string, i int) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == s[i] {
+		n++
+	}
+	return n
+}
+
+// codeSpan writes the code span at the start of s, returning its length or
+// 0 if the backticks are never closed by a run of the same length
+func codeSpan(out *strings.Builder, s string) int {
+	n := runLength(s, 0)
+	for i := n; i < len(s); {
+		if s[i] != '`' {
+			i++
+			continue
+		}
+		m := runLength(s, i)
+		if m == n {
+			code := strings.ReplaceAll(s[n:i], "\n", " ")
+			if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+				code = code[1 : len(code)-1]
+			}
+			out.WriteString("<code>" + html.EscapeString(code) + "</code>")
+			return i + m
+		}
+		i += m
+	}
+	out.WriteString(html.EscapeString(s[:n]))
+	return n
+}
+
+// emphasis writes the emphasis opened at s[i], returning how much of s it
+// used or 0 if the delimiters don't open emphasis that gets closed
+func emphasis(out *strings.Builder, s string, i int) int {
+	c := s[i]
+	run := runLength(s, i)
+	n := run
+	if n > 3 {
+		n = 3
+	}
+	after := i + run
+	if after >= len(s) || isSpace(s[after]) {
+		return 0
+	}
+	// _ can't open or close inside a word
+	if c == '_' && i > 0 && isAlnum(s[i-1]) {
+		return 0
+	}
+
+	for j := after; j < len(s); {
+		if s[j] == '`' {
+			j += runLength(s, j)
+			continue
+		}
+		if s[j] != c {
+			j++
+			continue
+		}
+		m := runLength(s, j)
+		closes := m == run && !isSpace(s[j-1])
+		if c == '_' && j+m < len(s) && isAlnum(s[j+m]) {
+			closes = false
+		}
+		if !closes {
+			j += m
+			continue
+		}
+
+		open, close := "<em>", "</em>"
+		switch n {
+		case 2:
+			open, close = "<strong>", "</strong>"
+		case 3:
+			open, close = "<em><strong>", "</strong></em>"
+		}
+		out.WriteString(open)
+		renderInline(out, s[after:j])
+		out.WriteString(close)
+		return j + m - i
+	}
+	return 0
+}
+
+// link writes the [text](destination "title") at the start of s, returning
+// its length or 0 if it isn't one. Unsafe destinations keep only the text.
+func link(out *strings.Builder, s string) int {
+	depth := 0
+	end := -1
+	for i := 0; i < len(s) && end < 0; i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				end = i
+			}
+		}
+	}
+	if end < 0 || end+1 >= len(s) || s[end+1] != '(' {
+		return 0
+	}
+	text := s[1:end]
+
+	i := end + 2
+	for i < len(s) && s[i] == ' ' {
+		i++
+	}
+	var dest string
+	if i < len(s) && s[i] == '<' {
+		close := strings.IndexByte(s[i:], '>')
+		if close < 0 {
+			return 0
+		}
+		dest = s[i+1 : i+close]
+		i += close + 1
+	} else {
+		start, parens := i, 0
+		for ; i < len(s) && !isSpace(s[i]); i++ {
+			if s[i] == '(' {
+				parens++
+			} else if s[i] == ')' {
+				if parens == 0 {
+					break
+				}
+				parens--
+			}
+		}
+		dest = s[start:i]
+	}
+
+	for i < len(s) && isSpace(s[i]) {
+		i++
+	}
+	var title string
+	if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+		close := strings.IndexByte(s[i+1:], s[i])
+		if close < 0 {
+			return 0
+		}
+		title = s[i+1 : i+1+close]
+		i += close + 2
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+	}
+	if i >= len(s) || s[i] != ')' {
+		return 0
+	}
+
+	if !safeURL(html.UnescapeString(dest)) {
+		renderInline(out, text)
+		return i + 1
+	}
+	out.WriteString(`<a href="` + html.EscapeString(dest) + `"`)
+	if title != "" {
+		out.WriteString(` title="` + html.EscapeString(title) + `"`)
+	}
+	out.WriteString(">")
+	renderInline(out, text)
+	out.WriteString("</a>")
+	return i + 1
+}
+
+// autolink writes the <scheme:...> at the start of s, returning its length
+// or 0 if it isn't an autolink with an allowed scheme
+func autolink(out *strings.Builder, s string) int {
+	end := strings.IndexByte(s, '>')
+	if end < 0 {
+		return 0
+	}
+	url := s[1:end]
+	colon := strings.IndexByte(url, ':')
+	if colon <= 0 || strings.ContainsAny(url, " <\n") || !allowedSchemes[strings.ToLower(url[:colon])] {
+		return 0
+	}
+	escaped := html.EscapeString(url)
+	out.WriteString(`<a href="` + escaped + `">` + escaped + "</a>")
+	return end + 1
+}
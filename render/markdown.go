@@ -0,0 +1,298 @@
+// Package render turns post content written in Markdown into HTML that is
+// safe to embed in a page.
+package render
+
+import (
+	"html"
+	"strconv"
+	"strings"
+)
+
+// Markdown renders a CommonMark subset to HTML: ATX headings, paragraphs,
+// emphasis, inline code and fenced code blocks, links and autolinks, block
+// quotes, and ordered and unordered lists. Raw HTML in src is escaped
+// rather than passed through, and the result goes through Sanitize.
+func Markdown(src string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\t", "    ")
+	var out strings.Builder
+	renderBlocks(&out, strings.Split(src, "\n"), false)
+	return Sanitize(out.String())
+}
+
+// renderBlocks writes lines as block elements. In a tight list paragraphs
+// are written without <p>.
+func renderBlocks(out *strings.Builder, lines []string, tight bool) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimLeft(line, " ")
+
+		if trimmed == "" {
+			i++
+			continue
+		}
+		if level, text, ok := heading(trimmed); ok {
+			tag := "h" + strconv.Itoa(level)
+			out.WriteString("<" + tag + ">")
+			renderInline(out, text)
+			out.WriteString("</" + tag + ">\n")
+			i++
+			continue
+		}
+		if fence, info, ok := openingFence(trimmed); ok {
+			i = renderFencedCode(out, lines, i+1, fence, info)
+			continue
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			i = renderQuote(out, lines, i)
+			continue
+		}
+		if _, ok := listItem(line); ok {
+			i = renderList(out, lines, i)
+			continue
+		}
+		i = renderParagraph(out, lines, i, tight)
+	}
+}
+
+// heading recognizes "# text" through "###### text"
+func heading(line string) (int, string, bool) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || (level < len(line) && line[level] != ' ') {
+		return 0, "", false
+	}
+	text := strings.TrimSpace(line[level:])
+	// an optional closing sequence of #s
+	if closing := strings.TrimRight(text, "#"); closing == "" || strings.HasSuffix(closing, " ") {
+		text = strings.TrimSpace(closing)
+	}
+	return level, text, true
+}
+
+// openingFence recognizes ``` or ~~~ (or longer) with an optional info
+// string, whose first word names the language
+func openingFence(line string) (string, string, bool) {
+	if !strings.HasPrefix(line, "```") && !strings.HasPrefix(line, "~~~") {
+		return "", "", false
+	}
+	n := 0
+	for n < len(line) && line[n] == line[0] {
+		n++
+	}
+	info := strings.TrimSpace(line[n:])
+	if line[0] == '`' && strings.Contains(info, "`") {
+		return "", "", false
+	}
+	if fields := strings.Fields(info); len(fields) > 0 {
+		info = fields[0]
+	}
+	return line[:n], info, true
+}
+
+func renderFencedCode(out *strings.Builder, lines []string, i int, fence, info string) int {
+	out.WriteString("<pre><code")
+	if info != "" {
+		out.WriteString(` class="language-` + html.EscapeString(info) + `"`)
+	}
+	out.WriteString(">")
+	for ; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			i++
+			break
+		}
+		out.WriteString(html.EscapeString(lines[i]) + "\n")
+	}
+	out.WriteString("</code></pre>\n")
+	return i
+}
+
+func renderQuote(out *strings.Builder, lines []string, i int) int {
+	var inner []string
+	for ; i < len(lines); i++ {
+		trimmed := strings.TrimLeft(lines[i], " ")
+		if !strings.HasPrefix(trimmed, ">") {
+			break
+		}
+		trimmed = trimmed[1:]
+		if strings.HasPrefix(trimmed, " ") {
+			trimmed = trimmed[1:]
+		}
+		inner = append(inner, trimmed)
+	}
+	out.WriteString("<blockquote>\n")
+	renderBlocks(out, inner, false)
+	out.WriteString("</blockquote>\n")
+	return i
+}
+
+type marker struct {
+	ordered bool
+	// delimiter is the bullet, or the . or ) after an ordered number
+	delimiter byte
+	start     int
+	// indent is the column the item's content starts at
+	indent int
+}
+
+// listItem recognizes "- ", "* ", "+ ", "1. " and "1) " list markers
+func listItem(line string) (marker, bool) {
+	i := 0
+	for i < len(line) && line[i] == ' ' {
+		i++
+	}
+	if i > 3 || i >= len(line) {
+		return marker{}, false
+	}
+
+	m := marker{}
+	switch c := line[i]; {
+	case c == '-' || c == '*' || c == '+':
+		m.delimiter = c
+		i++
+	case c >= '0' && c <= '9':
+		start := i
+		for i < len(line) && i-start < 9 && line[i] >= '0' && line[i] <= '9' {
+			i++
+		}
+		if i >= len(line) || (line[i] != '.' && line[i] != ')') {
+			return marker{}, false
+		}
+		m.ordered = true
+		m.start, _ = strconv.Atoi(line[start:i])
+		m.delimiter = line[i]
+		i++
+	default:
+		return marker{}, false
+	}
+
+	if i < len(line) && line[i] != ' ' {
+		return marker{}, false
+	}
+	m.indent = i + 1
+	return m, true
+}
+
+func startsBlock(line string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	_, _, isHeading := heading(trimmed)
+	_, _, isFence := openingFence(trimmed)
+	_, isItem := listItem(line)
+	return isHeading || isFence || isItem || strings.HasPrefix(trimmed, ">")
+}
+
+func renderList(out *strings.Builder, lines []string, i int) int {
+	first, _ := listItem(lines[i])
+	var items [][]string
+	tight := true
+
+	for i < len(lines) {
+		m, ok := listItem(lines[i])
+		if !ok || m.ordered != first.ordered || m.delimiter != first.delimiter {
+			break
+		}
+		item := []string{padTo(lines[i], m.indent)[m.indent:]}
+		i++
+
+		for i < len(lines) {
+			line := lines[i]
+			switch {
+			case strings.TrimSpace(line) == "":
+				// blank lines belong to the item only if it continues after them
+				j := i
+				for j < len(lines) && strings.TrimSpace(lines[j]) == "" {
+					j++
+				}
+				if j < len(lines) && indentation(lines[j]) >= m.indent {
+					item = append(item, lines[i:j]...)
+					tight = false
+					i = j
+					continue
+				}
+			case indentation(line) >= m.indent:
+				item = append(item, line[m.indent:])
+				i++
+				continue
+			case !startsBlock(line) && strings.TrimSpace(item[len(item)-1]) != "":
+				// a lazy continuation of the item's paragraph
+				item = append(item, strings.TrimLeft(line, " "))
+				i++
+				continue
+			}
+			break
+		}
+		items = append(items, item)
+
+		// a blank line between items makes the list loose
+		j := i
+		for j < len(lines) && strings.TrimSpace(lines[j]) == "" {
+			j++
+		}
+		if next, ok := listItem(lineAt(lines, j)); j > i && ok && next.ordered == first.ordered && next.delimiter == first.delimiter {
+			tight = false
+			i = j
+		}
+	}
+
+	tag := "ul"
+	if first.ordered {
+		tag = "ol"
+		if first.start != 1 {
+			out.WriteString(`<ol start="` + strconv.Itoa(first.start) + `">` + "\n")
+		} else {
+			out.WriteString("<ol>\n")
+		}
+	} else {
+		out.WriteString("<ul>\n")
+	}
+	for _, item := range items {
+		out.WriteString("<li>")
+		var inner strings.Builder
+		renderBlocks(&inner, item, tight)
+		out.WriteString(strings.TrimSuffix(inner.String(), "\n"))
+		out.WriteString("</li>\n")
+	}
+	out.WriteString("</" + tag + ">\n")
+	return i
+}
+
+func renderParagraph(out *strings.Builder, lines []string, i int, tight bool) int {
+	var text []string
+	for ; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "" || (len(text) > 0 && startsBlock(lines[i])) {
+			break
+		}
+		text = append(text, strings.TrimSpace(lines[i]))
+	}
+
+	if !tight {
+		out.WriteString("<p>")
+	}
+	renderInline(out, strings.Join(text, "\n"))
+	if !tight {
+		out.WriteString("</p>")
+	}
+	out.WriteString("\n")
+	return i
+}
+
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+func padTo(line string, n int) string {
+	for len(line) < n {
+		line += " "
+	}
+	return line
+}
+
+func lineAt(lines []string, i int) string {
+	if i < len(lines) {
+		return lines[i]
+	}
+	return ""
+}
@@ -0,0 +1,53 @@
+package render_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/steve-kaufman/postsService/render"
+)
+
+func TestMarkdown(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Paragraphs", "one\ntwo\n\nthree", "<p>one\ntwo</p>\n<p>three</p>\n"},
+		{"Headings", "# One\n### Three ###\n####### seven", "<h1>One</h1>\n<h3>Three</h3>\n<p>####### seven</p>\n"},
+		{"Heading without space", "#hashtag", "<p>#hashtag</p>\n"},
+		{"Emphasis", "*em* _em_ **strong** __strong__ ***both***", "<p><em>em</em> <em>em</em> <strong>strong</strong> <strong>strong</strong> <em><strong>both</strong></em></p>\n"},
+		{"Nested emphasis", "*a **b** c*", "<p><em>a <strong>b</strong> c</em></p>\n"},
+		{"Unclosed emphasis", "2 * 3 * 4 and *open", "<p>2 * 3 * 4 and *open</p>\n"},
+		{"Intraword underscores", "snake_case_name", "<p>snake_case_name</p>\n"},
+		{"Code span", "use `a < b` or ``x ` y``", "<p>use <code>a &lt; b</code> or <code>x ` y</code></p>\n"},
+		{"No emphasis in code", "`*not em*`", "<p><code>*not em*</code></p>\n"},
+		{"Escapes", `\*not em\* \[x\]`, "<p>*not em* [x]</p>\n"},
+		{"Fenced code", "```go\nif a < b {\n```\nafter", "<pre><code class=\"language-go\">if a &lt; b {\n</code></pre>\n<p>after</p>\n"},
+		{"Unclosed fence", "~~~\ncode", "<pre><code>code\n</code></pre>\n"},
+		{"Link", `[the *site*](https://example.com/a?b=1&c=2 "Title")`, "<p><a href=\"https://example.com/a?b=1&amp;c=2\" title=\"Title\" rel=\"nofollow noopener noreferrer\">the <em>site</em></a></p>\n"},
+		{"Relative link", "[home](/posts/1)", "<p><a href=\"/posts/1\" rel=\"nofollow noopener noreferrer\">home</a></p>\n"},
+		{"Unsafe link", "[click](javascript:alert(1))", "<p>click</p>\n"},
+		{"Encoded unsafe link", "[click](&#106;avascript:alert(1))", "<p>click</p>\n"},
+		{"Not a link", "[just brackets] (x)", "<p>[just brackets] (x)</p>\n"},
+		{"Autolink", "see <https://example.com>", "<p>see <a href=\"https://example.com\" rel=\"nofollow noopener noreferrer\">https://example.com</a></p>\n"},
+		{"Raw HTML is escaped", "<script>alert(1)</script> <b>bold</b>", "<p>&lt;script&gt;alert(1)&lt;/script&gt; &lt;b&gt;bold&lt;/b&gt;</p>\n"},
+		{"Blockquote", "> quoted\n> # heading\n>\n> more", "<blockquote>\n<p>quoted</p>\n<h1>heading</h1>\n<p>more</p>\n</blockquote>\n"},
+		{"Tight list", "- one\n- two\n  continued\n- three", "<ul>\n<li>one</li>\n<li>two\ncontinued</li>\n<li>three</li>\n</ul>\n"},
+		{"Loose list", "* one\n\n* two", "<ul>\n<li><p>one</p></li>\n<li><p>two</p></li>\n</ul>\n"},
+		{"Ordered list", "3. three\n4. four", "<ol start=\"3\">\n<li>three</li>\n<li>four</li>\n</ol>\n"},
+		{"Nested list", "1. one\n   - a\n   - b\n2. two", "<ol>\n<li>one\n<ul>\n<li>a</li>\n<li>b</li>\n</ul></li>\n<li>two</li>\n</ol>\n"},
+		{"Lazy continuation", "- one\nstill one\n\nafter", "<ul>\n<li>one\nstill one</li>\n</ul>\n<p>after</p>\n"},
+		{"List after paragraph", "intro\n- item", "<p>intro</p>\n<ul>\n<li>item</li>\n</ul>\n"},
+		{"Code in list", "- ```\n  x\n  ```", "<ul>\n<li><pre><code>x\n</code></pre></li>\n</ul>\n"},
+		{"Entities stay escaped", "AT&T &amp; &lt;", "<p>AT&amp;T &amp; &lt;</p>\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.expected, render.Markdown(tc.input)); diff != "" {
+				t.Fatalf("Expected rendered HTML to match: \n%s", diff)
+			}
+		})
+	}
+}
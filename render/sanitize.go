@@ -0,0 +1,248 @@
+package render
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// allowedAttrs lists the tags Sanitize keeps and the attributes each may
+// keep, with a check on the attribute's decoded value
+var allowedAttrs = map[string]map[string]func(string) bool{
+	"p":          {},
+	"br":         {},
+	"hr":         {},
+	"h1":         {},
+	"h2":         {},
+	"h3":         {},
+	"h4":         {},
+	"h5":         {},
+	"h6":         {},
+	"em":         {},
+	"strong":     {},
+	"pre":        {},
+	"blockquote": {},
+	"ul":         {},
+	"li":         {},
+	"ol":         {"start": regexp.MustCompile(`^[0-9]{1,9}$`).MatchString},
+	"code":       {"class": regexp.MustCompile(`^language-[A-Za-z0-9_+-]+$`).MatchString},
+	"a":          {"href": safeURL, "title": func(string) bool { return true }},
+}
+
+var voidTags = map[string]bool{"br": true, "hr": true}
+
+// rawTextTags are dropped along with everything up to their end tag
+var rawTextTags = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true,
+	"textarea": true, "title": true, "noscript": true, "template": true,
+}
+
+// allowedSchemes are the URL schemes links may use; relative URLs have none
+var allowedSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+// Sanitize returns html with every tag and attribute outside a small
+// allowlist removed. Text is re-escaped, comments and the contents of
+// scripts and styles are dropped, unclosed tags are closed and links get
+// rel="nofollow noopener noreferrer".
+func Sanitize(input string) string {
+	var out strings.Builder
+	var open []string
+
+	for len(input) > 0 {
+		lt := strings.IndexByte(input, '<')
+		if lt < 0 {
+			writeText(&out, input)
+			break
+		}
+		writeText(&out, input[:lt])
+		input = input[lt:]
+
+		switch {
+		case strings.HasPrefix(input, "<!--"):
+			input = skipPast(input, "-->")
+		case strings.HasPrefix(input, "<!") || strings.HasPrefix(input, "<?"):
+			input = skipPast(input, ">")
+		default:
+			t, rest, ok := parseTag(input)
+			if !ok {
+				out.WriteString("&lt;")
+				input = input[1:]
+				continue
+			}
+			input = rest
+			if !t.closing && rawTextTags[t.name] {
+				input = skipRawText(input, t.name)
+				continue
+			}
+			open = writeTag(&out, t, open)
+		}
+	}
+
+	for i := len(open) - 1; i >= 0; i-- {
+		out.WriteString("</" + open[i] + ">")
+	}
+	return out.String()
+}
+
+func writeText(out *strings.Builder, text string) {
+	out.WriteString(html.EscapeString(html.UnescapeString(text)))
+}
+
+// writeTag writes t if it is allowed, keeping open balanced, and returns
+// the tags still open
+func writeTag(out *strings.Builder, t tag, open []string) []string {
+	attrs, ok := allowedAttrs[t.name]
+	if !ok {
+		return open
+	}
+
+	if t.closing {
+		for i := len(open) - 1; i >= 0; i-- {
+			if open[i] == t.name {
+				for j := len(open) - 1; j >= i; j-- {
+					out.WriteString("</" + open[j] + ">")
+				}
+				return open[:i]
+			}
+		}
+		return open
+	}
+
+	out.WriteString("<" + t.name)
+	for _, attr := range t.attrs {
+		if valid, ok := attrs[attr.name]; ok && valid(attr.value) {
+			out.WriteString(" " + attr.name + `="` + html.EscapeString(attr.value) + `"`)
+		}
+	}
+	if t.name == "a" {
+		out.WriteString(` rel="nofollow noopener noreferrer"`)
+	}
+	out.WriteString(">")
+
+	if voidTags[t.name] {
+		return open
+	}
+	return append(open, t.name)
+}
+
+type attribute struct {
+	name  string
+	value string
+}
+
+type tag struct {
+	name    string
+	closing bool
+	attrs   []attribute
+}
+
+// parseTag reads the tag at the start of s, returning the rest of s after
+// it. Attribute values are entity-decoded. ok is false if s doesn't start
+// with something shaped like a tag.
+func parseTag(s string) (t tag, rest string, ok bool) {
+	i := 1
+	if i < len(s) && s[i] == '/' {
+		t.closing = true
+		i++
+	}
+	start := i
+	for i < len(s) && isAlnum(s[i]) {
+		i++
+	}
+	if i == start || !isLetter(s[start]) {
+		return tag{}, s, false
+	}
+	t.name = strings.ToLower(s[start:i])
+
+	for {
+		for i < len(s) && (isSpace(s[i]) || s[i] == '/') {
+			i++
+		}
+		if i >= len(s) {
+			return tag{}, s, false
+		}
+		if s[i] == '>' {
+			return t, s[i+1:], true
+		}
+
+		start = i
+		for i < len(s) && !isSpace(s[i]) && s[i] != '=' && s[i] != '>' && s[i] != '/' {
+			i++
+		}
+		attr := attribute{name: strings.ToLower(s[start:i])}
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		if i < len(s) && s[i] == '=' {
+			i++
+			for i < len(s) && isSpace(s[i]) {
+				i++
+			}
+			var value string
+			if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+				end := strings.IndexByte(s[i+1:], s[i])
+				if end < 0 {
+					return tag{}, s, false
+				}
+				value = s[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				start = i
+				for i < len(s) && !isSpace(s[i]) && s[i] != '>' {
+					i++
+				}
+				value = s[start:i]
+			}
+			attr.value = html.UnescapeString(value)
+		}
+		if attr.name != "" {
+			t.attrs = append(t.attrs, attr)
+		}
+	}
+}
+
+func skipPast(s, marker string) string {
+	end := strings.Index(s, marker)
+	if end < 0 {
+		return ""
+	}
+	return s[end+len(marker):]
+}
+
+// skipRawText drops everything up to and including the end tag for name
+func skipRawText(s, name string) string {
+	lower := strings.ToLower(s)
+	end := strings.Index(lower, "</"+name)
+	if end < 0 {
+		return ""
+	}
+	return skipPast(s[end:], ">")
+}
+
+// safeURL allows relative URLs and the allowed schemes. The scheme is
+// whatever precedes the first colon that comes before any /, ? or #, so
+// tricks like "java\tscript:" or "JaVaScRiPt:" are rejected too.
+func safeURL(url string) bool {
+	url = strings.TrimSpace(url)
+	for i := 0; i < len(url); i++ {
+		switch url[i] {
+		case '/', '?', '#':
+			return true
+		case ':':
+			return allowedSchemes[strings.ToLower(url[:i])]
+		}
+	}
+	return true
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isAlnum(c byte) bool {
+	return isLetter(c) || (c >= '0' && c <= '9')
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
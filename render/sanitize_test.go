@@ -0,0 +1,53 @@
+package render_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/steve-kaufman/postsService/render"
+)
+
+func TestSanitize(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Allowed tags", "<p>a <em>b</em> <strong>c</strong></p>", "<p>a <em>b</em> <strong>c</strong></p>"},
+		{"Unknown tags keep text", "<div><b>bold</b> <span>x</span></div>", "bold x"},
+		{"Scripts are dropped", "a<script>alert(1)</script>b<STYLE>p{}</style>c", "abc"},
+		{"Unclosed script drops the rest", "a<script>alert(1)", "a"},
+		{"Comments are dropped", "a<!-- <script> -->b<!DOCTYPE html>c", "abc"},
+		{"Event handlers are dropped", `<p onclick="x()" style="color:red">hi</p>`, "<p>hi</p>"},
+		{"Safe link", `<a href="https://example.com/?a=1&amp;b=2" title='t "q"'>x</a>`, `<a href="https://example.com/?a=1&amp;b=2" title="t &#34;q&#34;" rel="nofollow noopener noreferrer">x</a>`},
+		{"JavaScript link", `<a href="javascript:alert(1)">x</a>`, `<a rel="nofollow noopener noreferrer">x</a>`},
+		{"Obfuscated link", `<a href=" JaVa&#83;cript:alert(1)">x</a><a href="java&#9;script:x">y</a>`, `<a rel="nofollow noopener noreferrer">x</a><a rel="nofollow noopener noreferrer">y</a>`},
+		{"Data link", `<a href="data:text/html,<script>">x</a>`, `<a rel="nofollow noopener noreferrer">x</a>`},
+		{"Code class", `<pre><code class="language-go">x</code></pre><code class="evil">y</code>`, `<pre><code class="language-go">x</code></pre><code>y</code>`},
+		{"Ordered list start", `<ol start="3" type="a"><li>x</li></ol><ol start="x">`, `<ol start="3"><li>x</li></ol><ol></ol>`},
+		{"Unclosed tags are closed", "<p><em>open", "<p><em>open</em></p>"},
+		{"Misnested tags", "<em><strong>x</em>y</strong>", "<em><strong>x</strong></em>y"},
+		{"Stray closing tags", "</p>x</em>", "x"},
+		{"Void tags", "a<br/>b<hr>c", "a<br>b<hr>c"},
+		{"Text is escaped", `1 < 2 > 0 & "q"`, "1 &lt; 2 &gt; 0 &amp; &#34;q&#34;"},
+		{"Broken tag", `<a href="x`, "&lt;a href=&#34;x"},
+		{"Attribute with >", `<a title="a>b">x</a>`, `<a title="a&gt;b" rel="nofollow noopener noreferrer">x</a>`},
+		{"Entities are kept", "&lt;b&gt; &amp;", "&lt;b&gt; &amp;"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.expected, render.Sanitize(tc.input)); diff != "" {
+				t.Fatalf("Expected sanitized HTML to match: \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSanitize_IsIdempotent(t *testing.T) {
+	input := render.Markdown("# Hi\n\n[x](https://example.com) `a<b`\n\n- *one*\n- two")
+
+	if diff := cmp.Diff(input, render.Sanitize(input)); diff != "" {
+		t.Fatalf("Expected sanitizing twice to change nothing: \n%s", diff)
+	}
+}
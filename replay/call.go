@@ -0,0 +1,110 @@
+// Package replay records the calls made to a repository, with their
+// results, and serves a recording back in place of the repository, so a
+// session against real data can become a deterministic test.
+package replay
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/feeds"
+	"github.com/steve-kaufman/postsService/ranking"
+	"github.com/steve-kaufman/postsService/useCases"
+)
+
+// Call is one recorded repository call
+type Call struct {
+	Method string `json:"method"`
+	// ID is the post ID the call was made with, if any
+	ID *int `json:"id,omitempty"`
+	// Arg is the post passed to SavePost or UpdatePost
+	Arg *entities.Post `json:"arg,omitempty"`
+	// Slug, Sort, Seq and Limit are the other arguments, of
+	// GetPostBySlug, GetSortedPosts, GetChangesSince and RecentPosts
+	Slug  string       `json:"slug,omitempty"`
+	Sort  ranking.Sort `json:"sort,omitempty"`
+	Seq   *int64       `json:"seq,omitempty"`
+	Limit *int         `json:"limit,omitempty"`
+
+	// Post is what GetPost, GetPostBySlug or SavePost returned, Posts
+	// what GetPosts or GetSortedPosts did
+	Post    *entities.Post    `json:"post,omitempty"`
+	Posts   []entities.Post   `json:"posts,omitempty"`
+	Changes []entities.Change `json:"changes,omitempty"`
+	Entries []feeds.Entry     `json:"entries,omitempty"`
+	Err     string            `json:"err,omitempty"`
+
+	// Tx holds the calls made inside WithinTx, or is nil if the
+	// transaction failed before fn ran
+	Tx *[]Call `json:"tx,omitempty"`
+}
+
+// known are the errors a replay returns as themselves, so callers can
+// still tell them apart with errors.Is. Any other error is replayed as a
+// new error with the same message.
+var known = []error{useCases.ErrNotFound, errors.ErrUnsupported}
+
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func (call Call) err() error {
+	if call.Err == "" {
+		return nil
+	}
+	for _, err := range known {
+		if call.Err == err.Error() {
+			return err
+		}
+	}
+	return errors.New(call.Err)
+}
+
+// matches reports whether call was made the same way as recorded,
+// ignoring the results
+func (call Call) matches(recorded Call) bool {
+	if call.Method != recorded.Method {
+		return false
+	}
+	if (call.ID == nil) != (recorded.ID == nil) || (call.ID != nil && *call.ID != *recorded.ID) {
+		return false
+	}
+	if (call.Arg == nil) != (recorded.Arg == nil) || (call.Arg != nil && *call.Arg != *recorded.Arg) {
+		return false
+	}
+	if (call.Seq == nil) != (recorded.Seq == nil) || (call.Seq != nil && *call.Seq != *recorded.Seq) {
+		return false
+	}
+	if (call.Limit == nil) != (recorded.Limit == nil) || (call.Limit != nil && *call.Limit != *recorded.Limit) {
+		return false
+	}
+	return call.Slug == recorded.Slug && call.Sort == recorded.Sort
+}
+
+// WriteFile saves calls to path as indented JSON, to be checked in as a
+// golden file
+func WriteFile(path string, calls []Call) error {
+	data, err := json.MarshalIndent(calls, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// ReadFile loads the calls WriteFile saved
+func ReadFile(path string) ([]Call, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var calls []Call
+	if err := json.Unmarshal(data, &calls); err != nil {
+		return nil, err
+	}
+	return calls, nil
+}
@@ -0,0 +1,135 @@
+package replay
+
+import (
+	"sync"
+
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/feeds"
+	"github.com/steve-kaufman/postsService/interfaces"
+	"github.com/steve-kaufman/postsService/ranking"
+)
+
+// Backend is the repository a Recorder records calls to
+type Backend interface {
+	interfaces.Repository
+	interfaces.TxRunner
+}
+
+// Recorder passes every call through to its backend and records it with
+// its result. Calls made inside WithinTx are recorded inside the
+// transaction's own call.
+type Recorder struct {
+	recording
+	runner interfaces.TxRunner
+}
+
+// recording records calls to the repository a transaction runs against
+type recording struct {
+	backend interfaces.Repository
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+func NewRecorder(backend Backend) *Recorder {
+	return &Recorder{recording{backend: backend, calls: []Call{}}, backend}
+}
+
+// Calls returns what has been recorded so far, in the order the calls
+// returned
+func (repo *Recorder) Calls() []Call {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	return append([]Call{}, repo.calls...)
+}
+
+func (repo *Recorder) WithinTx(fn func(repo interfaces.Repository) error) error {
+	var tx *recording
+	err := repo.runner.WithinTx(func(inner interfaces.Repository) error {
+		// a backend that retries the transaction runs fn again, and only
+		// the last run is kept
+		tx = &recording{backend: inner, calls: []Call{}}
+		return fn(tx)
+	})
+
+	call := Call{Method: "WithinTx", Err: errorMessage(err)}
+	if tx != nil {
+		calls := tx.calls
+		call.Tx = &calls
+	}
+	repo.record(call)
+	return err
+}
+
+// GetSortedPosts is recorded as one call, however the backend sorts
+func (repo *Recorder) GetSortedPosts(by ranking.Sort) ([]entities.Post, error) {
+	posts, err := interfaces.SortedPosts(repo.backend, repo.backend, by)
+	repo.record(Call{Method: "GetSortedPosts", Sort: by, Posts: append([]entities.Post{}, posts...), Err: errorMessage(err)})
+	return posts, err
+}
+
+func (repo *Recorder) GetPostBySlug(slug string) (entities.Post, error) {
+	post, err := interfaces.PostBySlug(repo.backend, slug)
+	call := Call{Method: "GetPostBySlug", Slug: slug, Err: errorMessage(err)}
+	if err == nil {
+		call.Post = &post
+	}
+	repo.record(call)
+	return post, err
+}
+
+func (repo *Recorder) GetChangesSince(seq int64, limit int) ([]entities.Change, error) {
+	changes, err := interfaces.ChangesSince(repo.backend, seq, limit)
+	repo.record(Call{Method: "GetChangesSince", Seq: &seq, Limit: &limit, Changes: append([]entities.Change{}, changes...), Err: errorMessage(err)})
+	return changes, err
+}
+
+func (repo *Recorder) RecentPosts(limit int) ([]feeds.Entry, error) {
+	entries, err := interfaces.RecentPosts(repo.backend, limit)
+	repo.record(Call{Method: "RecentPosts", Limit: &limit, Entries: append([]feeds.Entry{}, entries...), Err: errorMessage(err)})
+	return entries, err
+}
+
+func (repo *recording) GetPosts() ([]entities.Post, error) {
+	posts, err := repo.backend.GetPosts()
+	repo.record(Call{Method: "GetPosts", Posts: append([]entities.Post{}, posts...), Err: errorMessage(err)})
+	return posts, err
+}
+
+func (repo *recording) GetPost(id int) (entities.Post, error) {
+	post, err := repo.backend.GetPost(id)
+	call := Call{Method: "GetPost", ID: &id, Err: errorMessage(err)}
+	if err == nil {
+		call.Post = &post
+	}
+	repo.record(call)
+	return post, err
+}
+
+func (repo *recording) SavePost(post entities.Post) (entities.Post, error) {
+	saved, err := repo.backend.SavePost(post)
+	call := Call{Method: "SavePost", Arg: &post, Err: errorMessage(err)}
+	if err == nil {
+		call.Post = &saved
+	}
+	repo.record(call)
+	return saved, err
+}
+
+func (repo *recording) DeletePost(id int) error {
+	err := repo.backend.DeletePost(id)
+	repo.record(Call{Method: "DeletePost", ID: &id, Err: errorMessage(err)})
+	return err
+}
+
+func (repo *recording) UpdatePost(id int, data entities.Post) error {
+	err := repo.backend.UpdatePost(id, data)
+	repo.record(Call{Method: "UpdatePost", ID: &id, Arg: &data, Err: errorMessage(err)})
+	return err
+}
+
+func (repo *recording) record(call Call) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.calls = append(repo.calls, call)
+}
@@ -0,0 +1,119 @@
+package replay_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/steve-kaufman/postsService/db"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/events"
+	"github.com/steve-kaufman/postsService/interfaces"
+	"github.com/steve-kaufman/postsService/memory"
+	"github.com/steve-kaufman/postsService/render"
+	"github.com/steve-kaufman/postsService/replay"
+	"github.com/steve-kaufman/postsService/repotest"
+	"github.com/steve-kaufman/postsService/useCases"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+const sessionFile = "testdata/session.json"
+
+var examplePosts = []entities.Post{
+	{ID: 1, Title: "Foo", Content: "Hello", Likes: 2},
+	{ID: 2, Title: "Bar", Content: "World", Dislikes: 1},
+}
+
+type result struct {
+	Post  entities.Post
+	Posts []entities.Post
+	Err   string
+}
+
+// session runs a few use cases against repo and returns what they gave
+func session(repo interface {
+	interfaces.Repository
+	interfaces.TxRunner
+}) []result {
+	record := func(post entities.Post, err error) result {
+		if err != nil {
+			return result{Err: err.Error()}
+		}
+		return result{Post: post}
+	}
+	posts, err := useCases.GetAllPosts(repo, render.Skip, "")
+	results := []result{{Posts: posts}}
+	if err != nil {
+		results[0] = result{Err: err.Error()}
+	}
+	results = append(results,
+		record(useCases.GetOnePost(repo, render.Skip, 1)),
+		record(useCases.LikePost(repo, new(events.Recorder), render.Skip, 2)),
+		record(useCases.UpdatePost(repo, new(events.Recorder), render.Skip, 1, entities.Post{Title: "Foo!"})),
+		record(useCases.DeletePost(repo, new(events.Recorder), 9)),
+	)
+	return results
+}
+
+func TestRecorder_MatchesTheGoldenFile(t *testing.T) {
+	recorder := replay.NewRecorder(db.NewGoodRepository(append([]entities.Post{}, examplePosts...)))
+
+	session(recorder)
+
+	if *update {
+		if err := replay.WriteFile(sessionFile, recorder.Calls()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	golden, err := replay.ReadFile(sessionFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(golden, recorder.Calls()); diff != "" {
+		t.Fatalf("Expected the recording to match %s (run with -update to rewrite it): \n%s", sessionFile, diff)
+	}
+}
+
+func TestReplay_GivesTheRecordedSessionsResults(t *testing.T) {
+	recorder := replay.NewRecorder(db.NewGoodRepository(append([]entities.Post{}, examplePosts...)))
+	expected := session(recorder)
+	path := filepath.Join(t.TempDir(), "session.json")
+	if err := replay.WriteFile(path, recorder.Calls()); err != nil {
+		t.Fatal(err)
+	}
+
+	calls, err := replay.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := replay.NewRepository(calls)
+	results := session(repo)
+
+	if diff := cmp.Diff(expected, results); diff != "" {
+		t.Fatalf("Expected the replay to give the recorded results: \n%s", diff)
+	}
+	if err := repo.Done(); err != nil {
+		t.Fatalf("Expected every recorded call to be replayed; Got: '%v'", err)
+	}
+}
+
+func TestReadFile_ReturnsErrors(t *testing.T) {
+	if _, err := replay.ReadFile(filepath.Join(t.TempDir(), "missing.json")); !os.IsNotExist(err) {
+		t.Fatalf("Expected a not-exist error; Got: '%v'", err)
+	}
+}
+
+func TestRecorder_Conformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repository {
+		return replay.NewRecorder(memory.NewRepository())
+	})
+}
+
+func TestRecorder_Forwarding(t *testing.T) {
+	repotest.RunForwarding(t, func(t *testing.T, backend repotest.Repository) repotest.Repository {
+		return replay.NewRecorder(backend)
+	})
+}
@@ -0,0 +1,193 @@
+package replay
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/feeds"
+	"github.com/steve-kaufman/postsService/interfaces"
+	"github.com/steve-kaufman/postsService/ranking"
+)
+
+var ErrUnexpectedCall = errors.New("unexpected repository call")
+var ErrCallsNotMade = errors.New("recorded calls were not made")
+
+// Repository serves recorded calls back in order. A call that isn't the
+// next one recorded, with the same method and arguments, fails with
+// ErrUnexpectedCall and doesn't use up the recording.
+type Repository struct {
+	mu    sync.Mutex
+	calls []Call
+	next  int
+}
+
+func NewRepository(calls []Call) *Repository {
+	return &Repository{calls: calls}
+}
+
+// Done returns ErrCallsNotMade if calls were recorded that haven't been
+// replayed
+func (repo *Repository) Done() error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if left := len(repo.calls) - repo.next; left > 0 {
+		return fmt.Errorf("%w: %d left, the next %s", ErrCallsNotMade, left, describe(repo.calls[repo.next]))
+	}
+	return nil
+}
+
+// WithinTx runs fn against the calls recorded inside the transaction and
+// returns what the transaction returned, unless fn strayed from them
+func (repo *Repository) WithinTx(fn func(repo interfaces.Repository) error) error {
+	recorded, err := repo.expect(Call{Method: "WithinTx"})
+	if err != nil {
+		return err
+	}
+	if recorded.Tx == nil {
+		return recorded.err()
+	}
+	tx := NewRepository(*recorded.Tx)
+	if err := fn(tx); errors.Is(err, ErrUnexpectedCall) {
+		return err
+	}
+	if err := tx.Done(); err != nil {
+		return err
+	}
+	return recorded.err()
+}
+
+func (repo *Repository) GetPosts() ([]entities.Post, error) {
+	recorded, err := repo.expect(Call{Method: "GetPosts"})
+	if err != nil {
+		return nil, err
+	}
+	if err := recorded.err(); err != nil {
+		return nil, err
+	}
+	return append([]entities.Post{}, recorded.Posts...), nil
+}
+
+func (repo *Repository) GetPost(id int) (entities.Post, error) {
+	recorded, err := repo.expect(Call{Method: "GetPost", ID: &id})
+	if err != nil {
+		return entities.Post{}, err
+	}
+	if err := recorded.err(); err != nil || recorded.Post == nil {
+		return entities.Post{}, err
+	}
+	return *recorded.Post, nil
+}
+
+func (repo *Repository) SavePost(post entities.Post) (entities.Post, error) {
+	recorded, err := repo.expect(Call{Method: "SavePost", Arg: &post})
+	if err != nil {
+		return entities.Post{}, err
+	}
+	if err := recorded.err(); err != nil || recorded.Post == nil {
+		return entities.Post{}, err
+	}
+	return *recorded.Post, nil
+}
+
+func (repo *Repository) DeletePost(id int) error {
+	recorded, err := repo.expect(Call{Method: "DeletePost", ID: &id})
+	if err != nil {
+		return err
+	}
+	return recorded.err()
+}
+
+func (repo *Repository) UpdatePost(id int, data entities.Post) error {
+	recorded, err := repo.expect(Call{Method: "UpdatePost", ID: &id, Arg: &data})
+	if err != nil {
+		return err
+	}
+	return recorded.err()
+}
+
+func (repo *Repository) GetSortedPosts(by ranking.Sort) ([]entities.Post, error) {
+	recorded, err := repo.expect(Call{Method: "GetSortedPosts", Sort: by})
+	if err != nil {
+		return nil, err
+	}
+	if err := recorded.err(); err != nil {
+		return nil, err
+	}
+	return append([]entities.Post{}, recorded.Posts...), nil
+}
+
+func (repo *Repository) GetPostBySlug(slug string) (entities.Post, error) {
+	recorded, err := repo.expect(Call{Method: "GetPostBySlug", Slug: slug})
+	if err != nil {
+		return entities.Post{}, err
+	}
+	if err := recorded.err(); err != nil || recorded.Post == nil {
+		return entities.Post{}, err
+	}
+	return *recorded.Post, nil
+}
+
+func (repo *Repository) GetChangesSince(seq int64, limit int) ([]entities.Change, error) {
+	recorded, err := repo.expect(Call{Method: "GetChangesSince", Seq: &seq, Limit: &limit})
+	if err != nil {
+		return nil, err
+	}
+	if err := recorded.err(); err != nil {
+		return nil, err
+	}
+	return append([]entities.Change{}, recorded.Changes...), nil
+}
+
+func (repo *Repository) RecentPosts(limit int) ([]feeds.Entry, error) {
+	recorded, err := repo.expect(Call{Method: "RecentPosts", Limit: &limit})
+	if err != nil {
+		return nil, err
+	}
+	if err := recorded.err(); err != nil {
+		return nil, err
+	}
+	return append([]feeds.Entry{}, recorded.Entries...), nil
+}
+
+// expect returns the next recorded call if call matches it
+func (repo *Repository) expect(call Call) (Call, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if repo.next == len(repo.calls) {
+		return Call{}, fmt.Errorf("%w: %s after the last recorded call", ErrUnexpectedCall, describe(call))
+	}
+	recorded := repo.calls[repo.next]
+	if !call.matches(recorded) {
+		return Call{}, fmt.Errorf("%w: %s, recorded %s", ErrUnexpectedCall, describe(call), describe(recorded))
+	}
+	repo.next++
+	return recorded, nil
+}
+
+// describe writes a call the way it was made, such as GetPost(1)
+func describe(call Call) string {
+	args := []string{}
+	if call.ID != nil {
+		args = append(args, strconv.Itoa(*call.ID))
+	}
+	if call.Slug != "" {
+		args = append(args, strconv.Quote(call.Slug))
+	}
+	if call.Sort != "" {
+		args = append(args, strconv.Quote(string(call.Sort)))
+	}
+	if call.Seq != nil {
+		args = append(args, strconv.FormatInt(*call.Seq, 10))
+	}
+	if call.Limit != nil {
+		args = append(args, strconv.Itoa(*call.Limit))
+	}
+	if call.Arg != nil {
+		args = append(args, fmt.Sprintf("%+v", *call.Arg))
+	}
+	return call.Method + "(" + strings.Join(args, ", ") + ")"
+}
@@ -0,0 +1,173 @@
+package replay_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/steve-kaufman/postsService/db"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/feeds"
+	"github.com/steve-kaufman/postsService/interfaces"
+	"github.com/steve-kaufman/postsService/ranking"
+	"github.com/steve-kaufman/postsService/replay"
+	"github.com/steve-kaufman/postsService/useCases"
+)
+
+func record(fn func(repo *replay.Recorder)) []replay.Call {
+	recorder := replay.NewRecorder(db.NewGoodRepository(append([]entities.Post{}, examplePosts...)))
+	fn(recorder)
+	return recorder.Calls()
+}
+
+func TestReplay_FailsOnUnexpectedCalls(t *testing.T) {
+	repo := replay.NewRepository(record(func(repo *replay.Recorder) {
+		repo.GetPost(1)
+	}))
+
+	if _, err := repo.GetPost(2); !errors.Is(err, replay.ErrUnexpectedCall) {
+		t.Fatalf("Expected a call with another ID to be unexpected; Got: '%v'", err)
+	}
+	if err := repo.DeletePost(1); !errors.Is(err, replay.ErrUnexpectedCall) {
+		t.Fatalf("Expected a call to another method to be unexpected; Got: '%v'", err)
+	}
+	if post, err := repo.GetPost(1); err != nil || post.Title != "Foo" {
+		t.Fatalf("Expected the recorded post; Got: '%v', '%v'", post, err)
+	}
+	if _, err := repo.GetPost(1); !errors.Is(err, replay.ErrUnexpectedCall) {
+		t.Fatalf("Expected a call past the recording to be unexpected; Got: '%v'", err)
+	}
+}
+
+func TestReplay_ComparesArguments(t *testing.T) {
+	repo := replay.NewRepository(record(func(repo *replay.Recorder) {
+		repo.SavePost(entities.Post{ID: 3, Title: "Baz"})
+	}))
+
+	if _, err := repo.SavePost(entities.Post{ID: 3, Title: "Qux"}); !errors.Is(err, replay.ErrUnexpectedCall) {
+		t.Fatalf("Expected a different post to be unexpected; Got: '%v'", err)
+	}
+	if _, err := repo.SavePost(entities.Post{ID: 3, Title: "Baz"}); err != nil {
+		t.Fatalf("Expected the recorded save; Got: '%v'", err)
+	}
+}
+
+func TestReplay_ReportsCallsNotMade(t *testing.T) {
+	repo := replay.NewRepository(record(func(repo *replay.Recorder) {
+		repo.GetPosts()
+		repo.DeletePost(2)
+	}))
+
+	repo.GetPosts()
+
+	if err := repo.Done(); !errors.Is(err, replay.ErrCallsNotMade) {
+		t.Fatalf("Expected ErrCallsNotMade; Got: '%v'", err)
+	}
+}
+
+func TestReplay_ReturnsRecordedErrors(t *testing.T) {
+	calls := append(record(func(repo *replay.Recorder) {
+		repo.GetPost(9)
+	}), replay.Call{Method: "GetPosts", Err: "disk I/O error"})
+	repo := replay.NewRepository(calls)
+
+	if _, err := repo.GetPost(9); err != useCases.ErrNotFound {
+		t.Fatalf("Expected ErrNotFound itself; Got: '%v'", err)
+	}
+	if _, err := repo.GetPosts(); err == nil || err.Error() != "disk I/O error" {
+		t.Fatalf("Expected the recorded error message; Got: '%v'", err)
+	}
+}
+
+func TestReplay_StrayingInsideATransactionFailsIt(t *testing.T) {
+	repo := replay.NewRepository(record(func(repo *replay.Recorder) {
+		repo.WithinTx(func(tx interfaces.Repository) error {
+			_, err := tx.GetPost(1)
+			return err
+		})
+	}))
+
+	err := repo.WithinTx(func(tx interfaces.Repository) error {
+		tx.GetPost(1)
+		return tx.DeletePost(1)
+	})
+
+	if !errors.Is(err, replay.ErrUnexpectedCall) {
+		t.Fatalf("Expected the extra call to fail the transaction; Got: '%v'", err)
+	}
+}
+
+func TestReplay_DoesNotRunFnIfTheTransactionNeverBegan(t *testing.T) {
+	recorder := replay.NewRecorder(new(db.BadRepository))
+	recorder.WithinTx(func(tx interfaces.Repository) error {
+		return nil
+	})
+	repo := replay.NewRepository(recorder.Calls())
+
+	ran := false
+	err := repo.WithinTx(func(tx interfaces.Repository) error {
+		ran = true
+		return nil
+	})
+
+	if ran || err == nil || err.Error() != db.ErrBad.Error() {
+		t.Fatalf("Expected the recorded failure without running fn; Got: '%v', ran: %v", err, ran)
+	}
+}
+
+func TestReplay_ServesOptionalCalls(t *testing.T) {
+	type results struct {
+		Sorted     []entities.Post
+		SlugErr    error
+		Changes    []entities.Change
+		EntriesErr error
+	}
+	run := func(repo interface {
+		interfaces.SortedPostsGetter
+		interfaces.SlugGetter
+		interfaces.ChangesGetter
+		feeds.Source
+	}) results {
+		sorted, _ := repo.GetSortedPosts(ranking.SortTop)
+		_, slugErr := repo.GetPostBySlug("foo")
+		changes, _ := repo.GetChangesSince(1, 10)
+		_, entriesErr := repo.RecentPosts(5)
+		return results{sorted, slugErr, changes, entriesErr}
+	}
+	var recorded results
+	calls := record(func(repo *replay.Recorder) {
+		recorded = run(repo)
+	})
+	path := filepath.Join(t.TempDir(), "calls.json")
+	if err := replay.WriteFile(path, calls); err != nil {
+		t.Fatalf("Expected no error writing calls; Got: '%v'", err)
+	}
+	calls, err := replay.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected no error reading calls; Got: '%v'", err)
+	}
+
+	replayed := run(replay.NewRepository(calls))
+
+	if !errors.Is(replayed.SlugErr, useCases.ErrNotFound) || !errors.Is(replayed.EntriesErr, errors.ErrUnsupported) {
+		t.Fatalf("Expected the recorded errors; Got: '%v', '%v'", replayed.SlugErr, replayed.EntriesErr)
+	}
+	if diff := cmp.Diff(recorded, replayed, cmpopts.EquateErrors()); diff != "" {
+		t.Fatalf("Expected the recorded results: \n%s", diff)
+	}
+}
+
+func TestReplay_ComparesOptionalArguments(t *testing.T) {
+	repo := replay.NewRepository(record(func(repo *replay.Recorder) {
+		repo.GetChangesSince(1, 10)
+	}))
+
+	if _, err := repo.GetChangesSince(1, 20); !errors.Is(err, replay.ErrUnexpectedCall) {
+		t.Fatalf("Expected a different limit to be unexpected; Got: '%v'", err)
+	}
+	if _, err := repo.GetChangesSince(1, 10); err != nil {
+		t.Fatalf("Expected the recorded call; Got: '%v'", err)
+	}
+}
@@ -0,0 +1,114 @@
+package repotest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/feeds"
+	"github.com/steve-kaufman/postsService/interfaces"
+	"github.com/steve-kaufman/postsService/memory"
+	"github.com/steve-kaufman/postsService/ranking"
+)
+
+// Decorator wraps backend in the repository under test
+type Decorator func(t *testing.T, backend Repository) Repository
+
+// Wrapped is every optional interface a decorator passes on to its backend
+type Wrapped interface {
+	Repository
+	interfaces.SortedPostsGetter
+	interfaces.SlugGetter
+	interfaces.ChangesGetter
+	feeds.Source
+}
+
+// forwardedPost is what optionalBackend answers with, which a decorator
+// couldn't come up with by itself
+var forwardedPost = entities.Post{ID: 42, Slug: "forwarded", Title: "Forwarded"}
+
+// optionalBackend implements every optional interface
+type optionalBackend struct {
+	Repository
+}
+
+func (optionalBackend) GetSortedPosts(by ranking.Sort) ([]entities.Post, error) {
+	return []entities.Post{forwardedPost}, nil
+}
+
+func (optionalBackend) GetPostBySlug(slug string) (entities.Post, error) {
+	return forwardedPost, nil
+}
+
+func (optionalBackend) GetChangesSince(seq int64, limit int) ([]entities.Change, error) {
+	return []entities.Change{{Seq: seq + 1, Post: forwardedPost}}, nil
+}
+
+func (optionalBackend) RecentPosts(limit int) ([]feeds.Entry, error) {
+	return []feeds.Entry{{Post: forwardedPost}}, nil
+}
+
+// RunForwarding checks that repositories made by decorate pass the
+// optional interfaces on to backends that have them, and that without
+// them GetSortedPosts sorts in memory and the rest are unsupported
+func RunForwarding(t *testing.T, decorate Decorator) {
+	t.Run("Forwards to a backend that has them", func(t *testing.T) {
+		repo := wrapped(t, decorate(t, optionalBackend{memory.NewRepository()}))
+
+		sorted, err := repo.GetSortedPosts(ranking.SortTop)
+		expectForwarded(t, "GetSortedPosts", []entities.Post{forwardedPost}, sorted, err)
+		post, err := repo.GetPostBySlug("forwarded")
+		expectForwarded(t, "GetPostBySlug", forwardedPost, post, err)
+		changes, err := repo.GetChangesSince(1, 10)
+		expectForwarded(t, "GetChangesSince", []entities.Change{{Seq: 2, Post: forwardedPost}}, changes, err)
+		entries, err := repo.RecentPosts(10)
+		expectForwarded(t, "RecentPosts", []feeds.Entry{{Post: forwardedPost}}, entries, err)
+	})
+
+	t.Run("Sorts in memory for a backend that can't", func(t *testing.T) {
+		repo := wrapped(t, decorate(t, memory.NewRepository(ExamplePosts...)))
+
+		expected := append([]entities.Post{}, ExamplePosts...)
+		ranking.SortPosts(expected, ranking.SortTop, nil)
+		sorted, err := repo.GetSortedPosts(ranking.SortTop)
+		expectForwarded(t, "GetSortedPosts", expected, sorted, err)
+	})
+
+	t.Run("Reports the rest unsupported by a backend without them", func(t *testing.T) {
+		repo := wrapped(t, decorate(t, memory.NewRepository(ExamplePosts...)))
+
+		_, err := repo.GetPostBySlug("post-1")
+		expectUnsupported(t, "GetPostBySlug", err)
+		_, err = repo.GetChangesSince(0, 10)
+		expectUnsupported(t, "GetChangesSince", err)
+		_, err = repo.RecentPosts(10)
+		expectUnsupported(t, "RecentPosts", err)
+	})
+}
+
+func wrapped(t *testing.T, repo Repository) Wrapped {
+	t.Helper()
+	full, ok := repo.(Wrapped)
+	if !ok {
+		t.Fatalf("Expected %T to implement every optional interface", repo)
+	}
+	return full
+}
+
+func expectForwarded(t *testing.T, method string, expected, got interface{}, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s: Expected no error; Got: '%v'", method, err)
+	}
+	if diff := cmp.Diff(expected, got, ignoreSlug); diff != "" {
+		t.Fatalf("%s: Expected the backend's answer: \n%s", method, diff)
+	}
+}
+
+func expectUnsupported(t *testing.T, method string, err error) {
+	t.Helper()
+	if !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("%s: Expected errors.ErrUnsupported; Got: '%v'", method, err)
+	}
+}
@@ -0,0 +1,300 @@
+// Package repotest is a conformance suite for implementations of the
+// repository interfaces. Every storage backend, and every decorator around
+// one, should pass Run.
+package repotest
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/interfaces"
+	"github.com/steve-kaufman/postsService/useCases"
+)
+
+type Repository interface {
+	interfaces.Repository
+	interfaces.TxRunner
+}
+
+// Factory returns a new, empty repository. It is called once per subtest.
+type Factory func(t *testing.T) Repository
+
+var ExamplePosts = []entities.Post{
+	{
+		ID:       1,
+		Title:    "Post 1",
+		Content:  "Content of Post 1",
+		Likes:    2,
+		Dislikes: 1,
+	},
+	{
+		ID:       2,
+		Title:    "Post 2",
+		Content:  "Content of Post 2",
+		Likes:    5,
+		Dislikes: 2,
+	},
+	{
+		ID:       3,
+		Title:    "Post 3",
+		Content:  "Content of Post 3",
+		Likes:    0,
+		Dislikes: 10,
+	},
+}
+
+var badIDs = []int{-10, -1, 0, 4, 5, 100}
+
+// ignoreSlug leaves slugs out of comparisons; only some repositories
+// generate them
+var ignoreSlug = cmpopts.IgnoreFields(entities.Post{}, "Slug")
+
+// Run runs the whole suite against repositories created by factory
+func Run(t *testing.T, factory Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, repo Repository)
+	}{
+		{"GetPosts returns nothing from empty repo", testGetPostsEmpty},
+		{"SavePost assigns sequential IDs and returns them", testSaveAssignsIDs},
+		{"SavePost ignores input ID", testSaveIgnoresInputID},
+		{"SavePost after delete assigns a fresh ID", testSaveAfterDelete},
+		{"GetPosts returns posts in ID order", testGetPostsOrder},
+		{"GetPost returns ErrNotFound for bad IDs", testGetPostNotFound},
+		{"GetPost returns correct post", testGetPost},
+		{"DeletePost removes only that post", testDeleteIsolation},
+		{"DeletePost returns ErrNotFound for bad IDs", testDeleteNotFound},
+		{"DeletePost returns ErrNotFound when already deleted", testDeleteTwice},
+		{"UpdatePost changes only that post", testUpdateIsolation},
+		{"UpdatePost keeps the ID it was given", testUpdateKeepsID},
+		{"UpdatePost returns ErrNotFound for bad IDs", testUpdateNotFound},
+		{"WithinTx commits when fn succeeds", testTxCommit},
+		{"WithinTx rolls back when fn fails", testTxRollback},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.test(t, factory(t))
+		})
+	}
+}
+
+func seed(t *testing.T, repo Repository) {
+	t.Helper()
+	for _, post := range ExamplePosts {
+		if _, err := repo.SavePost(post); err != nil {
+			t.Fatalf("Expected no error seeding posts; Got: '%v'", err)
+		}
+	}
+}
+
+func expectPosts(t *testing.T, repo Repository, expected []entities.Post) {
+	t.Helper()
+	posts, err := repo.GetPosts()
+	if err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	if diff := cmp.Diff(expected, posts, ignoreSlug); diff != "" {
+		t.Fatalf("Expected posts to match: \n%s", diff)
+	}
+}
+
+func testGetPostsEmpty(t *testing.T, repo Repository) {
+	posts, err := repo.GetPosts()
+
+	if err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	if len(posts) != 0 {
+		t.Fatalf("Expected no posts; Got: '%v'", posts)
+	}
+}
+
+func testSaveAssignsIDs(t *testing.T, repo Repository) {
+	for _, expected := range ExamplePosts {
+		post := expected
+		post.ID = 0
+		saved, err := repo.SavePost(post)
+		if err != nil {
+			t.Fatalf("Expected no error; Got: '%v'", err)
+		}
+		if diff := cmp.Diff(expected, saved, ignoreSlug); diff != "" {
+			t.Fatalf("Expected the saved post back with its ID: \n%s", diff)
+		}
+	}
+
+	expectPosts(t, repo, ExamplePosts)
+}
+
+func testSaveIgnoresInputID(t *testing.T, repo Repository) {
+	seed(t, repo)
+
+	if _, err := repo.SavePost(entities.Post{ID: 2, Title: "Foo"}); err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+
+	expectPosts(t, repo, append(ExamplePosts[:3:3], entities.Post{ID: 4, Title: "Foo"}))
+}
+
+// testSaveAfterDelete deletes the newest post, whose ID a repository that
+// counts from its highest row would hand out again
+func testSaveAfterDelete(t *testing.T, repo Repository) {
+	seed(t, repo)
+
+	if err := repo.DeletePost(3); err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	if _, err := repo.SavePost(entities.Post{Title: "Foo"}); err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+
+	expectPosts(t, repo, []entities.Post{ExamplePosts[0], ExamplePosts[1], {ID: 4, Title: "Foo"}})
+}
+
+func testGetPostsOrder(t *testing.T, repo Repository) {
+	seed(t, repo)
+
+	expectPosts(t, repo, ExamplePosts)
+}
+
+func testGetPostNotFound(t *testing.T, repo Repository) {
+	seed(t, repo)
+
+	for _, id := range badIDs {
+		t.Run(fmt.Sprint(id), func(t *testing.T) {
+			post, err := repo.GetPost(id)
+
+			if !errors.Is(err, useCases.ErrNotFound) {
+				t.Fatalf("Expected ErrNotFound; Got: '%v'", err)
+			}
+			if (post != entities.Post{}) {
+				t.Fatalf("Expected empty post; Got: '%v'", post)
+			}
+		})
+	}
+}
+
+func testGetPost(t *testing.T, repo Repository) {
+	seed(t, repo)
+
+	for _, expected := range ExamplePosts {
+		post, err := repo.GetPost(expected.ID)
+
+		if err != nil {
+			t.Fatalf("Expected no error; Got: '%v'", err)
+		}
+		if diff := cmp.Diff(expected, post, ignoreSlug); diff != "" {
+			t.Fatalf("Expected post %d: \n%s", expected.ID, diff)
+		}
+	}
+}
+
+func testDeleteIsolation(t *testing.T, repo Repository) {
+	seed(t, repo)
+
+	if err := repo.DeletePost(2); err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+
+	if _, err := repo.GetPost(2); !errors.Is(err, useCases.ErrNotFound) {
+		t.Fatalf("Expected deleted post to not be found; Got: '%v'", err)
+	}
+	expectPosts(t, repo, []entities.Post{ExamplePosts[0], ExamplePosts[2]})
+}
+
+func testDeleteNotFound(t *testing.T, repo Repository) {
+	seed(t, repo)
+
+	for _, id := range badIDs {
+		t.Run(fmt.Sprint(id), func(t *testing.T) {
+			if err := repo.DeletePost(id); !errors.Is(err, useCases.ErrNotFound) {
+				t.Fatalf("Expected ErrNotFound; Got: '%v'", err)
+			}
+		})
+	}
+	expectPosts(t, repo, ExamplePosts)
+}
+
+func testDeleteTwice(t *testing.T, repo Repository) {
+	seed(t, repo)
+
+	if err := repo.DeletePost(1); err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	if err := repo.DeletePost(1); !errors.Is(err, useCases.ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound; Got: '%v'", err)
+	}
+}
+
+func testUpdateIsolation(t *testing.T, repo Repository) {
+	seed(t, repo)
+
+	updated := entities.Post{ID: 2, Title: "Foo", Content: "Bar", Likes: 7, Dislikes: 8}
+	if err := repo.UpdatePost(2, updated); err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+
+	expectPosts(t, repo, []entities.Post{ExamplePosts[0], updated, ExamplePosts[2]})
+}
+
+func testUpdateKeepsID(t *testing.T, repo Repository) {
+	seed(t, repo)
+
+	if err := repo.UpdatePost(2, entities.Post{ID: 3, Title: "Foo"}); err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+
+	expectPosts(t, repo, []entities.Post{ExamplePosts[0], {ID: 2, Title: "Foo"}, ExamplePosts[2]})
+}
+
+func testUpdateNotFound(t *testing.T, repo Repository) {
+	seed(t, repo)
+
+	for _, id := range badIDs {
+		t.Run(fmt.Sprint(id), func(t *testing.T) {
+			err := repo.UpdatePost(id, entities.Post{Title: "Foo"})
+
+			if !errors.Is(err, useCases.ErrNotFound) {
+				t.Fatalf("Expected ErrNotFound; Got: '%v'", err)
+			}
+		})
+	}
+	expectPosts(t, repo, ExamplePosts)
+}
+
+func testTxCommit(t *testing.T, repo Repository) {
+	seed(t, repo)
+
+	err := repo.WithinTx(func(tx interfaces.Repository) error {
+		if err := tx.DeletePost(1); err != nil {
+			return err
+		}
+		return tx.UpdatePost(2, entities.Post{Title: "Foo"})
+	})
+
+	if err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	expectPosts(t, repo, []entities.Post{{ID: 2, Title: "Foo"}, ExamplePosts[2]})
+}
+
+func testTxRollback(t *testing.T, repo Repository) {
+	seed(t, repo)
+
+	errFn := errors.New("fn failed")
+	err := repo.WithinTx(func(tx interfaces.Repository) error {
+		tx.DeletePost(1)
+		tx.UpdatePost(2, entities.Post{Title: "Foo"})
+		tx.SavePost(entities.Post{Title: "Bar"})
+		return errFn
+	})
+
+	if !errors.Is(err, errFn) {
+		t.Fatalf("Expected error from fn; Got: '%v'", err)
+	}
+	expectPosts(t, repo, ExamplePosts)
+}
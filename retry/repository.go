@@ -0,0 +1,217 @@
+// Package retry retries repository calls that fail for a moment, such as
+// SQLite being busy with another writer, and bounds how long they keep
+// being retried.
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/steve-kaufman/postsService/backoff"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/feeds"
+	"github.com/steve-kaufman/postsService/interfaces"
+	"github.com/steve-kaufman/postsService/metrics"
+	"github.com/steve-kaufman/postsService/ranking"
+)
+
+// ErrTimeout is wrapped around the transient error of a call whose last
+// attempt ended past the deadline
+var ErrTimeout = errors.New("repository call timed out")
+
+// Backend is the repository a Repository retries calls to
+type Backend interface {
+	interfaces.Repository
+	interfaces.TxRunner
+}
+
+// Repository retries calls that fail with an error transient reports as
+// such, waiting a jittered, doubling backoff between attempts. Calls made
+// inside WithinTx go straight to the backend; the transaction is retried
+// as a whole, running fn again.
+//
+// Calls take no context, so an attempt can't be interrupted: Timeout only
+// decides whether to try again. The backend bounds each attempt itself,
+// as db.Options.BusyTimeout does for a SQLite lock, and that bound should
+// be well under Timeout.
+type Repository struct {
+	// MaxAttempts is how many times a call is tried, including the first
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// Timeout bounds how long a call keeps being retried: no wait or
+	// attempt starts that would end past it. Zero means no limit.
+	Timeout time.Duration
+	// Now and Sleep are the repository's clock
+	Now   func() time.Time
+	Sleep func(time.Duration)
+
+	backend   Backend
+	transient func(error) bool
+	retries   *metrics.Counter
+	exhausted *metrics.Counter
+	timeouts  *metrics.Counter
+}
+
+// NewRepository retries the calls to backend that fail with a transient
+// error, counting retries in registry
+func NewRepository(backend Backend, transient func(error) bool, registry *metrics.Registry) *Repository {
+	return &Repository{
+		MaxAttempts: 5,
+		BaseBackoff: 10 * time.Millisecond,
+		MaxBackoff:  time.Second,
+		Timeout:     10 * time.Second,
+		Now:         time.Now,
+		Sleep:       time.Sleep,
+
+		backend:   backend,
+		transient: transient,
+		retries: registry.Counter("posts_repository_retries_total",
+			"Repository calls retried after a transient error.", "method"),
+		exhausted: registry.Counter("posts_repository_retries_exhausted_total",
+			"Repository calls that still failed with a transient error when out of attempts or time.", "method"),
+		timeouts: registry.Counter("posts_repository_timeouts_total",
+			"Repository calls whose last attempt failed with a transient error past the deadline.", "method"),
+	}
+}
+
+func (repo *Repository) WithinTx(fn func(repo interfaces.Repository) error) error {
+	return repo.do("WithinTx", func() error {
+		return repo.backend.WithinTx(fn)
+	})
+}
+
+func (repo *Repository) GetPosts() ([]entities.Post, error) {
+	var posts []entities.Post
+	err := repo.do("GetPosts", func() (err error) {
+		posts, err = repo.backend.GetPosts()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+func (repo *Repository) GetPost(id int) (entities.Post, error) {
+	var post entities.Post
+	err := repo.do("GetPost", func() (err error) {
+		post, err = repo.backend.GetPost(id)
+		return err
+	})
+	if err != nil {
+		return entities.Post{}, err
+	}
+	return post, nil
+}
+
+func (repo *Repository) SavePost(post entities.Post) (entities.Post, error) {
+	var saved entities.Post
+	err := repo.do("SavePost", func() (err error) {
+		saved, err = repo.backend.SavePost(post)
+		return err
+	})
+	if err != nil {
+		return entities.Post{}, err
+	}
+	return saved, nil
+}
+
+func (repo *Repository) DeletePost(id int) error {
+	return repo.do("DeletePost", func() error {
+		return repo.backend.DeletePost(id)
+	})
+}
+
+func (repo *Repository) UpdatePost(id int, data entities.Post) error {
+	return repo.do("UpdatePost", func() error {
+		return repo.backend.UpdatePost(id, data)
+	})
+}
+
+func (repo *Repository) GetSortedPosts(by ranking.Sort) ([]entities.Post, error) {
+	var posts []entities.Post
+	err := repo.do("GetSortedPosts", func() (err error) {
+		posts, err = interfaces.SortedPosts(repo.backend, repo.backend, by)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+func (repo *Repository) GetPostBySlug(slug string) (entities.Post, error) {
+	var post entities.Post
+	err := repo.do("GetPostBySlug", func() (err error) {
+		post, err = interfaces.PostBySlug(repo.backend, slug)
+		return err
+	})
+	if err != nil {
+		return entities.Post{}, err
+	}
+	return post, nil
+}
+
+func (repo *Repository) GetChangesSince(seq int64, limit int) ([]entities.Change, error) {
+	var changes []entities.Change
+	err := repo.do("GetChangesSince", func() (err error) {
+		changes, err = interfaces.ChangesSince(repo.backend, seq, limit)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+func (repo *Repository) RecentPosts(limit int) ([]feeds.Entry, error) {
+	var entries []feeds.Entry
+	err := repo.do("RecentPosts", func() (err error) {
+		entries, err = interfaces.RecentPosts(repo.backend, limit)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// do tries call until it succeeds, fails for good, or runs out of
+// attempts or time, and returns its last error
+func (repo *Repository) do(method string, call func() error) error {
+	var deadline time.Time
+	if repo.Timeout > 0 {
+		deadline = repo.Now().Add(repo.Timeout)
+	}
+	for attempt := 1; ; attempt++ {
+		err := call()
+		if err == nil || !repo.transient(err) {
+			return err
+		}
+		if !deadline.IsZero() && !repo.Now().Before(deadline) {
+			repo.timeouts.Inc(method)
+			return fmt.Errorf("%w: %w", ErrTimeout, err)
+		}
+		if attempt >= repo.MaxAttempts {
+			repo.exhausted.Inc(method)
+			return err
+		}
+		wait := jitter(backoff.Exponential(repo.BaseBackoff, repo.MaxBackoff, attempt))
+		if !deadline.IsZero() && !repo.Now().Add(wait).Before(deadline) {
+			repo.exhausted.Inc(method)
+			return err
+		}
+		repo.retries.Inc(method)
+		repo.Sleep(wait)
+	}
+}
+
+// jitter picks a wait between half of backoff and all of it, so callers
+// that collided don't all retry at once
+func jitter(backoff time.Duration) time.Duration {
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(backoff-half)+1))
+}
@@ -0,0 +1,249 @@
+package retry_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steve-kaufman/postsService/db"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/interfaces"
+	"github.com/steve-kaufman/postsService/memory"
+	"github.com/steve-kaufman/postsService/metrics"
+	"github.com/steve-kaufman/postsService/repotest"
+	"github.com/steve-kaufman/postsService/retry"
+)
+
+var errBusy = errors.New("database is locked")
+
+func isBusy(err error) bool {
+	return errors.Is(err, errBusy)
+}
+
+// flakyBackend fails each call with the next of failures, then succeeds.
+// Each call runs during, if set.
+type flakyBackend struct {
+	*db.GoodRepository
+	failures []error
+	calls    int
+	during   func()
+}
+
+func (backend *flakyBackend) fail() error {
+	backend.calls++
+	if backend.during != nil {
+		backend.during()
+	}
+	if len(backend.failures) == 0 {
+		return nil
+	}
+	err := backend.failures[0]
+	backend.failures = backend.failures[1:]
+	return err
+}
+
+func (backend *flakyBackend) GetPost(id int) (entities.Post, error) {
+	if err := backend.fail(); err != nil {
+		return entities.Post{}, err
+	}
+	return backend.GoodRepository.GetPost(id)
+}
+
+func (backend *flakyBackend) WithinTx(fn func(repo interfaces.Repository) error) error {
+	if err := backend.fail(); err != nil {
+		return err
+	}
+	return backend.GoodRepository.WithinTx(fn)
+}
+
+type clock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func (c *clock) Sleep(d time.Duration) {
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+}
+
+func setup(failures ...error) (*retry.Repository, *flakyBackend, *clock, *metrics.Registry) {
+	backend := &flakyBackend{
+		GoodRepository: db.NewGoodRepository([]entities.Post{{ID: 1, Title: "Foo"}}),
+		failures:       failures,
+	}
+	registry := metrics.NewRegistry()
+	repo := retry.NewRepository(backend, isBusy, registry)
+	c := &clock{now: time.Unix(0, 0)}
+	repo.Now = c.Now
+	repo.Sleep = c.Sleep
+	repo.BaseBackoff = 100 * time.Millisecond
+	repo.MaxBackoff = 300 * time.Millisecond
+	repo.Timeout = time.Minute
+	return repo, backend, c, registry
+}
+
+func exposition(registry *metrics.Registry) string {
+	var out bytes.Buffer
+	registry.WriteTo(&out)
+	return out.String()
+}
+
+func TestRepository_RetriesTransientErrorsWithJitteredBackoff(t *testing.T) {
+	repo, backend, c, registry := setup(errBusy, errBusy, errBusy)
+
+	post, err := repo.GetPost(1)
+
+	if err != nil || post.Title != "Foo" {
+		t.Fatalf("Expected the post after retrying; Got: '%v', '%v'", post, err)
+	}
+	if backend.calls != 4 {
+		t.Fatalf("Expected 4 attempts; Got: %d", backend.calls)
+	}
+	// doubling from 100ms up to 300ms, each jittered down by at most half
+	backoffs := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond}
+	for i, sleep := range c.sleeps {
+		if sleep < backoffs[i]/2 || sleep > backoffs[i] {
+			t.Fatalf("Expected wait %d between %v and %v; Got: %v", i, backoffs[i]/2, backoffs[i], sleep)
+		}
+	}
+	if len(c.sleeps) != 3 {
+		t.Fatalf("Expected 3 waits; Got: '%v'", c.sleeps)
+	}
+	if !strings.Contains(exposition(registry), `posts_repository_retries_total{method="GetPost"} 3`) {
+		t.Fatalf("Expected 3 retries to be counted; Got:\n%s", exposition(registry))
+	}
+}
+
+func TestRepository_DoesNotRetryPermanentErrors(t *testing.T) {
+	repo, backend, _, _ := setup(db.ErrBad)
+
+	_, err := repo.GetPost(1)
+
+	if err != db.ErrBad || backend.calls != 1 {
+		t.Fatalf("Expected ErrBad from one attempt; Got: '%v' from %d", err, backend.calls)
+	}
+}
+
+func TestRepository_GivesUpAfterMaxAttempts(t *testing.T) {
+	repo, backend, _, registry := setup(errBusy, errBusy, errBusy, errBusy)
+	repo.MaxAttempts = 3
+
+	_, err := repo.GetPost(1)
+
+	if err != errBusy || backend.calls != 3 {
+		t.Fatalf("Expected the transient error after 3 attempts; Got: '%v' after %d", err, backend.calls)
+	}
+	if !strings.Contains(exposition(registry), `posts_repository_retries_exhausted_total{method="GetPost"} 1`) {
+		t.Fatalf("Expected the exhausted retries to be counted; Got:\n%s", exposition(registry))
+	}
+}
+
+func TestRepository_StopsRetryingAtTheDeadline(t *testing.T) {
+	repo, backend, _, _ := setup(errBusy, errBusy, errBusy, errBusy)
+	repo.Timeout = 120 * time.Millisecond
+
+	_, err := repo.GetPost(1)
+
+	// the first wait, of at most 100ms, fits; the second, of at least
+	// 100ms, would end past the deadline
+	if err != errBusy || backend.calls != 2 {
+		t.Fatalf("Expected to give up before the deadline; Got: '%v' after %d attempts", err, backend.calls)
+	}
+}
+
+func TestRepository_RetriesTransactionsAsAWhole(t *testing.T) {
+	repo, backend, _, _ := setup(errBusy)
+
+	runs := 0
+	err := repo.WithinTx(func(tx interfaces.Repository) error {
+		runs++
+		_, err := tx.SavePost(entities.Post{ID: 2, Title: "Bar"})
+		return err
+	})
+
+	if err != nil || runs != 1 || backend.calls != 2 {
+		t.Fatalf("Expected fn to run once on the second attempt; Got: '%v', %d runs, %d attempts", err, runs, backend.calls)
+	}
+}
+
+// slowBackend blocks GetPost, SavePost and WithinTx until release is
+// closed
+type slowBackend struct {
+	*db.GoodRepository
+	release chan struct{}
+}
+
+func (backend slowBackend) GetPost(id int) (entities.Post, error) {
+	<-backend.release
+	return backend.GoodRepository.GetPost(id)
+}
+
+func (backend slowBackend) SavePost(post entities.Post) (entities.Post, error) {
+	<-backend.release
+	return backend.GoodRepository.SavePost(post)
+}
+
+func (backend slowBackend) WithinTx(fn func(repo interfaces.Repository) error) error {
+	<-backend.release
+	return backend.GoodRepository.WithinTx(fn)
+}
+
+func TestRepository_TimesOutWhenAnAttemptEndsPastTheDeadline(t *testing.T) {
+	repo, backend, c, registry := setup(errBusy, errBusy)
+	backend.during = func() { c.now = c.now.Add(2 * time.Minute) }
+
+	_, err := repo.GetPost(1)
+
+	if !errors.Is(err, retry.ErrTimeout) || !errors.Is(err, errBusy) || backend.calls != 1 {
+		t.Fatalf("Expected ErrTimeout around the busy error from one attempt; Got: '%v' from %d", err, backend.calls)
+	}
+	if !strings.Contains(exposition(registry), `posts_repository_timeouts_total{method="GetPost"} 1`) {
+		t.Fatalf("Expected the timeout to be counted; Got:\n%s", exposition(registry))
+	}
+}
+
+func TestRepository_LetsSlowCallsFinish(t *testing.T) {
+	backend := slowBackend{db.NewGoodRepository([]entities.Post{{ID: 1, Title: "Foo"}}), make(chan struct{})}
+	registry := metrics.NewRegistry()
+	repo := retry.NewRepository(backend, isBusy, registry)
+	repo.Timeout = 10 * time.Millisecond
+	time.AfterFunc(50*time.Millisecond, func() { close(backend.release) })
+
+	// nothing is left running in the background: each call returns only
+	// once the backend has
+	post, getErr := repo.GetPost(1)
+	_, saveErr := repo.SavePost(entities.Post{Title: "Bar"})
+	committed := false
+	txErr := repo.WithinTx(func(tx interfaces.Repository) error {
+		committed = true
+		return nil
+	})
+
+	if getErr != nil || post.Title != "Foo" {
+		t.Fatalf("Expected the read to finish; Got: '%v', '%v'", post, getErr)
+	}
+	if saveErr != nil || txErr != nil || !committed {
+		t.Fatalf("Expected the write and transaction to finish; Got: '%v', '%v'", saveErr, txErr)
+	}
+	if strings.Contains(exposition(registry), "posts_repository_timeouts_total{") {
+		t.Fatalf("Expected no timeouts; Got:\n%s", exposition(registry))
+	}
+}
+
+func TestRepository_Conformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repository {
+		return retry.NewRepository(memory.NewRepository(), isBusy, metrics.NewRegistry())
+	})
+}
+
+func TestRepository_Forwarding(t *testing.T) {
+	repotest.RunForwarding(t, func(t *testing.T, backend repotest.Repository) repotest.Repository {
+		return retry.NewRepository(backend, isBusy, metrics.NewRegistry())
+	})
+}
@@ -0,0 +1,207 @@
+// Package sse streams post events to HTTP clients as Server-Sent Events.
+//
+// Every event gets a sequential ID and is kept in a bounded replay buffer,
+// so a client that reconnects with Last-Event-ID receives what it missed as
+// long as it is still buffered.
+package sse
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steve-kaufman/postsService/events"
+)
+
+// clientQueue is how many events may wait for a slow client before it is
+// disconnected; it can resume from the replay buffer
+const clientQueue = 64
+
+type message struct {
+	ID     int64
+	Name   string
+	PostID int
+	Data   []byte
+}
+
+type client struct {
+	postID int // 0 for every post
+	queue  chan message
+}
+
+func (c *client) wants(msg message) bool {
+	return c.postID == 0 || c.postID == msg.PostID
+}
+
+// Broker receives events through Handle and serves them under
+// /posts/stream and /posts/{id}/stream
+type Broker struct {
+	// KeepAlive is how often a comment is sent to idle clients
+	KeepAlive time.Duration
+
+	mu      sync.Mutex
+	buffer  []message
+	size    int
+	nextID  int64
+	clients map[*client]bool
+}
+
+// NewBroker returns a Broker that keeps the last bufferSize events for replay
+func NewBroker(bufferSize int) *Broker {
+	return &Broker{
+		KeepAlive: 15 * time.Second,
+		size:      bufferSize,
+		nextID:    1,
+		clients:   map[*client]bool{},
+	}
+}
+
+// Handle buffers event and sends it to every connected client that wants
+// it. It fits events.Bus.Subscribe.
+func (broker *Broker) Handle(event events.Event) error {
+	data, err := events.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+
+	msg := message{ID: broker.nextID, Name: event.EventName(), PostID: postID(event), Data: data}
+	broker.nextID++
+	broker.buffer = append(broker.buffer, msg)
+	if len(broker.buffer) > broker.size {
+		broker.buffer = broker.buffer[len(broker.buffer)-broker.size:]
+	}
+
+	for c := range broker.clients {
+		if !c.wants(msg) {
+			continue
+		}
+		select {
+		case c.queue <- msg:
+		default:
+			// too slow; dropping it lets it reconnect and catch up
+			delete(broker.clients, c)
+			close(c.queue)
+		}
+	}
+	return nil
+}
+
+// Clients returns the number of connected clients
+func (broker *Broker) Clients() int {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	return len(broker.clients)
+}
+
+func (broker *Broker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id, ok := parsePath(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	lastID, _ := strconv.ParseInt(r.Header.Get("Last-Event-ID"), 10, 64)
+	c := &client{postID: id, queue: make(chan message, clientQueue)}
+	replay := broker.subscribe(c, lastID)
+	defer broker.unsubscribe(c)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	for _, msg := range replay {
+		writeMessage(w, msg)
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(broker.KeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case msg, ok := <-c.queue:
+			if !ok {
+				return
+			}
+			writeMessage(w, msg)
+			flusher.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// subscribe registers c and returns the buffered messages after lastID that
+// it wants, atomically so nothing is missed or sent twice
+func (broker *Broker) subscribe(c *client, lastID int64) []message {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+
+	var replay []message
+	if lastID > 0 {
+		for _, msg := range broker.buffer {
+			if msg.ID > lastID && c.wants(msg) {
+				replay = append(replay, msg)
+			}
+		}
+	}
+	broker.clients[c] = true
+	return replay
+}
+
+func (broker *Broker) unsubscribe(c *client) {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	if broker.clients[c] {
+		delete(broker.clients, c)
+		close(c.queue)
+	}
+}
+
+func writeMessage(w http.ResponseWriter, msg message) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", msg.ID, msg.Name, msg.Data)
+}
+
+// parsePath accepts /posts/stream, returning 0, and /posts/{id}/stream
+func parsePath(path string) (int, bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) == 2 && parts[0] == "posts" && parts[1] == "stream" {
+		return 0, true
+	}
+	if len(parts) == 3 && parts[0] == "posts" && parts[2] == "stream" {
+		id, err := strconv.Atoi(parts[1])
+		return id, err == nil && id > 0
+	}
+	return 0, false
+}
+
+func postID(event events.Event) int {
+	switch e := event.(type) {
+	case events.PostCreated:
+		return e.Post.ID
+	case events.PostUpdated:
+		return e.After.ID
+	case events.PostDeleted:
+		return e.Post.ID
+	case events.PostVoted:
+		return e.Post.ID
+	}
+	return 0
+}
@@ -0,0 +1,165 @@
+package sse_test
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/events"
+	"github.com/steve-kaufman/postsService/sse"
+)
+
+func created(id int) events.Event {
+	return events.PostCreated{Post: entities.Post{ID: id, Title: "Foo"}}
+}
+
+func deleted(id int) events.Event {
+	return events.PostDeleted{Post: entities.Post{ID: id, Title: "Foo"}}
+}
+
+type stream struct {
+	resp    *http.Response
+	scanner *bufio.Scanner
+}
+
+func connect(t *testing.T, server *httptest.Server, path, lastEventID string) *stream {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Expected no error connecting; Got: '%v'", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200; Got: %d", resp.StatusCode)
+	}
+	return &stream{resp: resp, scanner: bufio.NewScanner(resp.Body)}
+}
+
+// next returns the id and event lines of the next event, skipping comments
+func (s *stream) next(t *testing.T) string {
+	t.Helper()
+	var lines []string
+	for s.scanner.Scan() {
+		line := s.scanner.Text()
+		if line == "" && len(lines) > 0 {
+			return strings.Join(lines, " ")
+		}
+		if strings.HasPrefix(line, "id:") || strings.HasPrefix(line, "event:") {
+			lines = append(lines, line)
+		}
+	}
+	t.Fatalf("Expected another event; stream ended: '%v'", s.scanner.Err())
+	return ""
+}
+
+func waitForClients(t *testing.T, broker *sse.Broker, n int) {
+	t.Helper()
+	for i := 0; i < 100 && broker.Clients() != n; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if broker.Clients() != n {
+		t.Fatalf("Expected %d clients; Got: %d", n, broker.Clients())
+	}
+}
+
+func setup(t *testing.T, bufferSize int) (*sse.Broker, *httptest.Server) {
+	broker := sse.NewBroker(bufferSize)
+	server := httptest.NewServer(broker)
+	t.Cleanup(server.Close)
+	return broker, server
+}
+
+func TestStream_PushesEventsAsTheyHappen(t *testing.T) {
+	broker, server := setup(t, 10)
+	s := connect(t, server, "/posts/stream", "")
+	waitForClients(t, broker, 1)
+
+	if ct := s.resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected text/event-stream; Got: '%s'", ct)
+	}
+
+	broker.Handle(created(1))
+	broker.Handle(deleted(1))
+
+	if got := s.next(t); got != "id: 1 event: post.created" {
+		t.Fatalf("Expected post.created; Got: '%s'", got)
+	}
+	if got := s.next(t); got != "id: 2 event: post.deleted" {
+		t.Fatalf("Expected post.deleted; Got: '%s'", got)
+	}
+}
+
+func TestPostStream_OnlyPushesThatPost(t *testing.T) {
+	broker, server := setup(t, 10)
+	s := connect(t, server, "/posts/2/stream", "")
+	waitForClients(t, broker, 1)
+
+	broker.Handle(created(1))
+	broker.Handle(created(2))
+	broker.Handle(deleted(1))
+	broker.Handle(deleted(2))
+
+	if got := s.next(t); got != "id: 2 event: post.created" {
+		t.Fatalf("Expected post 2 created; Got: '%s'", got)
+	}
+	if got := s.next(t); got != "id: 4 event: post.deleted" {
+		t.Fatalf("Expected post 2 deleted; Got: '%s'", got)
+	}
+}
+
+func TestStream_ResumesFromLastEventID(t *testing.T) {
+	broker, server := setup(t, 3)
+	for id := 1; id <= 5; id++ {
+		broker.Handle(created(id))
+	}
+
+	s := connect(t, server, "/posts/stream", "3")
+
+	if got := s.next(t); got != "id: 4 event: post.created" {
+		t.Fatalf("Expected replay from 4; Got: '%s'", got)
+	}
+	if got := s.next(t); got != "id: 5 event: post.created" {
+		t.Fatalf("Expected replay of 5; Got: '%s'", got)
+	}
+
+	broker.Handle(deleted(1))
+	if got := s.next(t); got != "id: 6 event: post.deleted" {
+		t.Fatalf("Expected live event after replay; Got: '%s'", got)
+	}
+}
+
+func TestStream_ReplaysOnlyWhatIsStillBuffered(t *testing.T) {
+	broker, server := setup(t, 2)
+	for id := 1; id <= 5; id++ {
+		broker.Handle(created(id))
+	}
+
+	s := connect(t, server, "/posts/stream", "1")
+
+	if got := s.next(t); got != "id: 4 event: post.created" {
+		t.Fatalf("Expected replay to start at oldest buffered event; Got: '%s'", got)
+	}
+}
+
+func TestStream_ReturnsNotFound_ForOtherPaths(t *testing.T) {
+	_, server := setup(t, 1)
+
+	for _, path := range []string{"/posts", "/posts/abc/stream", "/posts/0/stream", "/stream"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Fatalf("Expected 404 for '%s'; Got: %d", path, resp.StatusCode)
+		}
+	}
+}
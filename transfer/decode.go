@@ -0,0 +1,184 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrNotArray      = errors.New("json input must be an array")
+	ErrNoTitleColumn = errors.New("csv header has no title column")
+)
+
+// decoder reads one record at a time. next returns io.EOF at the end of
+// the input and a *badRecord for a record that can't be decoded but can
+// be skipped; any other error means the input can't be read further.
+type decoder interface {
+	next() (rec record, line int, err error)
+}
+
+type badRecord struct {
+	err error
+}
+
+func (bad *badRecord) Error() string {
+	return bad.err.Error()
+}
+
+func newDecoder(r io.Reader, format Format) (decoder, error) {
+	switch format {
+	case JSONLines:
+		return &jsonLinesDecoder{reader: bufio.NewReader(r)}, nil
+	case JSON:
+		return &jsonArrayDecoder{decoder: json.NewDecoder(r)}, nil
+	case CSV:
+		reader := csv.NewReader(r)
+		// rows with the wrong number of fields are reported, not fatal
+		reader.FieldsPerRecord = -1
+		return &csvDecoder{reader: reader}, nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+type jsonLinesDecoder struct {
+	reader *bufio.Reader
+	line   int
+}
+
+func (d *jsonLinesDecoder) next() (record, int, error) {
+	for {
+		text, err := d.reader.ReadBytes('\n')
+		if len(text) == 0 && err != nil {
+			return record{}, d.line, err
+		}
+		d.line++
+		if len(bytes.TrimSpace(text)) == 0 {
+			continue
+		}
+		var rec record
+		if err := json.Unmarshal(text, &rec); err != nil {
+			return record{}, d.line, &badRecord{err}
+		}
+		return rec, d.line, nil
+	}
+}
+
+type jsonArrayDecoder struct {
+	decoder *json.Decoder
+	started bool
+	index   int
+}
+
+func (d *jsonArrayDecoder) next() (record, int, error) {
+	if !d.started {
+		token, err := d.decoder.Token()
+		if err != nil {
+			return record{}, 0, err
+		}
+		if token != json.Delim('[') {
+			return record{}, 0, ErrNotArray
+		}
+		d.started = true
+	}
+	if !d.decoder.More() {
+		// consume the closing bracket so a truncated array is an error
+		if _, err := d.decoder.Token(); err != nil {
+			return record{}, d.index, err
+		}
+		return record{}, d.index, io.EOF
+	}
+
+	d.index++
+	var rec record
+	if err := d.decoder.Decode(&rec); err != nil {
+		// the decoder reads past a value of the wrong type, so the
+		// array can still be read after it
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return record{}, d.index, &badRecord{err}
+		}
+		return record{}, d.index, err
+	}
+	return rec, d.index, nil
+}
+
+type csvDecoder struct {
+	reader  *csv.Reader
+	header  []string
+	columns map[string]int
+	row     int
+}
+
+func (d *csvDecoder) next() (record, int, error) {
+	if d.columns == nil {
+		if err := d.readHeader(); err != nil {
+			return record{}, d.row, err
+		}
+	}
+
+	fields, err := d.reader.Read()
+	if err == io.EOF {
+		return record{}, d.row, err
+	}
+	d.row++
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return record{}, d.row, &badRecord{parseErr.Err}
+	}
+	if err != nil {
+		return record{}, d.row, err
+	}
+	if len(fields) != len(d.header) {
+		return record{}, d.row, &badRecord{csv.ErrFieldCount}
+	}
+
+	field := func(name string) string {
+		if i, ok := d.columns[name]; ok {
+			return fields[i]
+		}
+		return ""
+	}
+	rec := record{
+		Slug:    field("slug"),
+		Title:   field("title"),
+		Content: field("content"),
+	}
+	for name, dest := range map[string]*int{"id": &rec.ID, "likes": &rec.Likes, "dislikes": &rec.Dislikes} {
+		value := strings.TrimSpace(field(name))
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return record{}, d.row, &badRecord{fmt.Errorf("%s must be a number", name)}
+		}
+		*dest = n
+	}
+	return rec, d.row, nil
+}
+
+// readHeader maps column names to their positions, so columns can come in
+// any order and unknown ones are ignored
+func (d *csvDecoder) readHeader() error {
+	header, err := d.reader.Read()
+	if err != nil {
+		return err
+	}
+	d.row = 1
+	d.header = header
+	d.columns = map[string]int{}
+	for i, name := range header {
+		d.columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := d.columns["title"]; !ok {
+		return ErrNoTitleColumn
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package transfer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/steve-kaufman/postsService/entities"
+)
+
+// Export writes every post in source to w and returns how many it wrote
+func Export(source Source, w io.Writer, format Format) (int, error) {
+	buffered := bufio.NewWriter(w)
+	var write func(record) error
+	var finish func() error
+
+	switch format {
+	case JSONLines:
+		encoder := json.NewEncoder(buffered)
+		write = func(rec record) error { return encoder.Encode(rec) }
+		finish = func() error { return nil }
+	case JSON:
+		count := 0
+		write = func(rec record) error {
+			separator := ",\n"
+			if count == 0 {
+				separator = "[\n"
+			}
+			count++
+			line, err := json.Marshal(rec)
+			if err != nil {
+				return err
+			}
+			buffered.WriteString(separator)
+			_, err = buffered.Write(line)
+			return err
+		}
+		finish = func() error {
+			if count == 0 {
+				_, err := buffered.WriteString("[]\n")
+				return err
+			}
+			_, err := buffered.WriteString("\n]\n")
+			return err
+		}
+	case CSV:
+		writer := csv.NewWriter(buffered)
+		if err := writer.Write(csvHeader); err != nil {
+			return 0, err
+		}
+		write = func(rec record) error {
+			return writer.Write([]string{
+				strconv.Itoa(rec.ID),
+				rec.Slug,
+				rec.Title,
+				rec.Content,
+				strconv.Itoa(rec.Likes),
+				strconv.Itoa(rec.Dislikes),
+			})
+		}
+		finish = func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+	default:
+		return 0, ErrUnknownFormat
+	}
+
+	exported := 0
+	err := source.EachPost(func(post entities.Post) error {
+		if err := write(toRecord(post)); err != nil {
+			return err
+		}
+		exported++
+		return nil
+	})
+	if err != nil {
+		return exported, err
+	}
+	if err := finish(); err != nil {
+		return exported, err
+	}
+	return exported, buffered.Flush()
+}
@@ -0,0 +1,124 @@
+package transfer_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/transfer"
+)
+
+var examplePosts = []entities.Post{
+	{ID: 1, Slug: "post-1", Title: "Post 1", Content: "Hello, \"world\"", Likes: 2, Dislikes: 1},
+	{ID: 3, Slug: "post-3", Title: "Post 3", Content: "two\nlines"},
+}
+
+// store is a Source and Sink backed by a slice
+type store struct {
+	posts []entities.Post
+	// fail is returned by ImportPost once set
+	fail error
+}
+
+func (s *store) EachPost(fn func(post entities.Post) error) error {
+	for _, post := range s.posts {
+		if err := fn(post); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *store) ImportPost(post entities.Post, keepID bool) error {
+	if s.fail != nil {
+		return s.fail
+	}
+	if keepID {
+		for _, existing := range s.posts {
+			if existing.ID == post.ID {
+				return transfer.ErrIDTaken
+			}
+		}
+	} else {
+		post.ID = len(s.posts) + 100
+	}
+	s.posts = append(s.posts, post)
+	return nil
+}
+
+func TestExport(t *testing.T) {
+	tests := map[transfer.Format]string{
+		transfer.JSONLines: `{"id":1,"slug":"post-1","title":"Post 1","content":"Hello, \"world\"","likes":2,"dislikes":1}
+{"id":3,"slug":"post-3","title":"Post 3","content":"two\nlines","likes":0,"dislikes":0}
+`,
+		transfer.JSON: `[
+{"id":1,"slug":"post-1","title":"Post 1","content":"Hello, \"world\"","likes":2,"dislikes":1},
+{"id":3,"slug":"post-3","title":"Post 3","content":"two\nlines","likes":0,"dislikes":0}
+]
+`,
+		transfer.CSV: `id,slug,title,content,likes,dislikes
+1,post-1,Post 1,"Hello, ""world""",2,1
+3,post-3,Post 3,"two
+lines",0,0
+`,
+	}
+
+	for format, expected := range tests {
+		t.Run(string(format), func(t *testing.T) {
+			var out bytes.Buffer
+			exported, err := transfer.Export(&store{posts: examplePosts}, &out, format)
+
+			if err != nil {
+				t.Fatalf("Expected no error; Got: '%v'", err)
+			}
+			if exported != 2 {
+				t.Fatalf("Expected 2 posts exported; Got: %d", exported)
+			}
+			if diff := cmp.Diff(expected, out.String()); diff != "" {
+				t.Fatalf("Expected export: \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestExport_WritesEmptyJSONArray(t *testing.T) {
+	var out bytes.Buffer
+	transfer.Export(new(store), &out, transfer.JSON)
+
+	if out.String() != "[]\n" {
+		t.Fatalf("Expected an empty array; Got: '%s'", out.String())
+	}
+}
+
+func TestExport_RoundTripsThroughImport(t *testing.T) {
+	for _, format := range []transfer.Format{transfer.JSONLines, transfer.JSON, transfer.CSV} {
+		t.Run(string(format), func(t *testing.T) {
+			var out bytes.Buffer
+			transfer.Export(&store{posts: examplePosts}, &out, format)
+
+			imported := new(store)
+			count, err := transfer.Import(imported, &out, format, transfer.PreserveIDs, func(failure transfer.Failure) {
+				t.Fatalf("Expected no failures; Got: '%v'", failure)
+			})
+
+			if err != nil || count != 2 {
+				t.Fatalf("Expected 2 posts imported; Got: %d, '%v'", count, err)
+			}
+			expected := []entities.Post{examplePosts[0], examplePosts[1]}
+			expected[0].Slug, expected[1].Slug = "", ""
+			if diff := cmp.Diff(expected, imported.posts); diff != "" {
+				t.Fatalf("Expected the same posts back: \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if format, err := transfer.ParseFormat("csv"); format != transfer.CSV || err != nil {
+		t.Fatalf("Expected csv; Got: '%s', '%v'", format, err)
+	}
+	if _, err := transfer.ParseFormat("xml"); err != transfer.ErrUnknownFormat {
+		t.Fatalf("Expected ErrUnknownFormat; Got: '%v'", err)
+	}
+}
@@ -0,0 +1,63 @@
+// Package transfer moves posts in and out of a repository as JSON Lines,
+// CSV or a JSON array, one post at a time so neither side has to hold
+// every post in memory.
+package transfer
+
+import (
+	"errors"
+
+	"github.com/steve-kaufman/postsService/entities"
+)
+
+var ErrUnknownFormat = errors.New("unknown format")
+
+type Format string
+
+const (
+	JSONLines Format = "jsonl"
+	CSV       Format = "csv"
+	JSON      Format = "json"
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch format := Format(s); format {
+	case JSONLines, CSV, JSON:
+		return format, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// Source is anything that can stream its posts in ID order
+type Source interface {
+	EachPost(fn func(post entities.Post) error) error
+}
+
+// Sink stores imported posts. With keepID it stores post under post.ID, or
+// returns ErrIDTaken; otherwise it assigns a new ID.
+type Sink interface {
+	ImportPost(post entities.Post, keepID bool) error
+}
+
+// record is how a post is written out. Slug is informational: a post's
+// slug is generated again from its title when it is imported.
+type record struct {
+	ID       int    `json:"id"`
+	Slug     string `json:"slug,omitempty"`
+	Title    string `json:"title"`
+	Content  string `json:"content"`
+	Likes    int    `json:"likes"`
+	Dislikes int    `json:"dislikes"`
+}
+
+var csvHeader = []string{"id", "slug", "title", "content", "likes", "dislikes"}
+
+func toRecord(post entities.Post) record {
+	return record{
+		ID:       post.ID,
+		Slug:     post.Slug,
+		Title:    post.Title,
+		Content:  post.Content,
+		Likes:    post.Likes,
+		Dislikes: post.Dislikes,
+	}
+}
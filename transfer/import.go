@@ -0,0 +1,96 @@
+package transfer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/steve-kaufman/postsService/entities"
+)
+
+var (
+	ErrIDTaken       = errors.New("id is already taken")
+	ErrNeedsID       = errors.New("id is required when preserving ids")
+	ErrNegativeVotes = errors.New("likes and dislikes can't be negative")
+)
+
+type IDMode int
+
+const (
+	// ReassignIDs gives every imported post the next free ID
+	ReassignIDs IDMode = iota
+	// PreserveIDs keeps each post's ID, rejecting posts whose ID is taken
+	PreserveIDs
+)
+
+// Failure is a record that wasn't imported
+type Failure struct {
+	// Line is where the record is: its line in JSON Lines, its row in CSV
+	// counting the header as row 1, or its position from 1 in a JSON array
+	Line int
+	Err  error
+}
+
+// Import reads posts from r into sink and returns how many it stored.
+//
+// Every record is validated like a new post, but keeps its likes and
+// dislikes. A record that is invalid, can't be decoded or whose ID is
+// taken is passed to report and skipped; Import only stops early, with an
+// error, when r can't be read any further or sink fails.
+func Import(sink Sink, r io.Reader, format Format, ids IDMode, report func(Failure)) (int, error) {
+	decoder, err := newDecoder(r, format)
+	if err != nil {
+		return 0, err
+	}
+
+	imported := 0
+	for {
+		rec, line, err := decoder.next()
+		var bad *badRecord
+		switch {
+		case err == io.EOF:
+			return imported, nil
+		case errors.As(err, &bad):
+			report(Failure{Line: line, Err: bad.err})
+			continue
+		case err != nil:
+			return imported, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		post, err := toPost(rec, ids)
+		if err != nil {
+			report(Failure{Line: line, Err: err})
+			continue
+		}
+		err = sink.ImportPost(post, ids == PreserveIDs)
+		if err == ErrIDTaken {
+			report(Failure{Line: line, Err: err})
+			continue
+		}
+		if err != nil {
+			return imported, fmt.Errorf("line %d: %w", line, err)
+		}
+		imported++
+	}
+}
+
+func toPost(rec record, ids IDMode) (entities.Post, error) {
+	post, err := entities.FormatAndValidateNewPost(entities.Post{Title: rec.Title, Content: rec.Content})
+	if err != nil {
+		return entities.Post{}, err
+	}
+	if rec.Likes < 0 || rec.Dislikes < 0 {
+		return entities.Post{}, ErrNegativeVotes
+	}
+	// the votes are being moved along with the post, not cast by its author
+	post.Likes = rec.Likes
+	post.Dislikes = rec.Dislikes
+
+	if ids == PreserveIDs {
+		if rec.ID <= 0 {
+			return entities.Post{}, ErrNeedsID
+		}
+		post.ID = rec.ID
+	}
+	return post, nil
+}
@@ -0,0 +1,170 @@
+package transfer_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/transfer"
+)
+
+type reported struct {
+	Line  int
+	Error string
+}
+
+func importString(t *testing.T, sink transfer.Sink, input string, format transfer.Format, ids transfer.IDMode) (int, []reported, error) {
+	t.Helper()
+	var failures []reported
+	imported, err := transfer.Import(sink, strings.NewReader(input), format, ids, func(failure transfer.Failure) {
+		failures = append(failures, reported{failure.Line, failure.Err.Error()})
+	})
+	return imported, failures, err
+}
+
+func TestImport_ReportsBadLinesAndContinues(t *testing.T) {
+	input := `{"id":7,"title":"Good","content":"Kept","likes":4,"dislikes":2}
+{"title":""}
+
+not json
+{"title":"Long","content":"` + strings.Repeat("a", 501) + `"}
+{"title":"Negative","likes":-1}
+{"id":8,"title":"Also good"}
+`
+	sink := new(store)
+	imported, failures, err := importString(t, sink, input, transfer.JSONLines, transfer.ReassignIDs)
+
+	if err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	if imported != 2 {
+		t.Fatalf("Expected 2 posts imported; Got: %d", imported)
+	}
+	expectedFailures := []reported{
+		{2, entities.ErrNeedsTitle.Error()},
+		{4, "invalid character 'o' in literal null (expecting 'u')"},
+		{5, entities.ErrTooLong.Error()},
+		{6, transfer.ErrNegativeVotes.Error()},
+	}
+	if diff := cmp.Diff(expectedFailures, failures); diff != "" {
+		t.Fatalf("Expected failures by line: \n%s", diff)
+	}
+	expectedPosts := []entities.Post{
+		{ID: 100, Title: "Good", Content: "Kept", Likes: 4, Dislikes: 2},
+		{ID: 101, Title: "Also good"},
+	}
+	if diff := cmp.Diff(expectedPosts, sink.posts); diff != "" {
+		t.Fatalf("Expected posts with new IDs: \n%s", diff)
+	}
+}
+
+func TestImport_PreservesIDs(t *testing.T) {
+	input := `{"id":1,"title":"Taken"}
+{"id":2,"title":"Free"}
+{"title":"No ID"}
+`
+	sink := &store{posts: []entities.Post{{ID: 1, Title: "Existing"}}}
+	imported, failures, err := importString(t, sink, input, transfer.JSONLines, transfer.PreserveIDs)
+
+	if err != nil || imported != 1 {
+		t.Fatalf("Expected 1 post imported; Got: %d, '%v'", imported, err)
+	}
+	expectedFailures := []reported{
+		{1, transfer.ErrIDTaken.Error()},
+		{3, transfer.ErrNeedsID.Error()},
+	}
+	if diff := cmp.Diff(expectedFailures, failures); diff != "" {
+		t.Fatalf("Expected failures by line: \n%s", diff)
+	}
+	if sink.posts[1].ID != 2 {
+		t.Fatalf("Expected post 2 to keep its ID; Got: '%v'", sink.posts[1])
+	}
+}
+
+func TestImport_CSV(t *testing.T) {
+	input := `Title,Likes,Extra
+First,3,ignored
+Second,lots,ignored
+Third
+,1,ignored
+"Fourth ""quoted""",,
+`
+	sink := new(store)
+	imported, failures, err := importString(t, sink, input, transfer.CSV, transfer.ReassignIDs)
+
+	if err != nil || imported != 2 {
+		t.Fatalf("Expected 2 posts imported; Got: %d, '%v'", imported, err)
+	}
+	expectedFailures := []reported{
+		{3, "likes must be a number"},
+		{4, "wrong number of fields"},
+		{5, entities.ErrNeedsTitle.Error()},
+	}
+	if diff := cmp.Diff(expectedFailures, failures); diff != "" {
+		t.Fatalf("Expected failures by row: \n%s", diff)
+	}
+	expectedPosts := []entities.Post{
+		{ID: 100, Title: "First", Likes: 3},
+		{ID: 101, Title: `Fourth "quoted"`},
+	}
+	if diff := cmp.Diff(expectedPosts, sink.posts); diff != "" {
+		t.Fatalf("Expected imported posts: \n%s", diff)
+	}
+}
+
+func TestImport_CSVNeedsTitleColumn(t *testing.T) {
+	_, _, err := importString(t, new(store), "id,content\n1,foo\n", transfer.CSV, transfer.ReassignIDs)
+
+	if !errors.Is(err, transfer.ErrNoTitleColumn) {
+		t.Fatalf("Expected ErrNoTitleColumn; Got: '%v'", err)
+	}
+}
+
+func TestImport_JSONArray(t *testing.T) {
+	input := `[{"title":"First"}, {"title":5}, "nope", {"title":"Second","dislikes":2}]`
+	sink := new(store)
+	imported, failures, err := importString(t, sink, input, transfer.JSON, transfer.ReassignIDs)
+
+	if err != nil || imported != 2 {
+		t.Fatalf("Expected 2 posts imported; Got: %d, '%v'", imported, err)
+	}
+	if len(failures) != 2 || failures[0].Line != 2 || failures[1].Line != 3 {
+		t.Fatalf("Expected items 2 and 3 to fail; Got: '%v'", failures)
+	}
+	if sink.posts[1].Title != "Second" || sink.posts[1].Dislikes != 2 {
+		t.Fatalf("Expected the last item to be imported; Got: '%v'", sink.posts)
+	}
+}
+
+func TestImport_StopsOnUnreadableInput(t *testing.T) {
+	tests := map[string]struct {
+		input  string
+		format transfer.Format
+	}{
+		"not an array":      {`{"title":"Foo"}`, transfer.JSON},
+		"truncated array":   {`[{"title":"Foo"}, {"title":`, transfer.JSON},
+		"unterminated list": {`[{"title":"Foo"}`, transfer.JSON},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := importString(t, new(store), tc.input, tc.format, transfer.ReassignIDs)
+
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+		})
+	}
+}
+
+func TestImport_StopsWhenSinkFails(t *testing.T) {
+	sink := &store{fail: fmt.Errorf("disk full")}
+	imported, _, err := importString(t, sink, `{"title":"Foo"}`+"\n", transfer.JSONLines, transfer.ReassignIDs)
+
+	if imported != 0 || err == nil || err.Error() != "line 1: disk full" {
+		t.Fatalf("Expected the sink's error; Got: %d, '%v'", imported, err)
+	}
+}
@@ -0,0 +1,222 @@
+// Package trending ranks posts by how fast they are gaining likes compared
+// with their own baseline, so a burst of likes on any post can outrank a
+// steady stream on a popular one.
+//
+// Likes are counted into time buckets as they are published and a worker
+// recomputes the rankings from the buckets every RefreshInterval, so reading
+// a ranking never touches individual votes. The counts live in memory and
+// start empty, which only costs a restarted server its first Baseline.
+package trending
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/steve-kaufman/postsService/events"
+)
+
+var ErrUnknownWindow = errors.New("trending window not supported")
+
+// prior is added to every post's expected likes so that a post with no
+// baseline needs a few likes, not just one, to trend
+const prior = 1.0
+
+type update struct {
+	postID  int
+	at      time.Time
+	deleted bool
+}
+
+type Tracker struct {
+	// BucketSize is the resolution likes are counted at
+	BucketSize time.Duration
+	// Baseline is how far back a post's usual rate of likes is measured
+	Baseline time.Duration
+	// Windows are the windows kept ranked; each must be shorter than Baseline
+	Windows         []time.Duration
+	RefreshInterval time.Duration
+	// Limit is how many posts are ranked per window
+	Limit int
+	// Now is the tracker's clock
+	Now func() time.Time
+
+	mu      sync.Mutex
+	pending []update
+	ranked  map[time.Duration][]int
+
+	// buckets maps post IDs to bucket numbers to likes; only Refresh
+	// touches it
+	refreshMu sync.Mutex
+	buckets   map[int]map[int64]int
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{
+		BucketSize:      5 * time.Minute,
+		Baseline:        24 * time.Hour,
+		Windows:         []time.Duration{time.Hour, 6 * time.Hour},
+		RefreshInterval: time.Minute,
+		Limit:           100,
+		Now:             time.Now,
+		ranked:          map[time.Duration][]int{},
+		buckets:         map[int]map[int64]int{},
+	}
+}
+
+// Handle queues likes and deletions for the next refresh. It fits
+// events.Bus.Subscribe.
+func (tracker *Tracker) Handle(event events.Event) error {
+	var u update
+	switch e := event.(type) {
+	case events.PostVoted:
+		if !e.Liked {
+			return nil
+		}
+		u = update{postID: e.Post.ID, at: tracker.Now()}
+	case events.PostDeleted:
+		u = update{postID: e.Post.ID, deleted: true}
+	default:
+		return nil
+	}
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	tracker.pending = append(tracker.pending, u)
+	return nil
+}
+
+// GetTrending returns the IDs of the posts trending over window as of the
+// last refresh, most trending first
+func (tracker *Tracker) GetTrending(window time.Duration) ([]int, error) {
+	if !tracker.supports(window) {
+		return nil, ErrUnknownWindow
+	}
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	return append([]int{}, tracker.ranked[window]...), nil
+}
+
+func (tracker *Tracker) supports(window time.Duration) bool {
+	for _, w := range tracker.Windows {
+		if w == window {
+			return true
+		}
+	}
+	return false
+}
+
+// Run refreshes the rankings every RefreshInterval until ctx is done
+func (tracker *Tracker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(tracker.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		tracker.Refresh()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh counts the likes queued since the last refresh, forgets buckets
+// older than Baseline and re-ranks every window
+func (tracker *Tracker) Refresh() {
+	tracker.refreshMu.Lock()
+	defer tracker.refreshMu.Unlock()
+
+	tracker.mu.Lock()
+	pending := tracker.pending
+	tracker.pending = nil
+	tracker.mu.Unlock()
+
+	for _, u := range pending {
+		if u.deleted {
+			delete(tracker.buckets, u.postID)
+			continue
+		}
+		if tracker.buckets[u.postID] == nil {
+			tracker.buckets[u.postID] = map[int64]int{}
+		}
+		tracker.buckets[u.postID][tracker.bucket(u.at)]++
+	}
+
+	now := tracker.Now()
+	oldest := tracker.bucket(now.Add(-tracker.Baseline))
+	for id, buckets := range tracker.buckets {
+		for b := range buckets {
+			if b < oldest {
+				delete(buckets, b)
+			}
+		}
+		if len(buckets) == 0 {
+			delete(tracker.buckets, id)
+		}
+	}
+
+	ranked := map[time.Duration][]int{}
+	for _, window := range tracker.Windows {
+		ranked[window] = tracker.rank(now, window)
+	}
+	tracker.mu.Lock()
+	tracker.ranked = ranked
+	tracker.mu.Unlock()
+}
+
+func (tracker *Tracker) bucket(at time.Time) int64 {
+	return at.UnixNano() / int64(tracker.BucketSize)
+}
+
+type score struct {
+	postID int
+	recent int
+	score  float64
+}
+
+// rank scores each post by its likes within window over the likes its
+// baseline rate predicts for a window that long
+func (tracker *Tracker) rank(now time.Time, window time.Duration) []int {
+	start := tracker.bucket(now.Add(-window))
+	baselineLength := float64(tracker.Baseline - window)
+
+	scores := []score{}
+	for id, buckets := range tracker.buckets {
+		recent, before := 0, 0
+		for b, likes := range buckets {
+			if b > start {
+				recent += likes
+			} else {
+				before += likes
+			}
+		}
+		if recent == 0 {
+			continue
+		}
+		expected := float64(before) * float64(window) / baselineLength
+		scores = append(scores, score{postID: id, recent: recent, score: float64(recent) / (expected + prior)})
+	}
+
+	sort.Slice(scores, func(i, j int) bool {
+		a, b := scores[i], scores[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.recent != b.recent {
+			return a.recent > b.recent
+		}
+		return a.postID < b.postID
+	})
+
+	ids := []int{}
+	for _, s := range scores {
+		if len(ids) == tracker.Limit {
+			break
+		}
+		ids = append(ids, s.postID)
+	}
+	return ids
+}
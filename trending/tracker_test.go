@@ -0,0 +1,132 @@
+package trending_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/events"
+	"github.com/steve-kaufman/postsService/trending"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time { return c.now }
+
+func setup() (*trending.Tracker, *clock) {
+	c := &clock{now: time.Date(2021, time.June, 1, 12, 0, 0, 0, time.UTC)}
+	tracker := trending.NewTracker()
+	tracker.Now = c.Now
+	return tracker, c
+}
+
+func like(tracker *trending.Tracker, id, times int) {
+	for i := 0; i < times; i++ {
+		tracker.Handle(events.PostVoted{Post: entities.Post{ID: id}, Liked: true})
+	}
+}
+
+func trendingIDs(t *testing.T, tracker *trending.Tracker, window time.Duration) []int {
+	t.Helper()
+	ids, err := tracker.GetTrending(window)
+	if err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	return ids
+}
+
+func TestTracker_RanksByLikesAgainstBaseline(t *testing.T) {
+	tracker, c := setup()
+
+	// post 1 is steadily popular, 10 likes an hour through the day
+	for hour := 0; hour < 23; hour++ {
+		like(tracker, 1, 10)
+		c.now = c.now.Add(time.Hour)
+	}
+	// in the last hour post 1 keeps its pace while post 2 bursts from nothing
+	like(tracker, 1, 10)
+	like(tracker, 2, 5)
+	tracker.Refresh()
+
+	if diff := cmp.Diff([]int{2, 1}, trendingIDs(t, tracker, time.Hour)); diff != "" {
+		t.Fatalf("Expected the burst to outrank the steady post: \n%s", diff)
+	}
+}
+
+func TestTracker_IgnoresDislikesAndOnlyUpdatesOnRefresh(t *testing.T) {
+	tracker, _ := setup()
+	like(tracker, 1, 2)
+	tracker.Handle(events.PostVoted{Post: entities.Post{ID: 2}})
+
+	if ids := trendingIDs(t, tracker, time.Hour); len(ids) != 0 {
+		t.Fatalf("Expected nothing before a refresh; Got: '%v'", ids)
+	}
+	tracker.Refresh()
+
+	if diff := cmp.Diff([]int{1}, trendingIDs(t, tracker, time.Hour)); diff != "" {
+		t.Fatalf("Expected only the liked post: \n%s", diff)
+	}
+}
+
+func TestTracker_DropsLikesOutsideWindowAndBaseline(t *testing.T) {
+	tracker, c := setup()
+	like(tracker, 1, 3)
+	tracker.Refresh()
+
+	c.now = c.now.Add(2 * time.Hour)
+	tracker.Refresh()
+	if ids := trendingIDs(t, tracker, time.Hour); len(ids) != 0 {
+		t.Fatalf("Expected no trending posts an hour later; Got: '%v'", ids)
+	}
+	if diff := cmp.Diff([]int{1}, trendingIDs(t, tracker, 6*time.Hour)); diff != "" {
+		t.Fatalf("Expected the post to still trend over 6h: \n%s", diff)
+	}
+
+	c.now = c.now.Add(24 * time.Hour)
+	like(tracker, 1, 1)
+	tracker.Refresh()
+	// the old likes have left the baseline, so a single like trends fully
+	like(tracker, 2, 1)
+	tracker.Refresh()
+	if diff := cmp.Diff([]int{1, 2}, trendingIDs(t, tracker, time.Hour)); diff != "" {
+		t.Fatalf("Expected expired likes to be forgotten: \n%s", diff)
+	}
+}
+
+func TestTracker_ForgetsDeletedPosts(t *testing.T) {
+	tracker, _ := setup()
+	like(tracker, 1, 3)
+	like(tracker, 2, 1)
+	tracker.Refresh()
+
+	tracker.Handle(events.PostDeleted{Post: entities.Post{ID: 1}})
+	tracker.Refresh()
+
+	if diff := cmp.Diff([]int{2}, trendingIDs(t, tracker, time.Hour)); diff != "" {
+		t.Fatalf("Expected deleted post to be dropped: \n%s", diff)
+	}
+}
+
+func TestTracker_LimitsRankedPosts(t *testing.T) {
+	tracker, _ := setup()
+	tracker.Limit = 2
+	like(tracker, 1, 1)
+	like(tracker, 2, 3)
+	like(tracker, 3, 2)
+	tracker.Refresh()
+
+	if diff := cmp.Diff([]int{2, 3}, trendingIDs(t, tracker, time.Hour)); diff != "" {
+		t.Fatalf("Expected the top two posts: \n%s", diff)
+	}
+}
+
+func TestTracker_RejectsUnknownWindow(t *testing.T) {
+	tracker, _ := setup()
+
+	if _, err := tracker.GetTrending(time.Minute); err != trending.ErrUnknownWindow {
+		t.Fatalf("Expected ErrUnknownWindow; Got: '%v'", err)
+	}
+}
@@ -2,20 +2,27 @@ package useCases
 
 import (
 	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/events"
 	"github.com/steve-kaufman/postsService/interfaces"
 )
 
-func CreatePost(saver interfaces.PostSaver, post entities.Post) (entities.Post, error) {
+func CreatePost(saver interfaces.PostSaver, publisher events.Publisher, renderer interfaces.ContentRenderer, post entities.Post) (entities.Post, error) {
 	post, err := entities.FormatAndValidateNewPost(post)
 	if err != nil {
 		return entities.Post{}, err
 	}
-	return attemptSavePost(saver, post)
+	post, err = attemptSavePost(saver, post)
+	if err != nil {
+		return entities.Post{}, err
+	}
+	publisher.Publish(events.PostCreated{Post: post})
+	return withHTML(renderer, post), nil
 }
 
 func attemptSavePost(saver interfaces.PostSaver, post entities.Post) (entities.Post, error) {
-	if err := saver.SavePost(post); err != nil {
-		return entities.Post{}, ErrInternal
+	saved, err := saver.SavePost(post)
+	if err != nil {
+		return entities.Post{}, &Error{Op: "CreatePost", Err: err}
 	}
-	return post, nil
+	return saved, nil
 }
@@ -1,13 +1,18 @@
 package useCases_test
 
 import (
+	"errors"
 	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/steve-kaufman/postsService/db"
 	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/events"
 	"github.com/steve-kaufman/postsService/interfaces"
+	"github.com/steve-kaufman/postsService/memory"
+	"github.com/steve-kaufman/postsService/render"
 	"github.com/steve-kaufman/postsService/useCases"
 )
 
@@ -60,43 +65,89 @@ var createTests = []CreateTest{
 		repo:         db.NewGoodRepository(examplePosts),
 		inputPost:    entities.Post{Title: "Foo", Content: "Bar"},
 		expectedErr:  nil,
-		expectedPost: entities.Post{Title: "Foo", Content: "Bar"},
+		expectedPost: entities.Post{ID: 4, Title: "Foo", Content: "Bar"},
 	},
 	{
 		name:         "Saves post if title and length of content <= 500",
 		repo:         db.NewGoodRepository(examplePosts),
 		inputPost:    entities.Post{Title: "Foo", Content: strings.Repeat("a", 500)},
 		expectedErr:  nil,
-		expectedPost: entities.Post{Title: "Foo", Content: strings.Repeat("a", 500)},
+		expectedPost: entities.Post{ID: 4, Title: "Foo", Content: strings.Repeat("a", 500)},
 	},
 	{
 		name:         "Sets likes and dislikes to zero regardless of input",
 		repo:         db.NewGoodRepository(examplePosts),
 		inputPost:    entities.Post{Title: "Foo", Content: "Bar", Likes: 11, Dislikes: 2},
 		expectedErr:  nil,
-		expectedPost: entities.Post{Title: "Foo", Content: "Bar"},
+		expectedPost: entities.Post{ID: 4, Title: "Foo", Content: "Bar"},
 	},
 }
 
 func TestCreate(t *testing.T) {
 	for _, tc := range createTests {
 		t.Run(tc.name, func(t *testing.T) {
-			post, err := useCases.CreatePost(tc.repo, tc.inputPost)
+			publisher := new(events.Recorder)
+			post, err := useCases.CreatePost(tc.repo, publisher, render.Skip, tc.inputPost)
 
-			if err != tc.expectedErr {
+			if !errors.Is(err, tc.expectedErr) {
 				t.Fatalf("Expected err to be: '%v'; Got: '%v'", tc.expectedErr, err)
 			}
 			if diff := cmp.Diff(tc.expectedPost, post); diff != "" {
 				t.Fatalf("Expected posts to match: %s", diff)
 			}
 
+			expectedEvents := []events.Event{}
+			if tc.expectedErr == nil {
+				expectedEvents = append(expectedEvents, events.PostCreated{Post: tc.expectedPost})
+			}
+			if diff := cmp.Diff(expectedEvents, publisher.Events(), cmpopts.EquateEmpty()); diff != "" {
+				t.Fatalf("Expected published events to match: %s", diff)
+			}
+
 			goodRepo, ok := tc.repo.(*db.GoodRepository)
 			if !ok {
 				return
 			}
-			if diff := cmp.Diff(tc.expectedPost, goodRepo.SavedPost); diff != "" {
+			// the repository assigns the ID
+			saved := tc.expectedPost
+			saved.ID = 0
+			if diff := cmp.Diff(saved, goodRepo.SavedPost); diff != "" {
 				t.Fatalf("Expected post to be saved: %s", diff)
 			}
 		})
 	}
 }
+
+func TestCreate_ReturnsAndPublishesThePostAsStored(t *testing.T) {
+	repo := memory.NewRepository(examplePosts...)
+	repo.DeletePost(3)
+	publisher := new(events.Recorder)
+
+	post, err := useCases.CreatePost(repo, publisher, render.Skip, entities.Post{Title: "Foo", Content: "Bar"})
+
+	expected := entities.Post{ID: 4, Title: "Foo", Content: "Bar"}
+	if err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	if diff := cmp.Diff(expected, post); diff != "" {
+		t.Fatalf("Expected the post with its assigned ID: %s", diff)
+	}
+	if diff := cmp.Diff([]events.Event{events.PostCreated{Post: expected}}, publisher.Events()); diff != "" {
+		t.Fatalf("Expected the event to carry the assigned ID: %s", diff)
+	}
+}
+
+func TestCreate_ReturnsRenderedContent(t *testing.T) {
+	publisher := new(events.Recorder)
+	post, err := useCases.CreatePost(db.NewGoodRepository(examplePosts), publisher, render.NewCache(10), entities.Post{Title: "Foo", Content: "**Bar**"})
+
+	if err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	if post.ContentHTML != "<p><strong>Bar</strong></p>\n" {
+		t.Fatalf("Expected HTML for the new post; Got: '%s'", post.ContentHTML)
+	}
+	if created := publisher.Events()[0].(events.PostCreated); created.Post.ContentHTML != "" {
+		t.Fatalf("Expected the event to carry the post as stored; Got: '%v'", created.Post)
+	}
+}
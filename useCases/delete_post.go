@@ -2,20 +2,30 @@ package useCases
 
 import (
 	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/events"
 	"github.com/steve-kaufman/postsService/interfaces"
 )
 
-func DeletePost(getter interfaces.PostGetter, deleter interfaces.PostDeleter, id int) (entities.Post, error) {
-	post, err := GetOnePost(getter, id)
+func DeletePost(runner interfaces.TxRunner, publisher events.Publisher, id int) (entities.Post, error) {
+	var deleted entities.Post
+	err := runner.WithinTx(func(repo interfaces.Repository) error {
+		post, err := getOnePost(repo, "DeletePost", id)
+		if err != nil {
+			return err
+		}
+		deleted, err = attemptDelete(repo, id, post)
+		return err
+	})
 	if err != nil {
-		return entities.Post{}, err
+		return entities.Post{}, determineError("DeletePost", id, err)
 	}
-	return attemptDelete(deleter, id, post)
+	publisher.Publish(events.PostDeleted{Post: deleted})
+	return deleted, nil
 }
 
 func attemptDelete(deleter interfaces.PostDeleter, id int, post entities.Post) (entities.Post, error) {
 	if err := deleter.DeletePost(id); err != nil {
-		return entities.Post{}, determineError(deleter.DeletePost(id))
+		return entities.Post{}, determineError("DeletePost", id, err)
 	}
 	return post, nil
 }
@@ -1,28 +1,34 @@
 package useCases_test
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/steve-kaufman/postsService/db"
 	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/events"
 	"github.com/steve-kaufman/postsService/useCases"
 )
 
 func TestDelete_ReturnsErrInternal_FromBadRepo(t *testing.T) {
 	repo := new(db.BadRepository)
-	deletedPost, err := useCases.DeletePost(repo, repo, 1)
+	publisher := new(events.Recorder)
+	deletedPost, err := useCases.DeletePost(repo, publisher, 1)
 
 	if err == nil {
 		t.Fatal("Expected an error")
 	}
-	if err != useCases.ErrInternal {
+	if !errors.Is(err, useCases.ErrInternal) {
 		t.Fatalf("Expected ErrInternal; Got: '%v'", err)
 	}
 	if (deletedPost != entities.Post{}) {
 		t.Fatalf("Expected post to be empty; Got: '%v'", deletedPost)
 	}
+	if len(publisher.Events()) != 0 {
+		t.Fatalf("Expected no events; Got: '%v'", publisher.Events())
+	}
 }
 
 func TestDelete_ReturnsErrNotFound_FromGoodRepoWithBadID(t *testing.T) {
@@ -31,7 +37,8 @@ func TestDelete_ReturnsErrNotFound_FromGoodRepoWithBadID(t *testing.T) {
 	for _, id := range badIDs {
 		t.Run(fmt.Sprint(id), func(t *testing.T) {
 			repo := db.NewGoodRepository(examplePosts)
-			_, err := useCases.DeletePost(repo, repo, id)
+			publisher := new(events.Recorder)
+			_, err := useCases.DeletePost(repo, publisher, id)
 
 			if err != useCases.ErrNotFound {
 				t.Fatalf("Expected useCases.ErrNotFound; Got: '%v'", err)
@@ -46,7 +53,8 @@ func TestDelete_DeletesCorrectPost_FromGoodRepo(t *testing.T) {
 	for _, id := range goodIDs {
 		t.Run(fmt.Sprint(id), func(t *testing.T) {
 			repo := db.NewGoodRepository(examplePosts)
-			post, err := useCases.DeletePost(repo, repo, id)
+			publisher := new(events.Recorder)
+			post, err := useCases.DeletePost(repo, publisher, id)
 
 			if err != nil {
 				t.Fatalf("Expected no error; Got: '%v'", err)
@@ -60,6 +68,11 @@ func TestDelete_DeletesCorrectPost_FromGoodRepo(t *testing.T) {
 			if diff := cmp.Diff(expectedPost, post); diff != "" {
 				t.Fatal("Expected returned post to be deleted post; Got:", diff)
 			}
+
+			expectedEvents := []events.Event{events.PostDeleted{Post: expectedPost}}
+			if diff := cmp.Diff(expectedEvents, publisher.Events()); diff != "" {
+				t.Fatal("Expected PostDeleted to be published; Got:", diff)
+			}
 		})
 	}
 }
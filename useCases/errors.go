@@ -1,7 +1,57 @@
 package useCases
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var ErrInternal = errors.New("internal error")
 var ErrNotFound = errors.New("post not found")
 var ErrCantChangeLikes = errors.New("likes cant be changed")
+
+// Error is what a use case returns when its repository fails. It matches
+// ErrInternal with errors.Is, and unwraps to the repository's own error.
+type Error struct {
+	// Op is the use case that failed, such as "DeletePost"
+	Op string
+	// ID is the post it was working on, or 0
+	ID  int
+	Err error
+}
+
+func (err *Error) Error() string {
+	if err.ID != 0 {
+		return fmt.Sprintf("%s post %d: %v: %v", err.Op, err.ID, ErrInternal, err.Err)
+	}
+	return fmt.Sprintf("%s: %v: %v", err.Op, ErrInternal, err.Err)
+}
+
+func (err *Error) Is(target error) bool {
+	return target == ErrInternal
+}
+
+func (err *Error) Unwrap() error {
+	return err.Err
+}
+
+// Public returns err as it may be shown to a client, with the cause of an
+// internal error left out
+func Public(err error) error {
+	if errors.Is(err, ErrInternal) {
+		return ErrInternal
+	}
+	return err
+}
+
+// determineError passes ErrNotFound and errors that are already an *Error
+// through, and wraps any other repository error for op on post id
+func determineError(op string, id int, err error) error {
+	var internal *Error
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return ErrNotFound
+	case errors.As(err, &internal):
+		return err
+	}
+	return &Error{Op: op, ID: id, Err: err}
+}
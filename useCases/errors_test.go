@@ -0,0 +1,76 @@
+package useCases_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/steve-kaufman/postsService/db"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/events"
+	"github.com/steve-kaufman/postsService/render"
+	"github.com/steve-kaufman/postsService/useCases"
+)
+
+func TestInternalErrors_KeepOperationPostAndCause(t *testing.T) {
+	tests := []struct {
+		op  string
+		id  int
+		run func() error
+	}{
+		{"GetOnePost", 2, func() error {
+			_, err := useCases.GetOnePost(new(db.BadRepository), render.Skip, 2)
+			return err
+		}},
+		{"DeletePost", 3, func() error {
+			_, err := useCases.DeletePost(new(db.BadRepository), new(events.Recorder), 3)
+			return err
+		}},
+		{"DislikePost", 1, func() error {
+			_, err := useCases.DislikePost(new(db.BadRepository), new(events.Recorder), render.Skip, 1)
+			return err
+		}},
+		{"CreatePost", 0, func() error {
+			_, err := useCases.CreatePost(new(db.BadRepository), new(events.Recorder), render.Skip, entities.Post{Title: "Foo"})
+			return err
+		}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.op, func(t *testing.T) {
+			err := tc.run()
+
+			var internal *useCases.Error
+			if !errors.As(err, &internal) {
+				t.Fatalf("Expected a *useCases.Error; Got: '%v'", err)
+			}
+			if internal.Op != tc.op || internal.ID != tc.id {
+				t.Fatalf("Expected %s on post %d; Got: %s on post %d", tc.op, tc.id, internal.Op, internal.ID)
+			}
+			if !errors.Is(err, useCases.ErrInternal) || !errors.Is(err, db.ErrBad) {
+				t.Fatalf("Expected ErrInternal caused by ErrBad; Got: '%v'", err)
+			}
+			if public := useCases.Public(err); public != useCases.ErrInternal {
+				t.Fatalf("Expected the public error to leave out the cause; Got: '%v'", public)
+			}
+		})
+	}
+}
+
+func TestInternalErrors_Message(t *testing.T) {
+	err := &useCases.Error{Op: "UpdatePost", ID: 4, Err: errors.New("disk I/O error")}
+
+	if err.Error() != "UpdatePost post 4: internal error: disk I/O error" {
+		t.Fatalf("Expected the operation, post and cause; Got: '%s'", err.Error())
+	}
+}
+
+func TestNotFound_IsReturnedBare(t *testing.T) {
+	_, err := useCases.DeletePost(db.NewGoodRepository(examplePosts), new(events.Recorder), 9)
+
+	if err != useCases.ErrNotFound {
+		t.Fatalf("Expected ErrNotFound itself; Got: '%v'", err)
+	}
+	if useCases.Public(err) != useCases.ErrNotFound {
+		t.Fatalf("Expected ErrNotFound to be public; Got: '%v'", useCases.Public(err))
+	}
+}
@@ -3,12 +3,54 @@ package useCases
 import (
 	"github.com/steve-kaufman/postsService/entities"
 	"github.com/steve-kaufman/postsService/interfaces"
+	"github.com/steve-kaufman/postsService/ranking"
 )
 
-func GetAllPosts(getter interfaces.PostsGetter) ([]entities.Post, error) {
+// GetAllPosts returns every post ordered by sort, one of the names
+// ranking.ParseSort accepts, or in the repository's own order when sort is
+// empty. Repositories that can't sort themselves are sorted in memory.
+func GetAllPosts(getter interfaces.PostsGetter, renderer interfaces.ContentRenderer, sort string) ([]entities.Post, error) {
+	posts, err := getAllPosts(getter, sort)
+	if err != nil {
+		return nil, err
+	}
+	rendered := make([]entities.Post, len(posts))
+	for i, post := range posts {
+		rendered[i] = withHTML(renderer, post)
+	}
+	return rendered, nil
+}
+
+func getAllPosts(getter interfaces.PostsGetter, sort string) ([]entities.Post, error) {
+	if sort == "" {
+		return getPosts(getter)
+	}
+	by, err := ranking.ParseSort(sort)
+	if err != nil {
+		return nil, err
+	}
+
+	if sorter, ok := getter.(interfaces.SortedPostsGetter); ok {
+		posts, err := sorter.GetSortedPosts(by)
+		if err != nil {
+			return nil, &Error{Op: "GetAllPosts", Err: err}
+		}
+		return posts, nil
+	}
+
+	posts, err := getPosts(getter)
+	if err != nil {
+		return nil, err
+	}
+	sorted := append([]entities.Post{}, posts...)
+	ranking.SortPosts(sorted, by, nil)
+	return sorted, nil
+}
+
+func getPosts(getter interfaces.PostsGetter) ([]entities.Post, error) {
 	posts, err := getter.GetPosts()
 	if err != nil {
-		return nil, ErrInternal
+		return nil, &Error{Op: "GetAllPosts", Err: err}
 	}
 	return posts, nil
 }
@@ -1,11 +1,15 @@
 package useCases_test
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/steve-kaufman/postsService/db"
 	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/ranking"
+	"github.com/steve-kaufman/postsService/render"
 	"github.com/steve-kaufman/postsService/useCases"
 )
 
@@ -35,21 +39,22 @@ var examplePosts = []entities.Post{
 
 func TestGetAll_ReturnsErrInternal_FromBadRepo(t *testing.T) {
 	repo := new(db.BadRepository)
-	posts, err := useCases.GetAllPosts(repo)
+	posts, err := useCases.GetAllPosts(repo, render.Skip, "")
 
 	if err == nil {
 		t.Fatal("Expected an error")
 	}
-	if err != useCases.ErrInternal {
+	if !errors.Is(err, useCases.ErrInternal) {
 		t.Fatalf("Expected ErrInternal; Got: '%v'", err)
 	}
 	if posts != nil {
 		t.Fatal("Expected no posts")
 	}
 }
+
 func TestGetAll_ReturnsPosts_FromGoodRepo(t *testing.T) {
 	repo := db.NewGoodRepository(examplePosts)
-	posts, err := useCases.GetAllPosts(repo)
+	posts, err := useCases.GetAllPosts(repo, render.Skip, "")
 
 	if err != nil {
 		t.Fatalf("Expected no error; Got: '%s'", err)
@@ -58,3 +63,87 @@ func TestGetAll_ReturnsPosts_FromGoodRepo(t *testing.T) {
 		t.Fatalf("Expected posts from database:\nDiff: %s", diff)
 	}
 }
+
+func TestGetAll_SortsPosts_FromRepoThatCantSort(t *testing.T) {
+	testCases := map[string][]int{
+		"new":           {3, 2, 1},
+		"top":           {2, 1, 3},
+		"hot":           {2, 1, 3},
+		"controversial": {2, 1, 3},
+	}
+
+	for sort, expectedIDs := range testCases {
+		t.Run(fmt.Sprintf("By %s", sort), func(t *testing.T) {
+			repo := db.NewGoodRepository(examplePosts)
+			posts, err := useCases.GetAllPosts(repo, render.Skip, sort)
+
+			if err != nil {
+				t.Fatalf("Expected no error; Got: '%v'", err)
+			}
+			ids := []int{}
+			for _, post := range posts {
+				ids = append(ids, post.ID)
+			}
+			if diff := cmp.Diff(expectedIDs, ids); diff != "" {
+				t.Fatalf("Expected posts in order: \n%s", diff)
+			}
+			if examplePosts[0].ID != 1 {
+				t.Fatal("Expected the repository's posts to be left unsorted")
+			}
+		})
+	}
+}
+
+type sortRecorder struct {
+	db.BadRepository
+	by ranking.Sort
+}
+
+func (recorder *sortRecorder) GetSortedPosts(by ranking.Sort) ([]entities.Post, error) {
+	recorder.by = by
+	return examplePosts, nil
+}
+
+func TestGetAll_LetsRepoSort_WhenItCan(t *testing.T) {
+	recorder := new(sortRecorder)
+	posts, err := useCases.GetAllPosts(recorder, render.Skip, "hot")
+
+	if err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	if recorder.by != ranking.SortHot {
+		t.Fatalf("Expected repository to sort by hot; Got: '%s'", recorder.by)
+	}
+	if diff := cmp.Diff(examplePosts, posts); diff != "" {
+		t.Fatalf("Expected posts as sorted by the repository: \n%s", diff)
+	}
+}
+
+func TestGetAll_ReturnsErrUnknownSort_WithBadSort(t *testing.T) {
+	repo := db.NewGoodRepository(examplePosts)
+	posts, err := useCases.GetAllPosts(repo, render.Skip, "random")
+
+	if err != ranking.ErrUnknownSort {
+		t.Fatalf("Expected ranking.ErrUnknownSort; Got: '%v'", err)
+	}
+	if posts != nil {
+		t.Fatal("Expected no posts")
+	}
+}
+
+func TestGetAll_ReturnsRenderedContent(t *testing.T) {
+	repo := db.NewGoodRepository(examplePosts)
+	posts, err := useCases.GetAllPosts(repo, render.NewCache(10), "top")
+
+	if err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	for _, post := range posts {
+		if post.ContentHTML != "<p>"+post.Content+"</p>\n" {
+			t.Fatalf("Expected HTML for post %d; Got: '%s'", post.ID, post.ContentHTML)
+		}
+	}
+	if examplePosts[0].ContentHTML != "" {
+		t.Fatal("Expected the repository's posts to be left alone")
+	}
+}
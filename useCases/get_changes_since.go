@@ -0,0 +1,33 @@
+package useCases
+
+import (
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/interfaces"
+)
+
+const DefaultChangesLimit = 100
+const MaxChangesLimit = 1000
+
+// GetChangesSince returns the changes after seq along with the sequence
+// number to ask from next time. A limit outside 1..MaxChangesLimit is
+// replaced by DefaultChangesLimit or MaxChangesLimit.
+func GetChangesSince(getter interfaces.ChangesGetter, seq int64, limit int) ([]entities.Change, int64, error) {
+	if seq < 0 {
+		seq = 0
+	}
+	if limit <= 0 {
+		limit = DefaultChangesLimit
+	}
+	if limit > MaxChangesLimit {
+		limit = MaxChangesLimit
+	}
+
+	changes, err := getter.GetChangesSince(seq, limit)
+	if err != nil {
+		return nil, seq, &Error{Op: "GetChangesSince", Err: err}
+	}
+	if len(changes) > 0 {
+		seq = changes[len(changes)-1].Seq
+	}
+	return changes, seq, nil
+}
@@ -0,0 +1,92 @@
+package useCases_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/steve-kaufman/postsService/db"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/useCases"
+)
+
+func TestGetChangesSince_ReturnsErrInternal_FromBadRepo(t *testing.T) {
+	repo := new(db.BadRepository)
+	changes, seq, err := useCases.GetChangesSince(repo, 5, 10)
+
+	if !errors.Is(err, useCases.ErrInternal) {
+		t.Fatalf("Expected ErrInternal; Got: '%v'", err)
+	}
+	if changes != nil {
+		t.Fatalf("Expected no changes; Got: '%v'", changes)
+	}
+	if seq != 5 {
+		t.Fatalf("Expected high-water mark to stay at 5; Got: %d", seq)
+	}
+}
+
+func TestGetChangesSince_ReturnsChangesAndHighWaterMark(t *testing.T) {
+	testCases := []struct {
+		seq         int64
+		limit       int
+		expectedIDs []int
+		expectedSeq int64
+	}{
+		{seq: 0, limit: 10, expectedIDs: []int{1, 2, 3}, expectedSeq: 3},
+		{seq: 0, limit: 2, expectedIDs: []int{1, 2}, expectedSeq: 2},
+		{seq: 2, limit: 10, expectedIDs: []int{3}, expectedSeq: 3},
+		{seq: 3, limit: 10, expectedIDs: []int{}, expectedSeq: 3},
+		{seq: -4, limit: 1, expectedIDs: []int{1}, expectedSeq: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("Since %d limit %d", tc.seq, tc.limit), func(t *testing.T) {
+			repo := db.NewGoodRepository(examplePosts)
+			changes, seq, err := useCases.GetChangesSince(repo, tc.seq, tc.limit)
+
+			if err != nil {
+				t.Fatalf("Expected no error; Got: '%v'", err)
+			}
+			ids := []int{}
+			for _, change := range changes {
+				ids = append(ids, change.Post.ID)
+			}
+			if diff := cmp.Diff(tc.expectedIDs, ids); diff != "" {
+				t.Fatalf("Expected changed posts to match: \n%s", diff)
+			}
+			if seq != tc.expectedSeq {
+				t.Fatalf("Expected high-water mark %d; Got: %d", tc.expectedSeq, seq)
+			}
+		})
+	}
+}
+
+type limitRecorder struct {
+	limit int
+}
+
+func (recorder *limitRecorder) GetChangesSince(seq int64, limit int) ([]entities.Change, error) {
+	recorder.limit = limit
+	return nil, nil
+}
+
+func TestGetChangesSince_BoundsLimit(t *testing.T) {
+	testCases := map[int]int{
+		-1:   useCases.DefaultChangesLimit,
+		0:    useCases.DefaultChangesLimit,
+		50:   50,
+		5000: useCases.MaxChangesLimit,
+	}
+
+	for limit, expected := range testCases {
+		t.Run(fmt.Sprintf("With limit %d", limit), func(t *testing.T) {
+			recorder := new(limitRecorder)
+			useCases.GetChangesSince(recorder, 0, limit)
+
+			if recorder.limit != expected {
+				t.Fatalf("Expected limit %d; Got: %d", expected, recorder.limit)
+			}
+		})
+	}
+}
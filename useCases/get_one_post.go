@@ -5,17 +5,23 @@ import (
 	"github.com/steve-kaufman/postsService/interfaces"
 )
 
-func GetOnePost(getter interfaces.PostGetter, id int) (entities.Post, error) {
+func GetOnePost(getter interfaces.PostGetter, renderer interfaces.ContentRenderer, id int) (entities.Post, error) {
+	post, err := getOnePost(getter, "GetOnePost", id)
+	if err != nil {
+		return entities.Post{}, err
+	}
+	return withHTML(renderer, post), nil
+}
+
+func getOnePost(getter interfaces.PostGetter, op string, id int) (entities.Post, error) {
 	post, err := getter.GetPost(id)
 	if err != nil {
-		return entities.Post{}, determineError(err)
+		return entities.Post{}, determineError(op, id, err)
 	}
 	return post, nil
 }
 
-func determineError(err error) error {
-	if err == ErrNotFound {
-		return ErrNotFound
-	}
-	return ErrInternal
+func withHTML(renderer interfaces.ContentRenderer, post entities.Post) entities.Post {
+	post.ContentHTML = renderer.RenderContent(post)
+	return post
 }
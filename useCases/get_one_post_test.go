@@ -1,12 +1,14 @@
 package useCases_test
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/steve-kaufman/postsService/db"
 	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/render"
 	"github.com/steve-kaufman/postsService/useCases"
 )
 
@@ -16,12 +18,12 @@ func TestGetOne_ReturnsErrInternal_FromBadRepo(t *testing.T) {
 	for _, id := range testIDs {
 		t.Run(fmt.Sprintf("With ID '%d'", id), func(t *testing.T) {
 			repo := new(db.BadRepository)
-			post, err := useCases.GetOnePost(repo, id)
+			post, err := useCases.GetOnePost(repo, render.Skip, id)
 
 			if err == nil {
 				t.Fatal("Expected an error")
 			}
-			if err != useCases.ErrInternal {
+			if !errors.Is(err, useCases.ErrInternal) {
 				t.Fatalf("Expected ErrInternal; Got: '%v'", err)
 			}
 			if (post != entities.Post{}) {
@@ -37,7 +39,7 @@ func TestGetOne_ReturnsErrNotFound_FromGoodRepoWithBadID(t *testing.T) {
 	for _, id := range outOfBoundsIDs {
 		t.Run(fmt.Sprintf("With ID '%d'", id), func(t *testing.T) {
 			repo := db.NewGoodRepository(examplePosts)
-			post, err := useCases.GetOnePost(repo, id)
+			post, err := useCases.GetOnePost(repo, render.Skip, id)
 
 			if err == nil {
 				t.Fatal("Expected an error")
@@ -58,7 +60,7 @@ func TestGetOne_ReturnsCorrrectPost_FromGoodRepo(t *testing.T) {
 	for _, id := range testIDs {
 		t.Run(fmt.Sprintf("With ID '%d'", id), func(t *testing.T) {
 			repo := db.NewGoodRepository(examplePosts)
-			post, err := useCases.GetOnePost(repo, id)
+			post, err := useCases.GetOnePost(repo, render.Skip, id)
 
 			if err != nil {
 				t.Fatalf("Expected no error; Got: '%v'", err)
@@ -70,3 +72,18 @@ func TestGetOne_ReturnsCorrrectPost_FromGoodRepo(t *testing.T) {
 		})
 	}
 }
+
+func TestGetOne_ReturnsRenderedContent(t *testing.T) {
+	repo := db.NewGoodRepository([]entities.Post{{ID: 1, Title: "Foo", Content: "Some **bold** <b>text</b>"}})
+	post, err := useCases.GetOnePost(repo, render.NewCache(10), 1)
+
+	if err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	if post.Content != "Some **bold** <b>text</b>" {
+		t.Fatalf("Expected the raw content; Got: '%s'", post.Content)
+	}
+	if post.ContentHTML != "<p>Some <strong>bold</strong> &lt;b&gt;text&lt;/b&gt;</p>\n" {
+		t.Fatalf("Expected sanitized HTML; Got: '%s'", post.ContentHTML)
+	}
+}
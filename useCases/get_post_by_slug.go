@@ -0,0 +1,17 @@
+package useCases
+
+import (
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/interfaces"
+)
+
+// GetPostBySlug returns the post with slug. If slug is one the post had
+// before its title changed, the returned post's Slug is its current one,
+// and callers serving URLs should redirect to it.
+func GetPostBySlug(getter interfaces.SlugGetter, renderer interfaces.ContentRenderer, slug string) (entities.Post, error) {
+	post, err := getter.GetPostBySlug(slug)
+	if err != nil {
+		return entities.Post{}, determineError("GetPostBySlug", 0, err)
+	}
+	return withHTML(renderer, post), nil
+}
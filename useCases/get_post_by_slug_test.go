@@ -0,0 +1,54 @@
+package useCases_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/steve-kaufman/postsService/db"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/render"
+	"github.com/steve-kaufman/postsService/useCases"
+)
+
+var sluggedPosts = []entities.Post{
+	{ID: 1, Slug: "hello-world", Title: "Hello, World", Content: "*hi*"},
+	{ID: 2, Slug: "second-post", Title: "Second post"},
+}
+
+func TestGetPostBySlug_ReturnsErrInternal_FromBadRepo(t *testing.T) {
+	post, err := useCases.GetPostBySlug(new(db.BadRepository), render.Skip, "hello-world")
+
+	if !errors.Is(err, useCases.ErrInternal) {
+		t.Fatalf("Expected ErrInternal; Got: '%v'", err)
+	}
+	if (post != entities.Post{}) {
+		t.Fatalf("Expected empty post; Got: '%v'", post)
+	}
+}
+
+func TestGetPostBySlug_ReturnsErrNotFound_WithUnknownSlug(t *testing.T) {
+	repo := db.NewGoodRepository(sluggedPosts)
+	post, err := useCases.GetPostBySlug(repo, render.Skip, "nope")
+
+	if err != useCases.ErrNotFound {
+		t.Fatalf("Expected ErrNotFound; Got: '%v'", err)
+	}
+	if (post != entities.Post{}) {
+		t.Fatalf("Expected empty post; Got: '%v'", post)
+	}
+}
+
+func TestGetPostBySlug_ReturnsRenderedPost(t *testing.T) {
+	repo := db.NewGoodRepository(sluggedPosts)
+	post, err := useCases.GetPostBySlug(repo, render.NewCache(10), "hello-world")
+
+	if err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	expected := sluggedPosts[0]
+	expected.ContentHTML = "<p><em>hi</em></p>\n"
+	if diff := cmp.Diff(expected, post); diff != "" {
+		t.Fatalf("Expected post 1: \n%s", diff)
+	}
+}
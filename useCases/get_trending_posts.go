@@ -0,0 +1,35 @@
+package useCases
+
+import (
+	"errors"
+	"time"
+
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/interfaces"
+	"github.com/steve-kaufman/postsService/trending"
+)
+
+// GetTrendingPosts returns the posts trending over window, most trending
+// first. Posts deleted since they were ranked are left out.
+func GetTrendingPosts(trends interfaces.TrendingGetter, getter interfaces.PostGetter, renderer interfaces.ContentRenderer, window time.Duration) ([]entities.Post, error) {
+	ids, err := trends.GetTrending(window)
+	if errors.Is(err, trending.ErrUnknownWindow) {
+		return nil, trending.ErrUnknownWindow
+	}
+	if err != nil {
+		return nil, &Error{Op: "GetTrendingPosts", Err: err}
+	}
+
+	posts := []entities.Post{}
+	for _, id := range ids {
+		post, err := getter.GetPost(id)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, &Error{Op: "GetTrendingPosts", ID: id, Err: err}
+		}
+		posts = append(posts, withHTML(renderer, post))
+	}
+	return posts, nil
+}
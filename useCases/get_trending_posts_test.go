@@ -0,0 +1,90 @@
+package useCases_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/steve-kaufman/postsService/db"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/interfaces"
+	"github.com/steve-kaufman/postsService/render"
+	"github.com/steve-kaufman/postsService/trending"
+	"github.com/steve-kaufman/postsService/useCases"
+)
+
+type fakeTrends struct {
+	ids []int
+	err error
+}
+
+func (trends fakeTrends) GetTrending(window time.Duration) ([]int, error) {
+	return trends.ids, trends.err
+}
+
+func TestGetTrendingPosts_ReturnsPostsInTrendingOrder(t *testing.T) {
+	trends := fakeTrends{ids: []int{3, 1}}
+	posts, err := useCases.GetTrendingPosts(trends, db.NewGoodRepository(examplePosts), render.Skip, time.Hour)
+
+	if err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	expected := []entities.Post{examplePosts[2], examplePosts[0]}
+	if diff := cmp.Diff(expected, posts); diff != "" {
+		t.Fatalf("Expected trending posts: \n%s", diff)
+	}
+}
+
+func TestGetTrendingPosts_SkipsDeletedPosts(t *testing.T) {
+	trends := fakeTrends{ids: []int{9, 2}}
+	posts, err := useCases.GetTrendingPosts(trends, db.NewGoodRepository(examplePosts), render.Skip, time.Hour)
+
+	if err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	if diff := cmp.Diff([]entities.Post{examplePosts[1]}, posts); diff != "" {
+		t.Fatalf("Expected missing post to be skipped: \n%s", diff)
+	}
+}
+
+func TestGetTrendingPosts_ReturnsErrors(t *testing.T) {
+	testCases := []struct {
+		name        string
+		trends      fakeTrends
+		getter      interfaces.PostGetter
+		expectedErr error
+	}{
+		{
+			name:        "Unknown window",
+			trends:      fakeTrends{err: trending.ErrUnknownWindow},
+			getter:      db.NewGoodRepository(examplePosts),
+			expectedErr: trending.ErrUnknownWindow,
+		},
+		{
+			name:        "Failing trends",
+			trends:      fakeTrends{err: errors.New("boom")},
+			getter:      db.NewGoodRepository(examplePosts),
+			expectedErr: useCases.ErrInternal,
+		},
+		{
+			name:        "Bad repo",
+			trends:      fakeTrends{ids: []int{1}},
+			getter:      new(db.BadRepository),
+			expectedErr: useCases.ErrInternal,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			posts, err := useCases.GetTrendingPosts(tc.trends, tc.getter, render.Skip, time.Hour)
+
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("Expected '%v'; Got: '%v'", tc.expectedErr, err)
+			}
+			if posts != nil {
+				t.Fatalf("Expected no posts; Got: '%v'", posts)
+			}
+		})
+	}
+}
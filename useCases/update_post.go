@@ -2,24 +2,30 @@ package useCases
 
 import (
 	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/events"
 	"github.com/steve-kaufman/postsService/interfaces"
 )
 
-func UpdatePost(getter interfaces.PostGetter, updater interfaces.PostUpdater, id int, updateData entities.Post) (entities.Post, error) {
-	post, err := getter.GetPost(id)
-	if err != nil {
-		return entities.Post{}, determineError(err)
+func UpdatePost(runner interfaces.TxRunner, publisher events.Publisher, renderer interfaces.ContentRenderer, id int, updateData entities.Post) (entities.Post, error) {
+	if err := verifyFields(updateData); err != nil {
+		return entities.Post{}, err
 	}
-	return verifyFieldsAndUpdatePost(post, updater, id, updateData)
-}
 
-func verifyFieldsAndUpdatePost(original entities.Post, updater interfaces.PostUpdater, id int, updateData entities.Post) (entities.Post, error) {
-	err := verifyFields(updateData)
+	var original, updated entities.Post
+	err := runner.WithinTx(func(repo interfaces.Repository) error {
+		var err error
+		original, err = repo.GetPost(id)
+		if err != nil {
+			return err
+		}
+		updated, err = attemptUpdatePost(repo, "UpdatePost", updateFields(original, updateData), id)
+		return err
+	})
 	if err != nil {
-		return entities.Post{}, err
+		return entities.Post{}, determineError("UpdatePost", id, err)
 	}
-	post := updateFields(original, updateData)
-	return attemptUpdatePost(updater, post, id)
+	publisher.Publish(events.PostUpdated{Before: original, After: updated})
+	return withHTML(renderer, updated), nil
 }
 
 func verifyFields(updateData entities.Post) error {
@@ -39,10 +45,10 @@ func updateFields(original entities.Post, updateData entities.Post) entities.Pos
 	return original
 }
 
-func attemptUpdatePost(updater interfaces.PostUpdater, post entities.Post, id int) (entities.Post, error) {
+func attemptUpdatePost(updater interfaces.PostUpdater, op string, post entities.Post, id int) (entities.Post, error) {
 	err := updater.UpdatePost(id, post)
 	if err != nil {
-		return entities.Post{}, determineError(err)
+		return entities.Post{}, determineError(op, id, err)
 	}
 	return post, nil
 }
@@ -1,23 +1,28 @@
 package useCases_test
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/steve-kaufman/postsService/db"
 	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/events"
+	"github.com/steve-kaufman/postsService/render"
 	"github.com/steve-kaufman/postsService/useCases"
 )
 
 func TestUpdate_ReturnsErrInternal_FromBadRepo(t *testing.T) {
 	repo := new(db.BadRepository)
-	_, err := useCases.UpdatePost(repo, repo, 1, entities.Post{Title: "Foo"})
+	publisher := new(events.Recorder)
+	_, err := useCases.UpdatePost(repo, publisher, render.Skip, 1, entities.Post{Title: "Foo"})
 
 	if err == nil {
 		t.Fatal("Expected an error")
 	}
-	if err != useCases.ErrInternal {
+	if !errors.Is(err, useCases.ErrInternal) {
 		t.Fatalf("Expected ErrInternal; Got: '%v'", err)
 	}
 }
@@ -28,7 +33,8 @@ func TestUpdate_ReturnsErrNotFound_FromGoodRepoWithBadID(t *testing.T) {
 	for _, id := range badIDs {
 		t.Run(fmt.Sprint(id), func(t *testing.T) {
 			repo := db.NewGoodRepository(examplePosts)
-			_, err := useCases.UpdatePost(repo, repo, 0, entities.Post{Title: "Foo"})
+			publisher := new(events.Recorder)
+			_, err := useCases.UpdatePost(repo, publisher, render.Skip, 0, entities.Post{Title: "Foo"})
 
 			if err != useCases.ErrNotFound {
 				t.Fatalf("Expected useCases.ErrNotFound; Got: '%v'", err)
@@ -88,7 +94,8 @@ func TestUpdate_WithGoodRepo(t *testing.T) {
 	for _, tc := range updateTests {
 		t.Run(tc.name, func(t *testing.T) {
 			repo := db.NewGoodRepository(examplePosts)
-			post, err := useCases.UpdatePost(repo, repo, tc.inputID, tc.updateData)
+			publisher := new(events.Recorder)
+			post, err := useCases.UpdatePost(repo, publisher, render.Skip, tc.inputID, tc.updateData)
 
 			if err != tc.expectedError {
 				t.Fatalf("Expected error '%v'; Got: '%v'", tc.expectedError, err)
@@ -101,6 +108,29 @@ func TestUpdate_WithGoodRepo(t *testing.T) {
 			if diff := cmp.Diff(tc.expectedPost, repo.UpdatedPost); diff != "" {
 				t.Fatalf("Expected post to be updated: \n%s", diff)
 			}
+
+			expectedEvents := []events.Event{}
+			if tc.expectedError == nil {
+				expectedEvents = append(expectedEvents, events.PostUpdated{
+					Before: examplePosts[tc.inputID-1],
+					After:  tc.expectedPost,
+				})
+			}
+			if diff := cmp.Diff(expectedEvents, publisher.Events(), cmpopts.EquateEmpty()); diff != "" {
+				t.Fatalf("Expected published events to match: \n%s", diff)
+			}
 		})
 	}
 }
+
+func TestUpdate_ReturnsRenderedContent(t *testing.T) {
+	repo := db.NewGoodRepository(examplePosts)
+	post, err := useCases.UpdatePost(repo, new(events.Recorder), render.NewCache(10), 1, entities.Post{Content: "*Foo*"})
+
+	if err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	if post.ContentHTML != "<p><em>Foo</em></p>\n" {
+		t.Fatalf("Expected HTML for the updated content; Got: '%s'", post.ContentHTML)
+	}
+}
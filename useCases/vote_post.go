@@ -0,0 +1,37 @@
+package useCases
+
+import (
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/events"
+	"github.com/steve-kaufman/postsService/interfaces"
+)
+
+func LikePost(runner interfaces.TxRunner, publisher events.Publisher, renderer interfaces.ContentRenderer, id int) (entities.Post, error) {
+	return votePost(runner, publisher, renderer, "LikePost", id, true)
+}
+
+func DislikePost(runner interfaces.TxRunner, publisher events.Publisher, renderer interfaces.ContentRenderer, id int) (entities.Post, error) {
+	return votePost(runner, publisher, renderer, "DislikePost", id, false)
+}
+
+func votePost(runner interfaces.TxRunner, publisher events.Publisher, renderer interfaces.ContentRenderer, op string, id int, like bool) (entities.Post, error) {
+	var voted entities.Post
+	err := runner.WithinTx(func(repo interfaces.Repository) error {
+		post, err := repo.GetPost(id)
+		if err != nil {
+			return err
+		}
+		if like {
+			post.Likes++
+		} else {
+			post.Dislikes++
+		}
+		voted, err = attemptUpdatePost(repo, op, post, id)
+		return err
+	})
+	if err != nil {
+		return entities.Post{}, determineError(op, id, err)
+	}
+	publisher.Publish(events.PostVoted{Post: voted, Liked: like})
+	return withHTML(renderer, voted), nil
+}
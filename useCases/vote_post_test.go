@@ -0,0 +1,132 @@
+package useCases_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/steve-kaufman/postsService/db"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/events"
+	"github.com/steve-kaufman/postsService/interfaces"
+	"github.com/steve-kaufman/postsService/render"
+	"github.com/steve-kaufman/postsService/useCases"
+)
+
+type voteFunc func(interfaces.TxRunner, events.Publisher, interfaces.ContentRenderer, int) (entities.Post, error)
+
+var voteFuncs = map[string]voteFunc{
+	"Like":    useCases.LikePost,
+	"Dislike": useCases.DislikePost,
+}
+
+func TestVote_ReturnsErrInternal_FromBadRepo(t *testing.T) {
+	for name, vote := range voteFuncs {
+		t.Run(name, func(t *testing.T) {
+			publisher := new(events.Recorder)
+			post, err := vote(new(db.BadRepository), publisher, render.Skip, 1)
+
+			if !errors.Is(err, useCases.ErrInternal) {
+				t.Fatalf("Expected ErrInternal; Got: '%v'", err)
+			}
+			if (post != entities.Post{}) {
+				t.Fatalf("Expected empty post; Got: '%v'", post)
+			}
+			if len(publisher.Events()) != 0 {
+				t.Fatalf("Expected no events; Got: '%v'", publisher.Events())
+			}
+		})
+	}
+}
+
+func TestVote_ReturnsErrNotFound_FromGoodRepoWithBadID(t *testing.T) {
+	badIDs := []int{-1, 0, 4, 10}
+
+	for name, vote := range voteFuncs {
+		for _, id := range badIDs {
+			t.Run(fmt.Sprintf("%s %d", name, id), func(t *testing.T) {
+				repo := db.NewGoodRepository(examplePosts)
+				_, err := vote(repo, new(events.Recorder), render.Skip, id)
+
+				if err != useCases.ErrNotFound {
+					t.Fatalf("Expected ErrNotFound; Got: '%v'", err)
+				}
+			})
+		}
+	}
+}
+
+func TestVote_IncrementsCountAndPublishes(t *testing.T) {
+	tests := []struct {
+		name         string
+		vote         voteFunc
+		id           int
+		expectedPost entities.Post
+		liked        bool
+	}{
+		{
+			name: "Like post 1",
+			vote: useCases.LikePost,
+			id:   1,
+			expectedPost: entities.Post{
+				ID:       1,
+				Title:    "Post 1",
+				Content:  "Content of Post 1",
+				Likes:    3,
+				Dislikes: 1,
+			},
+			liked: true,
+		},
+		{
+			name: "Dislike post 3",
+			vote: useCases.DislikePost,
+			id:   3,
+			expectedPost: entities.Post{
+				ID:       3,
+				Title:    "Post 3",
+				Content:  "Content of Post 3",
+				Likes:    0,
+				Dislikes: 11,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := db.NewGoodRepository(examplePosts)
+			publisher := new(events.Recorder)
+			post, err := tc.vote(repo, publisher, render.Skip, tc.id)
+
+			if err != nil {
+				t.Fatalf("Expected no error; Got: '%v'", err)
+			}
+			if diff := cmp.Diff(tc.expectedPost, post); diff != "" {
+				t.Fatalf("Expected voted post: \n%s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedPost, repo.UpdatedPost); diff != "" {
+				t.Fatalf("Expected post to be updated: \n%s", diff)
+			}
+			expectedEvents := []events.Event{events.PostVoted{Post: tc.expectedPost, Liked: tc.liked}}
+			if diff := cmp.Diff(expectedEvents, publisher.Events()); diff != "" {
+				t.Fatalf("Expected PostVoted to be published: \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestVote_ReturnsRenderedContent(t *testing.T) {
+	for name, vote := range voteFuncs {
+		t.Run(name, func(t *testing.T) {
+			repo := db.NewGoodRepository(examplePosts)
+			post, err := vote(repo, new(events.Recorder), render.NewCache(10), 1)
+
+			if err != nil {
+				t.Fatalf("Expected no error; Got: '%v'", err)
+			}
+			if post.ContentHTML != "<p>Content of Post 1</p>\n" {
+				t.Fatalf("Expected HTML for the post; Got: '%s'", post.ContentHTML)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/steve-kaufman/postsService/backoff"
+	"github.com/steve-kaufman/postsService/events"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// Sign returns the value of SignatureHeader for body signed with secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is a valid SignatureHeader for body
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// Dispatcher turns events into deliveries for every interested subscription
+// and sends due deliveries, retrying failures with exponential backoff
+type Dispatcher struct {
+	Client    *http.Client
+	BatchSize int
+	// Concurrency is how many subscriptions are sent to at once
+	Concurrency int
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// Now is the dispatcher's clock
+	Now func() time.Time
+	// OnError is told when Run fails to send a batch, which it retries at
+	// the next tick. It logs with slog's default logger unless replaced.
+	OnError func(err error)
+
+	store Store
+}
+
+func NewDispatcher(store Store) *Dispatcher {
+	return &Dispatcher{
+		Client:      &http.Client{Timeout: 10 * time.Second},
+		BatchSize:   100,
+		Concurrency: 8,
+		MaxAttempts: 8,
+		BaseBackoff: 5 * time.Second,
+		MaxBackoff:  time.Hour,
+		Now:         time.Now,
+		OnError:     logError,
+		store:       store,
+	}
+}
+
+// payload is the body of every webhook request
+type payload struct {
+	Event     string          `json:"event"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Enqueue records a pending delivery of event for each subscription that
+// wants it. It fits both events.Bus handlers and outbox.DeliverFunc.
+func (dispatcher *Dispatcher) Enqueue(event events.Event) error {
+	subs, err := dispatcher.store.GetSubscriptions()
+	if err != nil {
+		return err
+	}
+
+	data, err := events.Marshal(event)
+	if err != nil {
+		return err
+	}
+	now := dispatcher.Now()
+	body, err := json.Marshal(payload{Event: event.EventName(), CreatedAt: now.UTC(), Data: data})
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range subs {
+		if !sub.wants(event.EventName()) {
+			continue
+		}
+		err := dispatcher.store.SaveDelivery(Delivery{
+			SubscriptionID: sub.ID,
+			Event:          event.EventName(),
+			Payload:        body,
+			Status:         StatusPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run sends due deliveries every interval until ctx is done
+func (dispatcher *Dispatcher) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := dispatcher.DeliverDue()
+			if err != nil {
+				dispatcher.OnError(err)
+				break
+			}
+			if n < dispatcher.BatchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func logError(err error) {
+	slog.Error("webhook delivery failed", "error", err)
+}
+
+// DeliverDue attempts one batch of due deliveries and returns how many it
+// attempted. Each subscription's deliveries are sent in order, up to
+// Concurrency subscriptions at a time, so a slow endpoint only holds up
+// its own.
+func (dispatcher *Dispatcher) DeliverDue() (int, error) {
+	deliveries, err := dispatcher.store.DueDeliveries(dispatcher.Now(), dispatcher.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	queues := [][]Delivery{}
+	queueOf := map[int]int{}
+	for _, delivery := range deliveries {
+		i, ok := queueOf[delivery.SubscriptionID]
+		if !ok {
+			i = len(queues)
+			queueOf[delivery.SubscriptionID] = i
+			queues = append(queues, nil)
+		}
+		queues[i] = append(queues[i], delivery)
+	}
+
+	slots := make(chan struct{}, max(dispatcher.Concurrency, 1))
+	errs := make([]error, len(queues))
+	var wg sync.WaitGroup
+	for i, queue := range queues {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, queue []Delivery) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			for _, delivery := range queue {
+				if errs[i] = dispatcher.attempt(delivery); errs[i] != nil {
+					return
+				}
+			}
+		}(i, queue)
+	}
+	wg.Wait()
+	return len(deliveries), errors.Join(errs...)
+}
+
+// attempt sends delivery and records the outcome. Failing to look up its
+// subscription counts as a failed attempt, so one bad delivery can't hold
+// up the rest of the batch.
+func (dispatcher *Dispatcher) attempt(delivery Delivery) error {
+	delivery.Attempts++
+	sub, err := dispatcher.store.GetSubscription(delivery.SubscriptionID)
+	if err == nil {
+		delivery.ResponseStatus, err = dispatcher.send(sub, delivery)
+	}
+	if err == nil {
+		delivery.Status = StatusDelivered
+		delivery.LastError = ""
+		return dispatcher.store.UpdateDelivery(delivery)
+	}
+
+	delivery.LastError = err.Error()
+	// a subscription deleted since has nowhere to deliver to
+	if delivery.Attempts >= dispatcher.MaxAttempts || errors.Is(err, ErrNotFound) {
+		delivery.Status = StatusFailed
+	} else {
+		delivery.NextAttemptAt = dispatcher.Now().Add(backoff.Exponential(dispatcher.BaseBackoff, dispatcher.MaxBackoff, delivery.Attempts))
+	}
+	return dispatcher.store.UpdateDelivery(delivery)
+}
+
+// send posts the delivery and returns the response status; any non-2xx
+// status is an error
+func (dispatcher *Dispatcher) send(sub Subscription, delivery Delivery) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, sub.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, strconv.Itoa(delivery.ID))
+	req.Header.Set(SignatureHeader, Sign(sub.Secret, delivery.Payload))
+
+	resp, err := dispatcher.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,326 @@
+package webhooks_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/steve-kaufman/postsService/entities"
+	"github.com/steve-kaufman/postsService/events"
+	"github.com/steve-kaufman/postsService/webhooks"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var created = events.PostCreated{Post: entities.Post{ID: 1, Title: "Foo", Content: "Bar"}}
+var deleted = events.PostDeleted{Post: entities.Post{ID: 1, Title: "Foo", Content: "Bar"}}
+
+// receiver is an httptest endpoint that answers with the queued statuses
+// and then 200, recording every request it verifies
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	received []http.Header
+	bodies   [][]byte
+	badSigs  int
+}
+
+func newReceiver(t *testing.T, secret string, statuses ...int) *receiver {
+	rec := &receiver{statuses: statuses}
+	rec.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+
+		body, _ := ioutil.ReadAll(r.Body)
+		if !webhooks.Verify(secret, body, r.Header.Get(webhooks.SignatureHeader)) {
+			rec.badSigs++
+		}
+		rec.received = append(rec.received, r.Header)
+		rec.bodies = append(rec.bodies, body)
+
+		status := http.StatusOK
+		if len(rec.statuses) > 0 {
+			status, rec.statuses = rec.statuses[0], rec.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(rec.Close)
+	return rec
+}
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func setup(t *testing.T) (*webhooks.SqliteStore, *webhooks.Dispatcher, *clock) {
+	store, err := webhooks.NewSqliteStore(filepath.Join(t.TempDir(), "webhooks.db"))
+	if err != nil {
+		t.Fatalf("Expected no error opening store; Got: '%v'", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	c := &clock{now: time.Now()}
+	dispatcher := webhooks.NewDispatcher(store)
+	dispatcher.Now = c.Now
+	dispatcher.BaseBackoff = time.Second
+	dispatcher.MaxAttempts = 3
+	return store, dispatcher, c
+}
+
+func subscribe(t *testing.T, store webhooks.Store, url string, eventTypes ...string) webhooks.Subscription {
+	t.Helper()
+	sub, err := webhooks.Subscribe(store, webhooks.Subscription{URL: url, EventTypes: eventTypes, Secret: "s3cret"})
+	if err != nil {
+		t.Fatalf("Expected no error subscribing; Got: '%v'", err)
+	}
+	return sub
+}
+
+func TestSubscribe_ValidatesSubscription(t *testing.T) {
+	store, _, _ := setup(t)
+	tests := []struct {
+		name        string
+		sub         webhooks.Subscription
+		expectedErr error
+	}{
+		{"Relative URL", webhooks.Subscription{URL: "/hook", EventTypes: []string{"post.created"}, Secret: "s"}, webhooks.ErrInvalidURL},
+		{"FTP URL", webhooks.Subscription{URL: "ftp://example.com", EventTypes: []string{"post.created"}, Secret: "s"}, webhooks.ErrInvalidURL},
+		{"No event types", webhooks.Subscription{URL: "https://example.com", Secret: "s"}, webhooks.ErrNeedsEventTypes},
+		{"Unknown event type", webhooks.Subscription{URL: "https://example.com", EventTypes: []string{"post.liked"}, Secret: "s"}, webhooks.ErrUnknownEventType},
+		{"No secret", webhooks.Subscription{URL: "https://example.com", EventTypes: []string{"post.created"}}, webhooks.ErrNeedsSecret},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := webhooks.Subscribe(store, tc.sub); err != tc.expectedErr {
+				t.Fatalf("Expected '%v'; Got: '%v'", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestDispatcher_SendsSignedEventsToInterestedSubscriptions(t *testing.T) {
+	store, dispatcher, _ := setup(t)
+	createdOnly := newReceiver(t, "s3cret")
+	everything := newReceiver(t, "s3cret")
+	subscribe(t, store, createdOnly.URL, "post.created")
+	subscribe(t, store, everything.URL, "post.created", "post.deleted")
+
+	dispatcher.Enqueue(created)
+	dispatcher.Enqueue(deleted)
+	n, err := dispatcher.DeliverDue()
+
+	if err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	if n != 3 {
+		t.Fatalf("Expected 3 deliveries; Got: %d", n)
+	}
+	if len(createdOnly.received) != 1 || len(everything.received) != 2 {
+		t.Fatalf("Expected 1 and 2 requests; Got: %d and %d", len(createdOnly.received), len(everything.received))
+	}
+	if createdOnly.badSigs+everything.badSigs != 0 {
+		t.Fatal("Expected every request to carry a valid signature")
+	}
+	if event := everything.received[1].Get(webhooks.EventHeader); event != "post.deleted" {
+		t.Fatalf("Expected second event to be post.deleted; Got: '%s'", event)
+	}
+
+	var body struct {
+		Event string
+		Data  events.PostCreated
+	}
+	json.Unmarshal(createdOnly.bodies[0], &body)
+	if diff := cmp.Diff(created, body.Data); diff != "" || body.Event != "post.created" {
+		t.Fatalf("Expected body to carry the event: \n%s", diff)
+	}
+}
+
+func TestDispatcher_RetriesFailedDeliveriesWithBackoff(t *testing.T) {
+	store, dispatcher, c := setup(t)
+	rec := newReceiver(t, "s3cret", http.StatusInternalServerError, http.StatusServiceUnavailable)
+	sub := subscribe(t, store, rec.URL, "post.created")
+	dispatcher.Enqueue(created)
+
+	steps := []struct {
+		advance  time.Duration
+		expected int
+	}{
+		{0, 1},                      // 500, retry in 1s
+		{999 * time.Millisecond, 0}, // not due
+		{time.Millisecond, 1},       // 503, retry in 2s
+		{time.Second, 0},            // not due
+		{time.Second, 1},            // 200
+		{time.Hour, 0},              // nothing left
+	}
+	for i, step := range steps {
+		c.now = c.now.Add(step.advance)
+		if n, _ := dispatcher.DeliverDue(); n != step.expected {
+			t.Fatalf("Step %d: Expected %d attempts; Got: %d", i, step.expected, n)
+		}
+	}
+
+	deliveries, err := store.GetDeliveries(sub.ID)
+	if err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("Expected one delivery; Got: %d", len(deliveries))
+	}
+	delivery := deliveries[0]
+	if delivery.Status != webhooks.StatusDelivered || delivery.Attempts != 3 || delivery.ResponseStatus != 200 {
+		t.Fatalf("Expected delivered after 3 attempts with 200; Got: %+v", delivery)
+	}
+}
+
+func TestDispatcher_MarksDeliveryFailedAfterMaxAttempts(t *testing.T) {
+	store, dispatcher, c := setup(t)
+	rec := newReceiver(t, "s3cret", 500, 500, 500, 500)
+	sub := subscribe(t, store, rec.URL, "post.created")
+	dispatcher.Enqueue(created)
+
+	for i := 0; i < 5; i++ {
+		dispatcher.DeliverDue()
+		c.now = c.now.Add(time.Hour)
+	}
+
+	deliveries, _ := store.GetDeliveries(sub.ID)
+	delivery := deliveries[0]
+	if delivery.Status != webhooks.StatusFailed || delivery.Attempts != 3 {
+		t.Fatalf("Expected failed after 3 attempts; Got: %+v", delivery)
+	}
+	if delivery.ResponseStatus != 500 || delivery.LastError == "" {
+		t.Fatalf("Expected last response and error to be recorded; Got: %+v", delivery)
+	}
+}
+
+// flakyStore fails to look up one subscription
+type flakyStore struct {
+	*webhooks.SqliteStore
+	failing int
+	err     error
+}
+
+func (store flakyStore) GetSubscription(id int) (webhooks.Subscription, error) {
+	if id == store.failing {
+		return webhooks.Subscription{}, store.err
+	}
+	return store.SqliteStore.GetSubscription(id)
+}
+
+func TestDispatcher_KeepsDelivering_WhenASubscriptionCantBeRead(t *testing.T) {
+	store, _, c := setup(t)
+	rec := newReceiver(t, "s3cret")
+	broken := subscribe(t, store, rec.URL, "post.created")
+	working := subscribe(t, store, rec.URL, "post.created")
+	dispatcher := webhooks.NewDispatcher(flakyStore{store, broken.ID, errors.New("disk on fire")})
+	dispatcher.Now = c.Now
+	dispatcher.Enqueue(created)
+
+	n, err := dispatcher.DeliverDue()
+
+	if err != nil {
+		t.Fatalf("Expected no error; Got: '%v'", err)
+	}
+	if n != 2 || len(rec.received) != 1 {
+		t.Fatalf("Expected 2 attempts and 1 request; Got: %d and %d", n, len(rec.received))
+	}
+	deliveries, _ := store.GetDeliveries(broken.ID)
+	if delivery := deliveries[0]; delivery.Status != webhooks.StatusPending || delivery.Attempts != 1 || delivery.LastError == "" {
+		t.Fatalf("Expected the unreadable subscription's delivery to be retried; Got: %+v", delivery)
+	}
+	deliveries, _ = store.GetDeliveries(working.ID)
+	if delivery := deliveries[0]; delivery.Status != webhooks.StatusDelivered {
+		t.Fatalf("Expected the other delivery to be sent; Got: %+v", delivery)
+	}
+}
+
+func TestDispatcher_FailsDeliveries_WhoseSubscriptionIsGone(t *testing.T) {
+	store, _, c := setup(t)
+	sub := subscribe(t, store, "https://example.com", "post.created")
+	dispatcher := webhooks.NewDispatcher(flakyStore{store, sub.ID, webhooks.ErrNotFound})
+	dispatcher.Now = c.Now
+	dispatcher.Enqueue(created)
+
+	dispatcher.DeliverDue()
+
+	deliveries, _ := store.GetDeliveries(sub.ID)
+	if delivery := deliveries[0]; delivery.Status != webhooks.StatusFailed || delivery.Attempts != 1 {
+		t.Fatalf("Expected the delivery to fail at once; Got: %+v", delivery)
+	}
+}
+
+func TestDispatcher_DoesNotWaitOnASlowEndpoint(t *testing.T) {
+	store, dispatcher, _ := setup(t)
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(slow.Close)
+	fast := newReceiver(t, "s3cret")
+	subscribe(t, store, slow.URL, "post.created")
+	subscribe(t, store, fast.URL, "post.created")
+	dispatcher.Enqueue(created)
+
+	done := make(chan struct{})
+	go func() {
+		dispatcher.DeliverDue()
+		close(done)
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		fast.mu.Lock()
+		received := len(fast.received)
+		fast.mu.Unlock()
+		if received == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			close(release)
+			t.Fatal("Expected the fast endpoint to be sent to while the slow one hangs")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	<-done
+}
+
+type brokenStore struct {
+	webhooks.Store
+}
+
+func (brokenStore) DueDeliveries(now time.Time, limit int) ([]webhooks.Delivery, error) {
+	return nil, errors.New("disk on fire")
+}
+
+func TestRun_ReportsErrors(t *testing.T) {
+	dispatcher := webhooks.NewDispatcher(brokenStore{})
+	ctx, cancel := context.WithCancel(context.Background())
+	reported := 0
+	dispatcher.OnError = func(err error) {
+		reported++
+		if reported == 2 {
+			cancel()
+		}
+	}
+
+	if err := dispatcher.Run(ctx, time.Millisecond); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected Run to stop with the context; Got: '%v'", err)
+	}
+	if reported != 2 {
+		t.Fatalf("Expected each failed batch to be reported; Got: %d", reported)
+	}
+}
@@ -0,0 +1,180 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Handler serves the webhook management API under /webhooks:
+//
+//	GET    /webhooks                 list subscriptions
+//	POST   /webhooks                 create a subscription
+//	GET    /webhooks/{id}            get a subscription
+//	DELETE /webhooks/{id}            delete a subscription
+//	GET    /webhooks/{id}/deliveries list a subscription's deliveries
+//
+// Secrets are accepted on create but never returned.
+type Handler struct {
+	store Store
+}
+
+func NewHandler(store Store) *Handler {
+	return &Handler{store: store}
+}
+
+type subscriptionRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+}
+
+type subscriptionResponse struct {
+	ID        int       `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type deliveryResponse struct {
+	ID             int       `json:"id"`
+	Event          string    `json:"event"`
+	Status         string    `json:"status"`
+	Attempts       int       `json:"attempts"`
+	ResponseStatus int       `json:"response_status,omitempty"`
+	LastError      string    `json:"last_error,omitempty"`
+	NextAttemptAt  time.Time `json:"next_attempt_at"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func (handler *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/webhooks"), "/"), "/")
+
+	switch {
+	case parts[0] == "" && r.Method == http.MethodGet:
+		handler.list(w)
+	case parts[0] == "" && r.Method == http.MethodPost:
+		handler.create(w, r)
+	case parts[0] == "":
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	default:
+		id, err := strconv.Atoi(parts[0])
+		if err != nil || len(parts) > 2 || (len(parts) == 2 && parts[1] != "deliveries") {
+			http.NotFound(w, r)
+			return
+		}
+		handler.serveSubscription(w, r, id, len(parts) == 2)
+	}
+}
+
+func (handler *Handler) serveSubscription(w http.ResponseWriter, r *http.Request, id int, deliveries bool) {
+	switch {
+	case deliveries && r.Method == http.MethodGet:
+		handler.deliveries(w, id)
+	case !deliveries && r.Method == http.MethodGet:
+		handler.get(w, id)
+	case !deliveries && r.Method == http.MethodDelete:
+		handler.delete(w, id)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (handler *Handler) list(w http.ResponseWriter) {
+	subs, err := handler.store.GetSubscriptions()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	response := []subscriptionResponse{}
+	for _, sub := range subs {
+		response = append(response, toSubscriptionResponse(sub))
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (handler *Handler) create(w http.ResponseWriter, r *http.Request) {
+	var request subscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	sub, err := Subscribe(handler.store, Subscription{
+		URL:        request.URL,
+		EventTypes: request.Events,
+		Secret:     request.Secret,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, toSubscriptionResponse(sub))
+}
+
+func (handler *Handler) get(w http.ResponseWriter, id int) {
+	sub, err := handler.store.GetSubscription(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toSubscriptionResponse(sub))
+}
+
+func (handler *Handler) delete(w http.ResponseWriter, id int) {
+	if err := handler.store.DeleteSubscription(id); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (handler *Handler) deliveries(w http.ResponseWriter, id int) {
+	deliveries, err := handler.store.GetDeliveries(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	response := []deliveryResponse{}
+	for _, delivery := range deliveries {
+		response = append(response, deliveryResponse{
+			ID:             delivery.ID,
+			Event:          delivery.Event,
+			Status:         delivery.Status,
+			Attempts:       delivery.Attempts,
+			ResponseStatus: delivery.ResponseStatus,
+			LastError:      delivery.LastError,
+			NextAttemptAt:  delivery.NextAttemptAt.UTC(),
+			CreatedAt:      delivery.CreatedAt.UTC(),
+		})
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func toSubscriptionResponse(sub Subscription) subscriptionResponse {
+	return subscriptionResponse{
+		ID:        sub.ID,
+		URL:       sub.URL,
+		Events:    sub.EventTypes,
+		CreatedAt: sub.CreatedAt.UTC(),
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch err {
+	case ErrNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case ErrInvalidURL, ErrNeedsEventTypes, ErrUnknownEventType, ErrNeedsSecret:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,106 @@
+package webhooks_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/steve-kaufman/postsService/webhooks"
+)
+
+func serve(handler http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return w
+}
+
+func TestHandler_ManagesSubscriptions(t *testing.T) {
+	store, _, _ := setup(t)
+	handler := webhooks.NewHandler(store)
+
+	w := serve(handler, http.MethodPost, "/webhooks",
+		`{"url":"https://example.com/hook","events":["post.created"],"secret":"s3cret"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201; Got: %d %s", w.Code, w.Body)
+	}
+	if strings.Contains(w.Body.String(), "s3cret") {
+		t.Fatal("Expected secret not to be returned")
+	}
+	var sub struct {
+		ID     int
+		URL    string
+		Events []string
+	}
+	json.Unmarshal(w.Body.Bytes(), &sub)
+	if sub.ID != 1 || sub.URL != "https://example.com/hook" || len(sub.Events) != 1 {
+		t.Fatalf("Expected created subscription; Got: %+v", sub)
+	}
+
+	if w := serve(handler, http.MethodGet, "/webhooks", ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"id":1`) {
+		t.Fatalf("Expected subscription in list; Got: %d %s", w.Code, w.Body)
+	}
+	if w := serve(handler, http.MethodGet, "/webhooks/1", ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200; Got: %d", w.Code)
+	}
+	if w := serve(handler, http.MethodGet, "/webhooks/1/deliveries", ""); w.Code != http.StatusOK || w.Body.String() != "[]\n" {
+		t.Fatalf("Expected empty delivery history; Got: %d %s", w.Code, w.Body)
+	}
+	if w := serve(handler, http.MethodDelete, "/webhooks/1", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204; Got: %d", w.Code)
+	}
+	if w := serve(handler, http.MethodGet, "/webhooks/1", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404 after delete; Got: %d", w.Code)
+	}
+}
+
+func TestHandler_ReturnsDeliveryHistory(t *testing.T) {
+	store, dispatcher, _ := setup(t)
+	rec := newReceiver(t, "s3cret", http.StatusInternalServerError)
+	subscribe(t, store, rec.URL, "post.created")
+	dispatcher.Enqueue(created)
+	dispatcher.DeliverDue()
+
+	w := serve(webhooks.NewHandler(store), http.MethodGet, "/webhooks/1/deliveries", "")
+
+	var deliveries []struct {
+		Event          string
+		Status         string
+		Attempts       int
+		ResponseStatus int `json:"response_status"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &deliveries)
+	if len(deliveries) != 1 {
+		t.Fatalf("Expected one delivery; Got: %s", w.Body)
+	}
+	d := deliveries[0]
+	if d.Event != "post.created" || d.Status != webhooks.StatusPending || d.Attempts != 1 || d.ResponseStatus != 500 {
+		t.Fatalf("Expected pending delivery after one failed attempt; Got: %+v", d)
+	}
+}
+
+func TestHandler_RejectsBadRequests(t *testing.T) {
+	store, _, _ := setup(t)
+	handler := webhooks.NewHandler(store)
+	tests := []struct {
+		method, path, body string
+		expected           int
+	}{
+		{http.MethodPost, "/webhooks", `not json`, http.StatusBadRequest},
+		{http.MethodPost, "/webhooks", `{"url":"nope","events":["post.created"],"secret":"s"}`, http.StatusBadRequest},
+		{http.MethodGet, "/webhooks/abc", "", http.StatusNotFound},
+		{http.MethodGet, "/webhooks/5", "", http.StatusNotFound},
+		{http.MethodGet, "/webhooks/5/deliveries", "", http.StatusNotFound},
+		{http.MethodDelete, "/webhooks/5", "", http.StatusNotFound},
+		{http.MethodPut, "/webhooks", "", http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.method+" "+tc.path, func(t *testing.T) {
+			if w := serve(handler, tc.method, tc.path, tc.body); w.Code != tc.expected {
+				t.Fatalf("Expected %d; Got: %d", tc.expected, w.Code)
+			}
+		})
+	}
+}
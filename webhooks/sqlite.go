@@ -0,0 +1,227 @@
+package webhooks
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// SqliteStore keeps subscriptions and their delivery history in SQLite. It
+// can share a database file with db.SqliteRepo.
+type SqliteStore struct {
+	conn *sql.DB
+}
+
+func NewSqliteStore(path string) (*SqliteStore, error) {
+	conn, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = conn.Exec(`CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		url TEXT NOT NULL,
+		event_types TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		created_at INTEGER NOT NULL
+	);`)
+	if err == nil {
+		_, err = conn.Exec(`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			subscription_id INTEGER NOT NULL,
+			event TEXT NOT NULL,
+			payload BLOB NOT NULL,
+			status TEXT NOT NULL,
+			attempts INTEGER NOT NULL,
+			next_attempt_at INTEGER NOT NULL,
+			response_status INTEGER NOT NULL,
+			last_error TEXT NOT NULL,
+			created_at INTEGER NOT NULL
+		);`)
+	}
+	if err == nil {
+		_, err = conn.Exec(`CREATE INDEX IF NOT EXISTS webhook_deliveries_due
+			ON webhook_deliveries (status, next_attempt_at);`)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// one connection, so the dispatcher's concurrent updates queue up
+	// instead of failing with SQLITE_BUSY
+	conn.SetMaxOpenConns(1)
+	return &SqliteStore{conn: conn}, nil
+}
+
+func (store *SqliteStore) Close() error {
+	return store.conn.Close()
+}
+
+func (store *SqliteStore) CreateSubscription(sub Subscription) (Subscription, error) {
+	result, err := store.conn.Exec(`INSERT INTO webhook_subscriptions (url, event_types, secret, created_at)
+		VALUES (?, ?, ?, ?)`,
+		sub.URL,
+		strings.Join(sub.EventTypes, ","),
+		sub.Secret,
+		sub.CreatedAt.UnixNano(),
+	)
+	if err != nil {
+		return Subscription{}, err
+	}
+	id, err := result.LastInsertId()
+	sub.ID = int(id)
+	return sub, err
+}
+
+func (store *SqliteStore) GetSubscriptions() ([]Subscription, error) {
+	rows, err := store.conn.Query(`SELECT id, url, event_types, secret, created_at
+		FROM webhook_subscriptions ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []Subscription{}
+	for rows.Next() {
+		sub, err := mapToSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+func (store *SqliteStore) GetSubscription(id int) (Subscription, error) {
+	row := store.conn.QueryRow(`SELECT id, url, event_types, secret, created_at
+		FROM webhook_subscriptions WHERE id = ?`, id)
+	sub, err := mapToSubscription(row)
+	if err == sql.ErrNoRows {
+		return Subscription{}, ErrNotFound
+	}
+	return sub, err
+}
+
+// DeleteSubscription deletes the subscription and its deliveries together
+func (store *SqliteStore) DeleteSubscription(id int) error {
+	tx, err := store.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM webhook_subscriptions WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	if _, err := tx.Exec(`DELETE FROM webhook_deliveries WHERE subscription_id = ?`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (store *SqliteStore) SaveDelivery(delivery Delivery) error {
+	_, err := store.conn.Exec(`INSERT INTO webhook_deliveries (subscription_id, event, payload,
+		status, attempts, next_attempt_at, response_status, last_error, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		delivery.SubscriptionID,
+		delivery.Event,
+		delivery.Payload,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.NextAttemptAt.UnixNano(),
+		delivery.ResponseStatus,
+		delivery.LastError,
+		delivery.CreatedAt.UnixNano(),
+	)
+	return err
+}
+
+func (store *SqliteStore) UpdateDelivery(delivery Delivery) error {
+	_, err := store.conn.Exec(`UPDATE webhook_deliveries SET
+		status = ?,
+		attempts = ?,
+		next_attempt_at = ?,
+		response_status = ?,
+		last_error = ?
+	WHERE id = ?`,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.NextAttemptAt.UnixNano(),
+		delivery.ResponseStatus,
+		delivery.LastError,
+		delivery.ID,
+	)
+	return err
+}
+
+func (store *SqliteStore) DueDeliveries(now time.Time, limit int) ([]Delivery, error) {
+	return store.queryDeliveries(`WHERE status = ? AND next_attempt_at <= ? ORDER BY id LIMIT ?`,
+		StatusPending, now.UnixNano(), limit)
+}
+
+func (store *SqliteStore) GetDeliveries(subscriptionID int) ([]Delivery, error) {
+	if _, err := store.GetSubscription(subscriptionID); err != nil {
+		return nil, err
+	}
+	return store.queryDeliveries(`WHERE subscription_id = ? ORDER BY id`, subscriptionID)
+}
+
+func (store *SqliteStore) queryDeliveries(where string, args ...interface{}) ([]Delivery, error) {
+	rows, err := store.conn.Query(`SELECT id, subscription_id, event, payload, status, attempts,
+		next_attempt_at, response_status, last_error, created_at
+		FROM webhook_deliveries `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []Delivery{}
+	for rows.Next() {
+		var delivery Delivery
+		var nextAttemptAt, createdAt int64
+		err := rows.Scan(
+			&delivery.ID,
+			&delivery.SubscriptionID,
+			&delivery.Event,
+			&delivery.Payload,
+			&delivery.Status,
+			&delivery.Attempts,
+			&nextAttemptAt,
+			&delivery.ResponseStatus,
+			&delivery.LastError,
+			&createdAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		delivery.NextAttemptAt = time.Unix(0, nextAttemptAt)
+		delivery.CreatedAt = time.Unix(0, createdAt)
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func mapToSubscription(row rowScanner) (Subscription, error) {
+	var sub Subscription
+	var eventTypes string
+	var createdAt int64
+	if err := row.Scan(&sub.ID, &sub.URL, &eventTypes, &sub.Secret, &createdAt); err != nil {
+		return Subscription{}, err
+	}
+	sub.EventTypes = strings.Split(eventTypes, ",")
+	sub.CreatedAt = time.Unix(0, createdAt)
+	return sub, nil
+}
@@ -0,0 +1,107 @@
+// Package webhooks delivers post lifecycle events to subscribed HTTP
+// endpoints, signing every request with the subscription's secret.
+package webhooks
+
+import (
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/steve-kaufman/postsService/events"
+)
+
+var ErrNotFound = errors.New("webhook not found")
+var ErrInvalidURL = errors.New("url must be an absolute http or https url")
+var ErrNeedsEventTypes = errors.New("at least one event type is required")
+var ErrUnknownEventType = errors.New("unknown event type")
+var ErrNeedsSecret = errors.New("secret is required")
+
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+type Subscription struct {
+	ID         int
+	URL        string
+	EventTypes []string
+	Secret     string
+	CreatedAt  time.Time
+}
+
+// Delivery is one event sent, or being sent, to one subscription
+type Delivery struct {
+	ID             int
+	SubscriptionID int
+	Event          string
+	Payload        []byte
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	ResponseStatus int
+	LastError      string
+	CreatedAt      time.Time
+}
+
+// Store keeps subscriptions and deliveries. A Dispatcher uses it from
+// several goroutines at once.
+type Store interface {
+	CreateSubscription(sub Subscription) (Subscription, error)
+	GetSubscriptions() ([]Subscription, error)
+	GetSubscription(id int) (Subscription, error)
+	DeleteSubscription(id int) error
+
+	SaveDelivery(delivery Delivery) error
+	UpdateDelivery(delivery Delivery) error
+	// DueDeliveries returns up to limit pending deliveries due at now,
+	// oldest first
+	DueDeliveries(now time.Time, limit int) ([]Delivery, error)
+	GetDeliveries(subscriptionID int) ([]Delivery, error)
+}
+
+func (sub Subscription) wants(eventName string) bool {
+	for _, eventType := range sub.EventTypes {
+		if eventType == eventName {
+			return true
+		}
+	}
+	return false
+}
+
+func validateSubscription(sub Subscription) error {
+	u, err := url.Parse(sub.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidURL
+	}
+	if len(sub.EventTypes) == 0 {
+		return ErrNeedsEventTypes
+	}
+	for _, eventType := range sub.EventTypes {
+		if !isKnownEvent(eventType) {
+			return ErrUnknownEventType
+		}
+	}
+	if sub.Secret == "" {
+		return ErrNeedsSecret
+	}
+	return nil
+}
+
+func isKnownEvent(name string) bool {
+	for _, known := range events.Names {
+		if name == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscribe validates sub and stores it
+func Subscribe(store Store, sub Subscription) (Subscription, error) {
+	if err := validateSubscription(sub); err != nil {
+		return Subscription{}, err
+	}
+	sub.CreatedAt = time.Now()
+	return store.CreateSubscription(sub)
+}